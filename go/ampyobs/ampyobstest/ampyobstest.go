@@ -0,0 +1,402 @@
+// Package ampyobstest wires an ampyobs instance to in-memory exporters so
+// unit tests can assert on spans, domain metrics and logs without a
+// collector.
+//
+//	func TestPublish(t *testing.T) {
+//		rec := ampyobstest.New(t)
+//		publish(ctx, msg) // calls ampyobs.StartBusPublishSpan and ampyobs.BusProducedAdd
+//		rec.AssertSpan(t, "bus.publish", attribute.String("topic", "ampy/dev/bars/v1"))
+//		rec.AssertCounter(t, "ampy.bus.produced_total", map[string]string{"topic": "ampy/dev/bars/v1"}, 1)
+//		rec.AssertLogged(t, slog.LevelInfo, "published")
+//	}
+//
+// New installs the instance as ampyobs.Default and restores the previous
+// default when the test ends, so package-level helpers are captured too.
+// Tests that use package-level helpers must not run in parallel; tests that
+// only use Recorder.Obs may.
+package ampyobstest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AmpyFin/ampy-observability/go/ampyobs"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Recorder captures everything its Obs emits.
+type Recorder struct {
+	Obs *ampyobs.Obs
+
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+	logs   *logCapture
+
+	mu       sync.Mutex
+	baseline map[string]point // cumulative sums and histograms at the last Reset
+}
+
+// New builds an instance that records every span, metric and log in memory
+// and makes it the ampyobs Default for the duration of t. configure may
+// adjust the Config before the in-memory pipeline is attached; by default
+// the service is "ampyobstest" in env "dev", every span is sampled and
+// nothing is sent over the network.
+func New(t testing.TB, configure ...func(*ampyobs.Config)) *Recorder {
+	t.Helper()
+
+	r := &Recorder{
+		spans:  tracetest.NewInMemoryExporter(),
+		reader: sdkmetric.NewManualReader(),
+		logs:   &logCapture{},
+	}
+	cfg := ampyobs.Config{
+		ServiceName:      "ampyobstest",
+		Environment:      "dev",
+		Protocol:         ampyobs.ProtocolNone,
+		EnableLogs:       true,
+		DisableLogStdout: true, // records are captured below
+		EnableMetrics:    true,
+		EnableTracing:    true,
+		Sampler:          "always_on",
+	}
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	cfg.SpanProcessors = append(cfg.SpanProcessors, sdktrace.NewSimpleSpanProcessor(r.spans))
+	cfg.MetricReaders = append(cfg.MetricReaders, r.reader)
+	cfg.LogProcessors = append(cfg.LogProcessors, r.logs)
+
+	o, err := ampyobs.New(cfg)
+	if err != nil {
+		t.Fatalf("ampyobstest: %v", err)
+	}
+	r.Obs = o
+
+	prev := ampyobs.Default()
+	ampyobs.SetDefault(o)
+	t.Cleanup(func() {
+		ampyobs.SetDefault(prev)
+		if err := o.Shutdown(context.Background()); err != nil {
+			t.Errorf("ampyobstest: shutdown: %v", err)
+		}
+	})
+	return r
+}
+
+// Reset forgets recorded spans and logs and zeroes counters and histograms,
+// e.g. between the phases of one test. Gauges keep their last value.
+func (r *Recorder) Reset() {
+	r.spans.Reset()
+	r.logs.reset()
+	base := map[string]point{}
+	for _, p := range r.collectRaw() {
+		if !p.gauge {
+			base[p.key()] = p
+		}
+	}
+	r.mu.Lock()
+	r.baseline = base
+	r.mu.Unlock()
+}
+
+// ----------- Spans -----------
+
+// Spans returns the ended spans in the order they ended.
+func (r *Recorder) Spans() tracetest.SpanStubs {
+	return r.spans.GetSpans()
+}
+
+// FindSpans returns the ended spans called name that carry all of attrs.
+func (r *Recorder) FindSpans(name string, attrs ...attribute.KeyValue) []tracetest.SpanStub {
+	var out []tracetest.SpanStub
+	for _, s := range r.Spans() {
+		if s.Name == name && hasAttrs(s.Attributes, attrs) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// AssertSpan fails t unless a span called name with all of attrs has ended,
+// and returns the first such span.
+func (r *Recorder) AssertSpan(t testing.TB, name string, attrs ...attribute.KeyValue) tracetest.SpanStub {
+	t.Helper()
+	found := r.FindSpans(name, attrs...)
+	if len(found) == 0 {
+		t.Errorf("ampyobstest: no span %q with %s; ended spans: %s", name, fmtAttrs(attrs), r.spanNames())
+		return tracetest.SpanStub{}
+	}
+	return found[0]
+}
+
+// AssertNoSpan fails t if a span called name has ended.
+func (r *Recorder) AssertNoSpan(t testing.TB, name string) {
+	t.Helper()
+	if n := len(r.FindSpans(name)); n > 0 {
+		t.Errorf("ampyobstest: want no span %q, got %d", name, n)
+	}
+}
+
+func (r *Recorder) spanNames() string {
+	var names []string
+	for _, s := range r.Spans() {
+		names = append(names, s.Name)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func hasAttrs(have, want []attribute.KeyValue) bool {
+	set := attribute.NewSet(have...)
+	for _, kv := range want {
+		v, ok := set.Value(kv.Key)
+		if !ok || v != kv.Value {
+			return false
+		}
+	}
+	return true
+}
+
+func fmtAttrs(attrs []attribute.KeyValue) string {
+	parts := make([]string, len(attrs))
+	for i, kv := range attrs {
+		parts[i] = fmt.Sprintf("%s=%s", kv.Key, kv.Value.Emit())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// ----------- Metrics -----------
+
+// point is one flattened data point.
+type point struct {
+	name  string
+	attrs attribute.Set
+	value float64 // sum or gauge value; histogram sum
+	count uint64  // histogram count
+	gauge bool
+}
+
+func (p point) key() string {
+	return p.name + "|" + p.attrs.Encoded(attribute.DefaultEncoder())
+}
+
+func (p point) matches(name string, labels map[string]string) bool {
+	if p.name != name {
+		return false
+	}
+	for k, want := range labels {
+		v, ok := p.attrs.Value(attribute.Key(k))
+		if !ok || v.Emit() != want {
+			return false
+		}
+	}
+	return true
+}
+
+// collect reads the current cumulative state of every instrument, minus the
+// baseline taken by Reset.
+func (r *Recorder) collect() []point {
+	out := r.collectRaw()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, p := range out {
+		if b, ok := r.baseline[p.key()]; ok {
+			out[i].value -= b.value
+			out[i].count -= b.count
+		}
+	}
+	return out
+}
+
+func (r *Recorder) collectRaw() []point {
+	var rm metricdata.ResourceMetrics
+	if err := r.reader.Collect(context.Background(), &rm); err != nil {
+		return nil
+	}
+	var out []point
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			out = appendPoints(out, m)
+		}
+	}
+	return out
+}
+
+func appendPoints(out []point, m metricdata.Metrics) []point {
+	switch d := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: float64(dp.Value)})
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: dp.Value})
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: float64(dp.Value), gauge: true})
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: dp.Value, gauge: true})
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: float64(dp.Sum), count: dp.Count})
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range d.DataPoints {
+			out = append(out, point{name: m.Name, attrs: dp.Attributes, value: dp.Sum, count: dp.Count})
+		}
+	}
+	return out
+}
+
+// CounterValue sums the data points of the counter, up-down counter or gauge
+// called name whose attributes include labels (nil matches every point).
+func (r *Recorder) CounterValue(name string, labels map[string]string) float64 {
+	var sum float64
+	for _, p := range r.collect() {
+		if p.matches(name, labels) {
+			sum += p.value
+		}
+	}
+	return sum
+}
+
+// AssertCounter fails t unless CounterValue(name, labels) equals want.
+func (r *Recorder) AssertCounter(t testing.TB, name string, labels map[string]string, want float64) {
+	t.Helper()
+	if got := r.CounterValue(name, labels); got != want {
+		t.Errorf("ampyobstest: %s%s = %g, want %g; ampy metrics: %s", name, fmtLabels(labels), got, want, r.metricNames())
+	}
+}
+
+// HistogramCount counts the observations of the histogram called name whose
+// attributes include labels.
+func (r *Recorder) HistogramCount(name string, labels map[string]string) uint64 {
+	var n uint64
+	for _, p := range r.collect() {
+		if p.matches(name, labels) {
+			n += p.count
+		}
+	}
+	return n
+}
+
+// AssertHistogramCount fails t unless HistogramCount(name, labels) equals want.
+func (r *Recorder) AssertHistogramCount(t testing.TB, name string, labels map[string]string, want uint64) {
+	t.Helper()
+	if got := r.HistogramCount(name, labels); got != want {
+		t.Errorf("ampyobstest: %s%s observed %d times, want %d; ampy metrics: %s", name, fmtLabels(labels), got, want, r.metricNames())
+	}
+}
+
+func fmtLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// metricNames lists the domain metrics with data, skipping runtime metrics
+// and the pipeline's own ampy.obs.* metrics.
+func (r *Recorder) metricNames() string {
+	seen := map[string]bool{}
+	var names []string
+	for _, p := range r.collect() {
+		domain := strings.HasPrefix(p.name, "ampy.") && !strings.HasPrefix(p.name, "ampy.obs.")
+		if domain && !seen[p.name] {
+			seen[p.name] = true
+			names = append(names, p.name)
+		}
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// ----------- Logs -----------
+
+// Log is one captured log record.
+type Log struct {
+	Level   slog.Level
+	Message string
+	Attrs   map[string]string
+	TraceID trace.TraceID
+	SpanID  trace.SpanID
+}
+
+// Logs returns the captured records in emission order.
+func (r *Recorder) Logs() []Log {
+	return r.logs.snapshot()
+}
+
+// AssertLogged fails t unless a record with level and message msg was
+// logged, and returns the first one.
+func (r *Recorder) AssertLogged(t testing.TB, level slog.Level, msg string) Log {
+	t.Helper()
+	logs := r.Logs()
+	for _, l := range logs {
+		if l.Level == level && l.Message == msg {
+			return l
+		}
+	}
+	got := make([]string, len(logs))
+	for i, l := range logs {
+		got[i] = fmt.Sprintf("%s %q", l.Level, l.Message)
+	}
+	t.Errorf("ampyobstest: no %s log %q; logged: [%s]", level, msg, strings.Join(got, ", "))
+	return Log{}
+}
+
+// logCapture is a log processor that keeps every record.
+type logCapture struct {
+	mu   sync.Mutex
+	logs []Log
+}
+
+func (c *logCapture) OnEmit(_ context.Context, rec *sdklog.Record) error {
+	l := Log{
+		Level:   slog.Level(rec.Severity() - otellog.SeverityInfo),
+		Message: rec.Body().String(),
+		Attrs:   map[string]string{},
+		TraceID: rec.TraceID(),
+		SpanID:  rec.SpanID(),
+	}
+	rec.WalkAttributes(func(kv otellog.KeyValue) bool {
+		l.Attrs[kv.Key] = kv.Value.String()
+		return true
+	})
+	c.mu.Lock()
+	c.logs = append(c.logs, l)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *logCapture) snapshot() []Log {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Log(nil), c.logs...)
+}
+
+func (c *logCapture) reset() {
+	c.mu.Lock()
+	c.logs = nil
+	c.mu.Unlock()
+}
+
+func (c *logCapture) Shutdown(context.Context) error   { return nil }
+func (c *logCapture) ForceFlush(context.Context) error { return nil }
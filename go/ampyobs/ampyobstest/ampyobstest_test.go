@@ -0,0 +1,164 @@
+package ampyobstest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/AmpyFin/ampy-observability/go/ampyobs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeT records assertion failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+const topic = "ampy/dev/bars/v1"
+
+// emit publishes one message the way instrumented code would, through the
+// package-level helpers.
+func emit(ctx context.Context) {
+	ctx, span := ampyobs.StartBusPublishSpan(ctx, ampyobs.BusAttrs{Topic: topic, MessageID: "m-1"})
+	ampyobs.BusProducedAdd(ctx, topic, 2)
+	ampyobs.BusDeliveryLatencyMs(ctx, topic, 12.5)
+	ampyobs.C(ctx).Info("published", "topic", topic)
+	span.End()
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		name    string
+		assert  func(r *Recorder, t testing.TB)
+		wantErr string // substring of the failure; empty if the assertion passes
+	}{
+		{
+			name:   "span",
+			assert: func(r *Recorder, t testing.TB) { r.AssertSpan(t, "bus.publish", attribute.String("topic", topic)) },
+		},
+		{
+			name:    "span with other attributes",
+			assert:  func(r *Recorder, t testing.TB) { r.AssertSpan(t, "bus.publish", attribute.String("topic", "other")) },
+			wantErr: `no span "bus.publish" with {topic=other}; ended spans: [bus.publish]`,
+		},
+		{
+			name:    "no span",
+			assert:  func(r *Recorder, t testing.TB) { r.AssertNoSpan(t, "bus.publish") },
+			wantErr: `want no span "bus.publish", got 1`,
+		},
+		{
+			name: "counter",
+			assert: func(r *Recorder, t testing.TB) {
+				r.AssertCounter(t, "ampy.bus.produced_total", map[string]string{"topic": topic}, 2)
+			},
+		},
+		{
+			name:   "counter without labels",
+			assert: func(r *Recorder, t testing.TB) { r.AssertCounter(t, "ampy.bus.produced_total", nil, 2) },
+		},
+		{
+			name: "counter with other labels",
+			assert: func(r *Recorder, t testing.TB) {
+				r.AssertCounter(t, "ampy.bus.produced_total", map[string]string{"topic": "x"}, 2)
+			},
+			wantErr: "ampy.bus.produced_total{topic=x} = 0, want 2; ampy metrics: [ampy.bus.delivery_latency_ms, ampy.bus.produced_total]",
+		},
+		{
+			name: "histogram",
+			assert: func(r *Recorder, t testing.TB) {
+				r.AssertHistogramCount(t, "ampy.bus.delivery_latency_ms", map[string]string{"topic": topic}, 1)
+			},
+		},
+		{
+			name:    "histogram count",
+			assert:  func(r *Recorder, t testing.TB) { r.AssertHistogramCount(t, "ampy.bus.delivery_latency_ms", nil, 3) },
+			wantErr: "observed 1 times, want 3",
+		},
+		{
+			name:   "logged",
+			assert: func(r *Recorder, t testing.TB) { r.AssertLogged(t, slog.LevelInfo, "published") },
+		},
+		{
+			name:    "logged at another level",
+			assert:  func(r *Recorder, t testing.TB) { r.AssertLogged(t, slog.LevelError, "published") },
+			wantErr: `no ERROR log "published"; logged: [INFO "published"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(t)
+			emit(context.Background())
+
+			ft := &fakeT{TB: t}
+			tt.assert(r, ft)
+			switch {
+			case tt.wantErr == "" && len(ft.errors) > 0:
+				t.Errorf("unexpected failure: %v", ft.errors)
+			case tt.wantErr != "" && (len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tt.wantErr)):
+				t.Errorf("failures = %q, want one containing %q", ft.errors, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogCorrelation(t *testing.T) {
+	r := New(t)
+	emit(context.Background())
+
+	span := r.AssertSpan(t, "bus.publish")
+	l := r.AssertLogged(t, slog.LevelInfo, "published")
+	if l.TraceID != span.SpanContext.TraceID() || l.SpanID != span.SpanContext.SpanID() {
+		t.Errorf("log carries %s/%s, want the span's %s/%s", l.TraceID, l.SpanID, span.SpanContext.TraceID(), span.SpanContext.SpanID())
+	}
+	if l.Attrs["topic"] != topic {
+		t.Errorf("log attrs = %v", l.Attrs)
+	}
+}
+
+func TestReset(t *testing.T) {
+	r := New(t)
+	emit(context.Background())
+	r.Reset()
+
+	if n := len(r.Spans()) + len(r.Logs()); n != 0 {
+		t.Errorf("%d spans and logs left after Reset", n)
+	}
+	r.AssertCounter(t, "ampy.bus.produced_total", nil, 0)
+	r.AssertHistogramCount(t, "ampy.bus.delivery_latency_ms", nil, 0)
+
+	emit(context.Background())
+	r.AssertCounter(t, "ampy.bus.produced_total", nil, 2)
+	r.AssertHistogramCount(t, "ampy.bus.delivery_latency_ms", nil, 1)
+	if got := r.CounterValue("ampy.bus.produced_total", map[string]string{"service": "ampyobstest", "env": "dev"}); got != 2 {
+		t.Errorf("counter with service labels = %g, want 2", got)
+	}
+}
+
+func TestNewRestoresDefault(t *testing.T) {
+	prev := ampyobs.Default()
+	t.Run("recorder", func(t *testing.T) {
+		r := New(t, func(c *ampyobs.Config) { c.ServiceName = "custom" })
+		if ampyobs.Default() != r.Obs {
+			t.Fatal("New did not install the recorder's instance as Default")
+		}
+		_, span := r.Obs.StartSpan(context.Background(), "direct", trace.SpanKindInternal)
+		span.End()
+		s := r.AssertSpan(t, "direct")
+		if v, _ := s.Resource.Set().Value("service.name"); v.AsString() != "custom" {
+			t.Errorf("service.name = %q, want the configured name", v.AsString())
+		}
+	})
+	if ampyobs.Default() != prev {
+		t.Error("Default not restored after the test")
+	}
+}
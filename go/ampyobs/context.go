@@ -0,0 +1,52 @@
+package ampyobs
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type domainKey struct{}
+
+// DomainContext carries AmpyFin identifiers through a flow. C(ctx) adds the
+// non-empty ones to every log record, and they can be registered as debug
+// targets (see AddDebugTarget).
+type DomainContext struct {
+	RunID         string
+	AsOfISO       string
+	UniverseID    string
+	MessageID     string
+	ClientOrderID string
+	Symbol        string
+	MIC           string
+}
+
+// WithDomainContext returns a copy of ctx carrying dc.
+func WithDomainContext(ctx context.Context, dc DomainContext) context.Context {
+	return context.WithValue(ctx, domainKey{}, dc)
+}
+
+// FromDomainContext returns the DomainContext carried by ctx, if any.
+func FromDomainContext(ctx context.Context) (DomainContext, bool) {
+	dc, ok := ctx.Value(domainKey{}).(DomainContext)
+	return dc, ok
+}
+
+// fields returns the non-empty identifiers under their log keys.
+func (d DomainContext) fields() []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, 7)
+	for _, f := range []struct{ key, value string }{
+		{"run_id", d.RunID},
+		{"as_of", d.AsOfISO},
+		{"universe_id", d.UniverseID},
+		{"message_id", d.MessageID},
+		{"client_order_id", d.ClientOrderID},
+		{"symbol", d.Symbol},
+		{"mic", d.MIC},
+	} {
+		if f.value != "" {
+			out = append(out, attribute.String(f.key, f.value))
+		}
+	}
+	return out
+}
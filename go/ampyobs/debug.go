@@ -0,0 +1,145 @@
+package ampyobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/debugtarget"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BaggageDebug is the baggage member marking a flow for debugging. Flows
+// carrying it are sampled in full and log at debug level in every service
+// they reach; InjectTrace adds it to outgoing headers of flows matching a
+// debug target.
+const BaggageDebug = debugtarget.Baggage
+
+// debugAttr marks spans sampled because their flow is being debugged, so
+// tail sampling keeps them too.
+var debugAttr = debugtarget.Attr
+
+// DebugTarget is a flow forced to full sampling and debug logging.
+type DebugTarget struct {
+	Key     string    // DomainContext log key or span attribute, e.g. "run_id", "client_order_id"
+	Value   string    // e.g. the client order id a trader reported
+	Expires time.Time // zero: until removed
+}
+
+// WithDebug marks the flow of ctx for debugging via baggage, e.g. from an
+// API handler given a debug header.
+func WithDebug(ctx context.Context) context.Context {
+	return debugtarget.WithBaggage(ctx)
+}
+
+// AddDebugTarget forces full sampling and debug logging for flows whose
+// DomainContext or root span attributes have key set to value, for ttl
+// (0 keeps it until RemoveDebugTarget). Adding an existing target resets its
+// expiry.
+func (o *Obs) AddDebugTarget(key, value string, ttl time.Duration) {
+	o.debug.Add(key, value, ttl)
+}
+
+// RemoveDebugTarget stops debugging the flows of a target.
+func (o *Obs) RemoveDebugTarget(key, value string) {
+	o.debug.Remove(key, value)
+}
+
+// DebugTargets lists the targets that have not expired.
+func (o *Obs) DebugTargets() []DebugTarget {
+	targets := o.debug.List()
+	out := make([]DebugTarget, len(targets))
+	for i, t := range targets {
+		out[i] = DebugTarget(t)
+	}
+	return out
+}
+
+// IsDebug reports whether the flow of ctx is being debugged, through
+// baggage or a debug target matching its DomainContext.
+func (o *Obs) IsDebug(ctx context.Context) bool {
+	return o.debug.match(ctx, nil)
+}
+
+// AddDebugTarget adds a debug target to the Default instance.
+func AddDebugTarget(key, value string, ttl time.Duration) {
+	Default().AddDebugTarget(key, value, ttl)
+}
+
+// RemoveDebugTarget removes a debug target from the Default instance.
+func RemoveDebugTarget(key, value string) {
+	Default().RemoveDebugTarget(key, value)
+}
+
+// ----------- Targets -----------
+
+// debugTargets matches flows against the debug targets of an instance,
+// shared with the zap flavor through the debugtarget package.
+type debugTargets struct {
+	debugtarget.Set
+}
+
+func newDebugTargets() *debugTargets {
+	return &debugTargets{}
+}
+
+// match reports whether the flow of ctx, or a span starting with attrs, is
+// being debugged.
+func (t *debugTargets) match(ctx context.Context, attrs []attribute.KeyValue) bool {
+	if debugtarget.HasBaggage(ctx) {
+		return true
+	}
+	if t.Empty() {
+		return false
+	}
+	if dc, ok := FromDomainContext(ctx); ok && t.MatchAny(dc.fields()) {
+		return true
+	}
+	return t.MatchAny(attrs)
+}
+
+// ----------- Sampling and logging -----------
+
+// debugSampler samples every span of a debugged flow, even under a parent
+// that was not sampled, and defers to next otherwise.
+type debugSampler struct {
+	next  sdktrace.Sampler
+	debug *debugTargets
+}
+
+func (s debugSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !s.debug.match(p.ParentContext, p.Attributes) {
+		return s.next.ShouldSample(p)
+	}
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{debugAttr},
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s debugSampler) Description() string {
+	return fmt.Sprintf("DebugSampler{%s}", s.next.Description())
+}
+
+// debugHandler lets debug records of debugged flows through a levelHandler
+// whose level would drop them.
+type debugHandler struct {
+	slog.Handler
+	debug *debugTargets
+}
+
+func (h debugHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.Handler.Enabled(ctx, l) || l >= slog.LevelDebug && h.debug.match(ctx, nil)
+}
+
+func (h debugHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return debugHandler{Handler: h.Handler.WithAttrs(attrs), debug: h.debug}
+}
+
+func (h debugHandler) WithGroup(name string) slog.Handler {
+	return debugHandler{Handler: h.Handler.WithGroup(name), debug: h.debug}
+}
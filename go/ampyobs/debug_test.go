@@ -0,0 +1,132 @@
+package ampyobs
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestIsDebug(t *testing.T) {
+	o := newTestObs(t)
+	o.AddDebugTarget("client_order_id", "co-9", 0)
+	withBaggage := func(v string) context.Context {
+		m, _ := baggage.NewMemberRaw(BaggageDebug, v)
+		b, _ := baggage.New(m)
+		return baggage.ContextWithBaggage(context.Background(), b)
+	}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"plain", context.Background(), false},
+		{"WithDebug", WithDebug(context.Background()), true},
+		{"baggage true", withBaggage("TRUE"), true},
+		{"baggage 0", withBaggage("0"), false},
+		{"matching domain context", WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-9", Symbol: "AAPL"}), true},
+		{"other order", WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-1"}), false},
+	}
+	for _, tt := range tests {
+		if got := o.IsDebug(tt.ctx); got != tt.want {
+			t.Errorf("%s: IsDebug = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDebugSampling(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	o := newTestObs(t, func(c *Config) {
+		c.Sampler = "always_off"
+		c.SpanProcessors = []sdktrace.SpanProcessor{sdktrace.NewSimpleSpanProcessor(spans)}
+	})
+	o.AddDebugTarget("client_order_id", "co-9", 0)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []trace.SpanStartOption
+		want bool
+	}{
+		{name: "unrelated", ctx: context.Background(), want: false},
+		{name: "baggage", ctx: WithDebug(context.Background()), want: true},
+		{name: "domain context", ctx: WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-9"}), want: true},
+		{name: "root span attribute", ctx: context.Background(), opts: []trace.SpanStartOption{trace.WithAttributes(attribute.String("client_order_id", "co-9"))}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans.Reset()
+			tracer := o.TracerProvider().Tracer("test")
+			ctx, root := tracer.Start(tt.ctx, "oms.submit", tt.opts...)
+			_, child := tracer.Start(ctx, "risk.check")
+			child.End()
+			root.End()
+
+			got := spans.GetSpans()
+			if !tt.want {
+				if len(got) != 0 {
+					t.Errorf("exported %d spans of an undebugged flow", len(got))
+				}
+				return
+			}
+			if len(got) != 2 {
+				t.Fatalf("exported %d spans, want 2", len(got))
+			}
+			// The child follows its sampled parent; only spans matched
+			// themselves carry the marker.
+			if root := got[1]; root.Name != "oms.submit" || !slices.Contains(root.Attributes, debugAttr) {
+				t.Errorf("root span %s lacks %v", root.Name, debugAttr)
+			}
+		})
+	}
+}
+
+func TestDebugLogging(t *testing.T) {
+	logs := &recordCounter{}
+	o := newTestObs(t, func(c *Config) {
+		c.LogLevel = "info"
+		c.LogProcessors = []sdklog.Processor{logs}
+	})
+	o.AddDebugTarget("run_id", "bt-1", 0)
+
+	o.L().DebugContext(context.Background(), "hidden")
+	o.L().DebugContext(WithDomainContext(context.Background(), DomainContext{RunID: "bt-1"}), "debugged run")
+	o.L().DebugContext(WithDebug(context.Background()), "debug baggage")
+	o.C(WithDomainContext(context.Background(), DomainContext{RunID: "bt-1"})).Debug("through C")
+
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	if want := []string{"debugged run", "debug baggage", "through C"}; !reflect.DeepEqual(logs.msgs, want) {
+		t.Errorf("logged %q, want %q", logs.msgs, want)
+	}
+}
+
+func TestInjectTraceDebug(t *testing.T) {
+	o := newTestObs(t)
+	o.AddDebugTarget("client_order_id", "co-9", 0)
+
+	tests := []struct {
+		name string
+		dc   DomainContext
+		want bool
+	}{
+		{"matching target", DomainContext{ClientOrderID: "co-9"}, true},
+		{"other flow", DomainContext{ClientOrderID: "co-1"}, false},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{}
+		o.InjectTrace(WithDomainContext(context.Background(), tt.dc), headers)
+		// The receiving service has no targets; the baggage alone marks the flow.
+		downstream := newTestObs(t)
+		if got := downstream.IsDebug(downstream.ExtractTrace(context.Background(), headers)); got != tt.want {
+			t.Errorf("%s: downstream IsDebug = %v (headers %v), want %v", tt.name, got, headers, tt.want)
+		}
+	}
+}
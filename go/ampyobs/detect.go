@@ -0,0 +1,162 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// DetectorsConfig turns on resource detectors. All are off by default; the
+// detected attributes are added to every span, metric and log record, and
+// Config.ResourceAttributes, ServiceName, ServiceVersion and Environment win
+// over them.
+type DetectorsConfig struct {
+	Host       bool // host.name, host.id, host.arch
+	OS         bool // os.type, os.description
+	Process    bool // process.pid, executable name/path, owner and Go runtime (no command line: it may hold secrets)
+	Container  bool // container.id from /proc/self/cgroup
+	Kubernetes bool // k8s.* from downward-API env vars (see k8sEnv)
+	Build      bool // VCS revision and build settings from debug.ReadBuildInfo
+}
+
+// AllDetectors returns a DetectorsConfig with every detector on.
+func AllDetectors() DetectorsConfig {
+	return DetectorsConfig{Host: true, OS: true, Process: true, Container: true, Kubernetes: true, Build: true}
+}
+
+func (d DetectorsConfig) any() bool {
+	return d != DetectorsConfig{}
+}
+
+// parseDetectors parses a comma-separated detector list such as
+// "host,container,k8s" or "all".
+func parseDetectors(s string) (DetectorsConfig, bool) {
+	var d DetectorsConfig
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "all":
+			d = AllDetectors()
+		case "host":
+			d.Host = true
+		case "os":
+			d.OS = true
+		case "process":
+			d.Process = true
+		case "container":
+			d.Container = true
+		case "k8s", "kubernetes":
+			d.Kubernetes = true
+		case "build":
+			d.Build = true
+		default:
+			return d, false
+		}
+	}
+	return d, true
+}
+
+// k8sEnv maps k8s.* attributes to the env vars they are read from, first
+// match wins. Populate them from the downward API, e.g.
+//
+//	env:
+//	  - name: K8S_POD_NAME
+//	    valueFrom: {fieldRef: {fieldPath: metadata.name}}
+var k8sEnv = []struct {
+	attr func(string) attribute.KeyValue
+	vars []string
+}{
+	{semconv.K8SPodName, []string{"K8S_POD_NAME", "POD_NAME"}},
+	{semconv.K8SPodUID, []string{"K8S_POD_UID", "POD_UID"}},
+	{semconv.K8SNamespaceName, []string{"K8S_NAMESPACE_NAME", "K8S_NAMESPACE", "POD_NAMESPACE"}},
+	{semconv.K8SNodeName, []string{"K8S_NODE_NAME"}},
+	{semconv.K8SContainerName, []string{"K8S_CONTAINER_NAME"}},
+	{semconv.K8SDeploymentName, []string{"K8S_DEPLOYMENT_NAME"}},
+}
+
+// detectResource runs the enabled detectors. Detectors that find nothing
+// (e.g. no cgroup outside a container) are skipped silently.
+func detectResource(ctx context.Context, d DetectorsConfig) (*resource.Resource, error) {
+	var opts []resource.Option
+	if d.Host {
+		opts = append(opts, resource.WithHost(), resource.WithHostID(),
+			resource.WithAttributes(semconv.HostArchKey.String(runtime.GOARCH)))
+	}
+	if d.OS {
+		opts = append(opts, resource.WithOS())
+	}
+	if d.Process {
+		opts = append(opts,
+			resource.WithProcessPID(),
+			resource.WithProcessExecutableName(),
+			resource.WithProcessExecutablePath(),
+			resource.WithProcessOwner(),
+			resource.WithProcessRuntimeName(),
+			resource.WithProcessRuntimeVersion(),
+			resource.WithProcessRuntimeDescription(),
+		)
+	}
+	if d.Container {
+		opts = append(opts, resource.WithContainer())
+	}
+	if d.Kubernetes {
+		opts = append(opts, resource.WithAttributes(k8sAttributes()...))
+	}
+	if d.Build {
+		opts = append(opts, resource.WithAttributes(buildAttributes()...))
+	}
+	res, err := resource.New(ctx, append(opts, resource.WithSchemaURL(semconv.SchemaURL))...)
+	if errors.Is(err, resource.ErrPartialResource) {
+		err = nil
+	}
+	return res, err
+}
+
+func k8sAttributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	for _, k := range k8sEnv {
+		for _, name := range k.vars {
+			if v := os.Getenv(name); v != "" {
+				attrs = append(attrs, k.attr(v))
+				break
+			}
+		}
+	}
+	return attrs
+}
+
+// buildAttributes reads the main module and the VCS stamp that `go build`
+// embeds when building from a checkout.
+func buildAttributes() []attribute.KeyValue {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("ampy.build.go_version", bi.GoVersion),
+	}
+	if bi.Main.Path != "" {
+		attrs = append(attrs, attribute.String("ampy.build.module", bi.Main.Path))
+	}
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		attrs = append(attrs, attribute.String("ampy.build.module_version", v))
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			attrs = append(attrs, semconv.VCSRefHeadRevision(s.Value))
+		case "vcs.time":
+			attrs = append(attrs, attribute.String("ampy.build.vcs_time", s.Value))
+		case "vcs.modified":
+			attrs = append(attrs, attribute.Bool("ampy.build.vcs_modified", s.Value == "true"))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,106 @@
+package ampyobs
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestParseDetectors(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  DetectorsConfig
+		known bool
+	}{
+		{"", DetectorsConfig{}, true},
+		{"host, K8S ,build", DetectorsConfig{Host: true, Kubernetes: true, Build: true}, true},
+		{"kubernetes,os,process,container", DetectorsConfig{OS: true, Process: true, Container: true, Kubernetes: true}, true},
+		{"all", AllDetectors(), true},
+		{"host,gpu", DetectorsConfig{Host: true}, false},
+	}
+	for _, tt := range tests {
+		got, known := parseDetectors(tt.in)
+		if known != tt.known || (known && got != tt.want) {
+			t.Errorf("parseDetectors(%q) = %+v, %v; want %+v, %v", tt.in, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func TestK8sAttributes(t *testing.T) {
+	t.Setenv("K8S_POD_NAME", "")
+	t.Setenv("POD_NAME", "oms-7d9f")
+	t.Setenv("K8S_NAMESPACE_NAME", "trading")
+	t.Setenv("POD_NAMESPACE", "ignored")
+	t.Setenv("K8S_NODE_NAME", "")
+
+	got := attribute.NewSet(k8sAttributes()...)
+	want := attribute.NewSet(
+		attribute.String("k8s.pod.name", "oms-7d9f"),
+		attribute.String("k8s.namespace.name", "trading"),
+	)
+	if !got.Equals(&want) {
+		t.Errorf("k8sAttributes() = %v, want %v", got.Encoded(attribute.DefaultEncoder()), want.Encoded(attribute.DefaultEncoder()))
+	}
+}
+
+func TestNewResourceDetectors(t *testing.T) {
+	t.Setenv("K8S_POD_NAME", "oms-7d9f")
+	host, _ := os.Hostname()
+	tests := []struct {
+		name   string
+		cfg    Config
+		want   map[string]string // expected values; "" only checks presence
+		absent []string
+	}{
+		{
+			name:   "off by default",
+			cfg:    Config{ServiceName: "oms"},
+			want:   map[string]string{"service.name": "oms"},
+			absent: []string{"host.name", "process.pid", "k8s.pod.name"},
+		},
+		{
+			name: "host, process and kubernetes",
+			cfg:  Config{ServiceName: "oms", Detectors: DetectorsConfig{Host: true, Process: true, Kubernetes: true}},
+			want: map[string]string{
+				"host.name":            host,
+				"host.arch":            runtime.GOARCH,
+				"process.pid":          strconv.Itoa(os.Getpid()),
+				"process.runtime.name": "go",
+				"k8s.pod.name":         "oms-7d9f",
+			},
+			absent: []string{"process.command_line", "process.command_args"},
+		},
+		{
+			name: "explicit attributes win",
+			cfg: Config{
+				ServiceName:        "oms",
+				ResourceAttributes: map[string]string{"k8s.pod.name": "explicit"},
+				Detectors:          DetectorsConfig{Kubernetes: true, Build: true},
+			},
+			want: map[string]string{"k8s.pod.name": "explicit", "ampy.build.go_version": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := newResource(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set := res.Set()
+			for k, want := range tt.want {
+				v, ok := set.Value(attribute.Key(k))
+				if !ok || (want != "" && v.Emit() != want) {
+					t.Errorf("%s = %q (set %v), want %q", k, v.Emit(), ok, want)
+				}
+			}
+			for _, k := range tt.absent {
+				if set.HasValue(attribute.Key(k)) {
+					t.Errorf("%s detected", k)
+				}
+			}
+		})
+	}
+}
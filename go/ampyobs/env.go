@@ -0,0 +1,604 @@
+package ampyobs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard OpenTelemetry environment variables honored by ConfigFromEnv.
+const (
+	EnvOTELServiceName        = "OTEL_SERVICE_NAME"
+	EnvOTELExporterEndpoint   = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvOTELExporterProtocol   = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvOTELTracesEndpoint     = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvOTELTracesProtocol     = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	EnvOTELMetricsEndpoint    = "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"
+	EnvOTELMetricsProtocol    = "OTEL_EXPORTER_OTLP_METRICS_PROTOCOL"
+	EnvOTELLogsEndpoint       = "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"
+	EnvOTELLogsProtocol       = "OTEL_EXPORTER_OTLP_LOGS_PROTOCOL"
+	EnvOTELCertificate        = "OTEL_EXPORTER_OTLP_CERTIFICATE"
+	EnvOTELClientCertificate  = "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"
+	EnvOTELClientKey          = "OTEL_EXPORTER_OTLP_CLIENT_KEY"
+	EnvOTELHeaders            = "OTEL_EXPORTER_OTLP_HEADERS"
+	EnvOTELCompression        = "OTEL_EXPORTER_OTLP_COMPRESSION"
+	EnvOTELTimeout            = "OTEL_EXPORTER_OTLP_TIMEOUT" // milliseconds
+	EnvOTELTracesExporter     = "OTEL_TRACES_EXPORTER"       // otlp | console | none
+	EnvOTELMetricsExporter    = "OTEL_METRICS_EXPORTER"      // otlp | console | none
+	EnvOTELLogsExporter       = "OTEL_LOGS_EXPORTER"         // otlp | console | none
+	EnvOTELBSPScheduleDelay   = "OTEL_BSP_SCHEDULE_DELAY"    // milliseconds
+	EnvOTELBSPExportTimeout   = "OTEL_BSP_EXPORT_TIMEOUT"    // milliseconds
+	EnvOTELBSPMaxQueueSize    = "OTEL_BSP_MAX_QUEUE_SIZE"
+	EnvOTELBSPMaxBatchSize    = "OTEL_BSP_MAX_EXPORT_BATCH_SIZE"
+	EnvOTELMetricInterval     = "OTEL_METRIC_EXPORT_INTERVAL" // milliseconds
+	EnvOTELMetricTimeout      = "OTEL_METRIC_EXPORT_TIMEOUT"  // milliseconds
+	EnvOTELTracesSampler      = "OTEL_TRACES_SAMPLER"
+	EnvOTELTracesSamplerArg   = "OTEL_TRACES_SAMPLER_ARG"
+	EnvOTELResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+)
+
+// AmpyFin-specific overrides honored by ConfigFromEnv.
+const (
+	EnvAmpyEnvironment      = "AMPY_ENVIRONMENT"
+	EnvAmpyEnableLogs       = "AMPY_ENABLE_LOGS"
+	EnvAmpyLogStdout        = "AMPY_LOG_STDOUT" // false: with logs enabled, export over OTLP only
+	EnvAmpyLogLevel         = "AMPY_LOG_LEVEL"
+	EnvAmpyLogLevels        = "AMPY_LOG_LEVELS" // per logger, e.g. oms=debug,md=warn
+	EnvAmpyLogSampling      = "AMPY_LOG_SAMPLING"
+	EnvAmpyLogRateLimit     = "AMPY_LOG_RATE_LIMIT" // records per second
+	EnvAmpyEnableMetrics    = "AMPY_ENABLE_METRICS"
+	EnvAmpyEnableTracing    = "AMPY_ENABLE_TRACING"
+	EnvAmpyEnablePrometheus = "AMPY_ENABLE_PROMETHEUS"
+	EnvAmpyHeadersFile      = "AMPY_EXPORTER_HEADERS_FILE"
+	EnvAmpyWALDir           = "AMPY_WAL_DIR"
+	EnvAmpyExportFile       = "AMPY_EXPORT_FILE"
+	EnvAmpyExportFormat     = "AMPY_EXPORT_FORMAT"
+	EnvAmpyPipelineProfile  = "AMPY_PIPELINE_PROFILE"
+	EnvAmpyReplayRunID      = "AMPY_REPLAY_RUN_ID"
+	EnvAmpyEndpoints        = "AMPY_EXPORTER_OTLP_ENDPOINTS" // comma-separated, primary first
+	EnvAmpyRoutingPolicy    = "AMPY_EXPORTER_ROUTING"        // failover | fanout
+	EnvAmpyDetectors        = "AMPY_RESOURCE_DETECTORS"      // e.g. host,os,process,container,k8s,build or all
+	EnvAmpyTailSampling     = "AMPY_TAIL_SAMPLING"
+	EnvAmpyTailSampleRatio  = "AMPY_TAIL_SAMPLING_RATIO"
+	EnvAmpySamplingRules    = "AMPY_SAMPLING_RULES"      // inline JSON, see SamplingRules
+	EnvAmpySamplingRuleFile = "AMPY_SAMPLING_RULES_FILE" // JSON file, see SamplingRules
+	EnvAmpySamplingRemote   = "AMPY_SAMPLING_REMOTE_URL"
+	EnvAmpySamplingPoll     = "AMPY_SAMPLING_REMOTE_INTERVAL" // milliseconds
+	EnvAmpyRateLimit        = "AMPY_SAMPLER_RATE_LIMIT"       // traces per second
+	EnvAmpyRateLimitBurst   = "AMPY_SAMPLER_RATE_LIMIT_BURST"
+)
+
+// ConfigFromEnv builds a Config from OTEL_* and AMPY_* environment variables.
+// Unset variables leave the corresponding field at its zero value. Malformed
+// ones are skipped and reported as *ConfigError values, joined with
+// errors.Join, whose Field is the variable name; the rest of the Config is
+// still returned.
+func ConfigFromEnv() (Config, error) {
+	return configFromLookup(os.LookupEnv)
+}
+
+func configFromLookup(lookup func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	var errs []error
+	bad := func(key string, value any, format string, args ...any) {
+		errs = append(errs, &ConfigError{Field: key, Value: value, Err: fmt.Errorf("%w: "+format, append([]any{ErrInvalidEnvVar}, args...)...)})
+	}
+
+	get := func(key string) (string, bool) {
+		v, ok := lookup(key)
+		v = strings.TrimSpace(v)
+		return v, ok && v != ""
+	}
+
+	if v, ok := get(EnvOTELResourceAttributes); ok {
+		attrs, err := parseResourceAttributes(v)
+		if err != nil {
+			bad(EnvOTELResourceAttributes, v, "%v", err)
+		}
+		cfg.ResourceAttributes = attrs
+		// Well-known keys fill the dedicated fields; the explicit variables below win.
+		cfg.ServiceName = attrs["service.name"]
+		cfg.ServiceVersion = attrs["service.version"]
+		cfg.Environment = attrs["deployment.environment.name"]
+		if cfg.Environment == "" {
+			cfg.Environment = attrs["deployment.environment"]
+		}
+	}
+	if v, ok := get(EnvOTELServiceName); ok {
+		cfg.ServiceName = v
+	}
+	if v, ok := get(EnvAmpyEnvironment); ok {
+		cfg.Environment = v
+	}
+	if v, ok := get(EnvOTELExporterEndpoint); ok {
+		cfg.CollectorEndpoint = v
+	}
+	if v, ok := get(EnvOTELTracesEndpoint); ok {
+		cfg.TraceEndpoint = v
+	}
+	if v, ok := get(EnvOTELMetricsEndpoint); ok {
+		cfg.MetricEndpoint = v
+	}
+	if v, ok := get(EnvOTELLogsEndpoint); ok {
+		cfg.LogEndpoint = v
+	}
+	if v, ok := get(EnvOTELCertificate); ok {
+		cfg.TLS.CAFile = v
+	}
+	if v, ok := get(EnvOTELClientCertificate); ok {
+		cfg.TLS.CertFile = v
+	}
+	if v, ok := get(EnvOTELClientKey); ok {
+		cfg.TLS.KeyFile = v
+	}
+	if v, ok := get(EnvOTELHeaders); ok {
+		h, err := parseHeaders(v)
+		if err != nil {
+			// Do not echo the value: it most likely holds a secret.
+			bad(EnvOTELHeaders, "<redacted>", "malformed header list")
+		}
+		cfg.Headers = h
+	}
+	if v, ok := get(EnvAmpyHeadersFile); ok {
+		cfg.HeadersFile = v
+	}
+	if v, ok := get(EnvAmpyWALDir); ok {
+		cfg.WAL.Dir = v
+	}
+	if v, ok := get(EnvAmpyExportFile); ok {
+		cfg.ExportFile = v
+	}
+	if v, ok := get(EnvAmpyExportFormat); ok {
+		cfg.ExportFormat = strings.ToLower(v)
+	}
+	if v, ok := get(EnvOTELCompression); ok {
+		cfg.Compression = strings.ToLower(v)
+	}
+	if v, ok := get(EnvOTELTimeout); ok {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms < 0 {
+			bad(EnvOTELTimeout, v, "want milliseconds")
+		} else {
+			cfg.ExportTimeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v, ok := get(EnvAmpyPipelineProfile); ok {
+		cfg.Pipeline.Profile = strings.ToLower(v)
+	}
+	if v, ok := get(EnvAmpyReplayRunID); ok {
+		cfg.Replay.RunID = v
+	}
+	if v, ok := get(EnvAmpyEndpoints); ok {
+		for _, ep := range strings.Split(v, ",") {
+			if ep = strings.TrimSpace(ep); ep != "" {
+				cfg.Routing.Endpoints = append(cfg.Routing.Endpoints, ep)
+			}
+		}
+	}
+	if v, ok := get(EnvAmpyRoutingPolicy); ok {
+		cfg.Routing.Policy = strings.ToLower(v)
+	}
+	if v, ok := get(EnvAmpyTailSampleRatio); ok {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			bad(EnvAmpyTailSampleRatio, v, "%v", err)
+		} else {
+			cfg.TailSampling.Ratio = ratio
+		}
+	}
+	if v, ok := get(EnvAmpyLogRateLimit); ok {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			bad(EnvAmpyLogRateLimit, v, "%v", err)
+		} else {
+			cfg.LogSampling.RateLimit = rate
+		}
+	}
+	if v, ok := get(EnvAmpySamplingRules); ok {
+		rules, err := parseSamplingRules([]byte(v))
+		if err != nil {
+			bad(EnvAmpySamplingRules, v, "%v", err)
+		} else {
+			cfg.SamplingRules = rules
+		}
+	}
+	if v, ok := get(EnvAmpySamplingRuleFile); ok {
+		cfg.SamplingRulesFile = v
+	}
+	if v, ok := get(EnvAmpySamplingRemote); ok {
+		cfg.RemoteSampling.URL = v
+	}
+	if v, ok := get(EnvAmpyRateLimit); ok {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			bad(EnvAmpyRateLimit, v, "%v", err)
+		} else {
+			cfg.RateLimit = rate
+		}
+	}
+	if v, ok := get(EnvAmpyLogLevel); ok {
+		cfg.LogLevel = v
+	}
+	if v, ok := get(EnvAmpyLogLevels); ok {
+		levels, err := parseResourceAttributes(v)
+		if err != nil {
+			bad(EnvAmpyLogLevels, v, "%v", err)
+		}
+		cfg.LogLevels = levels
+	}
+	if v, ok := get(EnvAmpyDetectors); ok {
+		d, known := parseDetectors(v)
+		if !known {
+			bad(EnvAmpyDetectors, v, "use host, os, process, container, k8s, build or all")
+		} else {
+			cfg.Detectors = d
+		}
+	}
+	for _, d := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{EnvOTELBSPScheduleDelay, &cfg.Pipeline.BatchTimeout},
+		{EnvOTELBSPExportTimeout, &cfg.Pipeline.BatchExportTimeout},
+		{EnvOTELMetricInterval, &cfg.Pipeline.MetricInterval},
+		{EnvOTELMetricTimeout, &cfg.Pipeline.MetricTimeout},
+		{EnvAmpySamplingPoll, &cfg.RemoteSampling.PollInterval},
+	} {
+		if v, ok := get(d.key); ok {
+			ms, err := strconv.Atoi(v)
+			if err != nil || ms < 0 {
+				bad(d.key, v, "want milliseconds")
+				continue
+			}
+			*d.dst = time.Duration(ms) * time.Millisecond
+		}
+	}
+	for _, n := range []struct {
+		key string
+		dst *int
+	}{
+		{EnvOTELBSPMaxQueueSize, &cfg.Pipeline.MaxQueueSize},
+		{EnvOTELBSPMaxBatchSize, &cfg.Pipeline.MaxExportBatchSize},
+		{EnvAmpyRateLimitBurst, &cfg.RateLimitBurst},
+	} {
+		if v, ok := get(n.key); ok {
+			size, err := strconv.Atoi(v)
+			if err != nil || size < 0 {
+				bad(n.key, v, "want a non-negative integer")
+				continue
+			}
+			*n.dst = size
+		}
+	}
+	for _, p := range []struct {
+		key string
+		dst *string
+	}{
+		{EnvOTELExporterProtocol, &cfg.Protocol},
+		{EnvOTELTracesProtocol, &cfg.TraceProtocol},
+		{EnvOTELMetricsProtocol, &cfg.MetricProtocol},
+		{EnvOTELLogsProtocol, &cfg.LogProtocol},
+	} {
+		if v, ok := get(p.key); ok {
+			proto, known := normalizeProtocol(v)
+			if !known {
+				bad(p.key, v, "use grpc, http/protobuf, http/json, stdout, file or none")
+				continue
+			}
+			*p.dst = proto
+		}
+	}
+	// OTEL_*_EXPORTER picks the exporter kind; "otlp" keeps the protocol above.
+	for _, e := range []struct {
+		key string
+		dst *string
+	}{
+		{EnvOTELTracesExporter, &cfg.TraceProtocol},
+		{EnvOTELMetricsExporter, &cfg.MetricProtocol},
+		{EnvOTELLogsExporter, &cfg.LogProtocol},
+	} {
+		if v, ok := get(e.key); ok {
+			switch strings.ToLower(v) {
+			case "otlp":
+			case "console":
+				*e.dst = ProtocolStdout
+			case "none":
+				*e.dst = ProtocolNone
+			default:
+				bad(e.key, v, "use otlp, console or none")
+			}
+		}
+	}
+	if v, ok := get(EnvOTELTracesSampler); ok {
+		switch strings.ToLower(v) {
+		case "always_on", "parentbased_always_on":
+			cfg.Sampler = "always_on"
+		case "always_off", "parentbased_always_off":
+			cfg.Sampler = "always_off"
+		case "traceidratio", "parentbased_traceidratio":
+			cfg.Sampler = "ratio"
+			cfg.SampleRatio = 1.0 // spec default when the arg is absent
+		default:
+			bad(EnvOTELTracesSampler, v, "use always_on, always_off, traceidratio or their parentbased_ forms")
+		}
+	}
+	if v, ok := get(EnvOTELTracesSamplerArg); ok && cfg.Sampler == "ratio" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			bad(EnvOTELTracesSamplerArg, v, "%v", err)
+		} else {
+			cfg.SampleRatio = ratio
+		}
+	}
+
+	for _, b := range []struct {
+		key string
+		dst *bool
+	}{
+		{EnvAmpyEnableLogs, &cfg.EnableLogs},
+		{EnvAmpyEnableMetrics, &cfg.EnableMetrics},
+		{EnvAmpyEnableTracing, &cfg.EnableTracing},
+		{EnvAmpyEnablePrometheus, &cfg.EnablePrometheus},
+		{EnvAmpyTailSampling, &cfg.TailSampling.Enabled},
+		{EnvAmpyLogSampling, &cfg.LogSampling.Enabled},
+	} {
+		if v, ok := get(b.key); ok {
+			on, err := strconv.ParseBool(v)
+			if err != nil {
+				bad(b.key, v, "want true or false")
+				continue
+			}
+			*b.dst = on
+		}
+	}
+
+	if v, ok := get(EnvAmpyLogStdout); ok {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			bad(EnvAmpyLogStdout, v, "want true or false")
+		} else {
+			cfg.DisableLogStdout = !on
+		}
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+// parseResourceAttributes parses the W3C-baggage-like "k1=v1,k2=v2" format
+// used by OTEL_RESOURCE_ATTRIBUTES. Values may be percent-encoded.
+func parseResourceAttributes(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return out, fmt.Errorf("invalid attribute %q", pair)
+		}
+		dec, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return out, fmt.Errorf("invalid value for %q: %w", k, err)
+		}
+		out[k] = dec
+	}
+	return out, nil
+}
+
+// withEnv fills fields left unset in c from env. Explicit struct fields win;
+// booleans can only be switched on by env since false is indistinguishable
+// from unset. Sampler and SampleRatio are taken together.
+func (c Config) withEnv(env Config) Config {
+	if c.ServiceName == "" {
+		c.ServiceName = env.ServiceName
+	}
+	if c.ServiceVersion == "" {
+		c.ServiceVersion = env.ServiceVersion
+	}
+	if c.Environment == "" {
+		c.Environment = env.Environment
+	}
+	if c.CollectorEndpoint == "" {
+		c.CollectorEndpoint = env.CollectorEndpoint
+	}
+	if c.TraceEndpoint == "" {
+		c.TraceEndpoint = env.TraceEndpoint
+	}
+	if c.MetricEndpoint == "" {
+		c.MetricEndpoint = env.MetricEndpoint
+	}
+	if c.Protocol == "" {
+		c.Protocol = env.Protocol
+	}
+	if c.TraceProtocol == "" {
+		c.TraceProtocol = env.TraceProtocol
+	}
+	if c.MetricProtocol == "" {
+		c.MetricProtocol = env.MetricProtocol
+	}
+	if c.LogEndpoint == "" {
+		c.LogEndpoint = env.LogEndpoint
+	}
+	if c.LogProtocol == "" {
+		c.LogProtocol = env.LogProtocol
+	}
+	if c.TLS.CAFile == "" && len(c.TLS.CAPEM) == 0 {
+		c.TLS.CAFile = env.TLS.CAFile
+	}
+	if !c.TLS.hasClientCert() && !c.TLS.hasClientKey() {
+		c.TLS.CertFile = env.TLS.CertFile
+		c.TLS.KeyFile = env.TLS.KeyFile
+	}
+	if c.HeadersFile == "" {
+		c.HeadersFile = env.HeadersFile
+	}
+	if c.WAL.Dir == "" {
+		c.WAL.Dir = env.WAL.Dir
+	}
+	if c.ExportFile == "" {
+		c.ExportFile = env.ExportFile
+	}
+	if c.ExportFormat == "" {
+		c.ExportFormat = env.ExportFormat
+	}
+	if c.Compression == "" {
+		c.Compression = env.Compression
+	}
+	if c.ExportTimeout == 0 {
+		c.ExportTimeout = env.ExportTimeout
+	}
+	if c.Pipeline.Profile == "" {
+		c.Pipeline.Profile = env.Pipeline.Profile
+	}
+	c.Pipeline = c.Pipeline.or(env.Pipeline)
+	if c.Replay.RunID == "" {
+		c.Replay.RunID = env.Replay.RunID
+	}
+	if len(c.Routing.Endpoints) == 0 {
+		c.Routing.Endpoints = env.Routing.Endpoints
+	}
+	if c.Routing.Policy == "" {
+		c.Routing.Policy = env.Routing.Policy
+	}
+	if !c.Detectors.any() {
+		c.Detectors = env.Detectors
+	}
+	if len(c.SamplingRules.Rules) == 0 && c.SamplingRules.Fallback == nil {
+		c.SamplingRules = env.SamplingRules
+	}
+	if c.SamplingRulesFile == "" {
+		c.SamplingRulesFile = env.SamplingRulesFile
+	}
+	if c.RemoteSampling.URL == "" {
+		c.RemoteSampling.URL = env.RemoteSampling.URL
+	}
+	if c.RemoteSampling.PollInterval == 0 {
+		c.RemoteSampling.PollInterval = env.RemoteSampling.PollInterval
+	}
+	if c.TailSampling.Ratio == 0 {
+		c.TailSampling.Ratio = env.TailSampling.Ratio
+	}
+	if c.Sampler == "" {
+		c.Sampler = env.Sampler
+		c.SampleRatio = env.SampleRatio
+	}
+	if c.RateLimit == 0 {
+		c.RateLimit, c.RateLimitBurst = env.RateLimit, env.RateLimitBurst
+	}
+	c.EnableLogs = c.EnableLogs || env.EnableLogs
+	c.DisableLogStdout = c.DisableLogStdout || env.DisableLogStdout
+	c.EnableMetrics = c.EnableMetrics || env.EnableMetrics
+	c.EnableTracing = c.EnableTracing || env.EnableTracing
+	c.EnablePrometheus = c.EnablePrometheus || env.EnablePrometheus
+	c.TailSampling.Enabled = c.TailSampling.Enabled || env.TailSampling.Enabled
+	c.LogSampling.Enabled = c.LogSampling.Enabled || env.LogSampling.Enabled
+	if c.LogSampling.RateLimit == 0 {
+		c.LogSampling.RateLimit = env.LogSampling.RateLimit
+	}
+
+	if len(env.ResourceAttributes) > 0 {
+		merged := maps.Clone(env.ResourceAttributes)
+		maps.Copy(merged, c.ResourceAttributes)
+		c.ResourceAttributes = merged
+	}
+	if len(env.Headers) > 0 {
+		merged := maps.Clone(env.Headers)
+		maps.Copy(merged, c.Headers)
+		c.Headers = merged
+	}
+	if c.LogLevel == "" {
+		c.LogLevel = env.LogLevel
+	}
+	if len(env.LogLevels) > 0 {
+		merged := maps.Clone(env.LogLevels)
+		maps.Copy(merged, c.LogLevels)
+		c.LogLevels = merged
+	}
+	return c
+}
+
+// overridesEnv reports whether an explicit field of c wins over the
+// variable key in withEnv, so that a malformed value there is irrelevant.
+// Variables merged into maps (resource attributes, headers, log levels) are
+// never overridden.
+func (c Config) overridesEnv(key string) bool {
+	switch key {
+	case EnvOTELTimeout:
+		return c.ExportTimeout != 0
+	case EnvOTELExporterProtocol:
+		return c.Protocol != ""
+	case EnvOTELTracesProtocol, EnvOTELTracesExporter:
+		return c.TraceProtocol != ""
+	case EnvOTELMetricsProtocol, EnvOTELMetricsExporter:
+		return c.MetricProtocol != ""
+	case EnvOTELLogsProtocol, EnvOTELLogsExporter:
+		return c.LogProtocol != ""
+	case EnvOTELTracesSampler, EnvOTELTracesSamplerArg:
+		return c.Sampler != ""
+	case EnvOTELBSPScheduleDelay:
+		return c.Pipeline.BatchTimeout != 0
+	case EnvOTELBSPExportTimeout:
+		return c.Pipeline.BatchExportTimeout != 0
+	case EnvOTELBSPMaxQueueSize:
+		return c.Pipeline.MaxQueueSize != 0
+	case EnvOTELBSPMaxBatchSize:
+		return c.Pipeline.MaxExportBatchSize != 0
+	case EnvOTELMetricInterval:
+		return c.Pipeline.MetricInterval != 0
+	case EnvOTELMetricTimeout:
+		return c.Pipeline.MetricTimeout != 0
+	case EnvAmpyDetectors:
+		return c.Detectors.any()
+	case EnvAmpyTailSampleRatio:
+		return c.TailSampling.Ratio != 0
+	case EnvAmpyLogRateLimit:
+		return c.LogSampling.RateLimit != 0
+	case EnvAmpySamplingRules:
+		return len(c.SamplingRules.Rules) > 0 || c.SamplingRules.Fallback != nil
+	case EnvAmpySamplingPoll:
+		return c.RemoteSampling.PollInterval != 0
+	case EnvAmpyRateLimit, EnvAmpyRateLimitBurst:
+		return c.RateLimit != 0
+	// Booleans can only be switched on by env.
+	case EnvAmpyEnableLogs:
+		return c.EnableLogs
+	case EnvAmpyLogStdout:
+		return c.DisableLogStdout
+	case EnvAmpyEnableMetrics:
+		return c.EnableMetrics
+	case EnvAmpyEnableTracing:
+		return c.EnableTracing
+	case EnvAmpyEnablePrometheus:
+		return c.EnablePrometheus
+	case EnvAmpyTailSampling:
+		return c.TailSampling.Enabled
+	case EnvAmpyLogSampling:
+		return c.LogSampling.Enabled
+	}
+	return false
+}
+
+// clone returns a copy of c that shares no mutable state with it.
+func (c Config) clone() Config {
+	c.ResourceAttributes = maps.Clone(c.ResourceAttributes)
+	c.Headers = maps.Clone(c.Headers)
+	c.LogLevels = maps.Clone(c.LogLevels)
+	c.TLS.CAPEM = bytes.Clone(c.TLS.CAPEM)
+	c.TLS.CertPEM = bytes.Clone(c.TLS.CertPEM)
+	c.TLS.KeyPEM = bytes.Clone(c.TLS.KeyPEM)
+	c.SpanProcessors = slices.Clone(c.SpanProcessors)
+	c.MetricReaders = slices.Clone(c.MetricReaders)
+	c.LogProcessors = slices.Clone(c.LogProcessors)
+	c.Routing = c.Routing.clone()
+	c.SamplingRules = c.SamplingRules.clone()
+	return c
+}
@@ -0,0 +1,259 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestConfigFromLookup(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		check func(t *testing.T, c Config)
+	}{
+		{
+			name: "service and resource attributes",
+			env: map[string]string{
+				EnvOTELResourceAttributes: "service.name=from-attrs,service.version=1.2.3,deployment.environment=paper,team=exec%20desk",
+				EnvOTELServiceName:        "oms",
+			},
+			check: func(t *testing.T, c Config) {
+				if c.ServiceName != "oms" || c.ServiceVersion != "1.2.3" || c.Environment != "paper" {
+					t.Errorf("service = %q %q %q", c.ServiceName, c.ServiceVersion, c.Environment)
+				}
+				if c.ResourceAttributes["team"] != "exec desk" {
+					t.Errorf("team = %q", c.ResourceAttributes["team"])
+				}
+			},
+		},
+		{
+			name: "endpoints and protocols",
+			env: map[string]string{
+				EnvOTELExporterEndpoint: "http://collector:4318",
+				EnvOTELExporterProtocol: "http",
+				EnvOTELTracesProtocol:   "grpc",
+				EnvOTELMetricsEndpoint:  "http://metrics:4318/v1/metrics",
+				EnvOTELLogsExporter:     "console",
+			},
+			check: func(t *testing.T, c Config) {
+				if c.CollectorEndpoint != "http://collector:4318" || c.MetricEndpoint != "http://metrics:4318/v1/metrics" {
+					t.Errorf("endpoints = %q %q", c.CollectorEndpoint, c.MetricEndpoint)
+				}
+				if c.Protocol != ProtocolHTTPProtobuf || c.TraceProtocol != ProtocolGRPC || c.LogProtocol != ProtocolStdout {
+					t.Errorf("protocols = %q %q %q", c.Protocol, c.TraceProtocol, c.LogProtocol)
+				}
+			},
+		},
+		{
+			name: "durations and sizes",
+			env: map[string]string{
+				EnvOTELTimeout:          "2500",
+				EnvOTELBSPScheduleDelay: "200",
+				EnvOTELBSPMaxQueueSize:  "4096",
+				EnvOTELMetricInterval:   "1000",
+			},
+			check: func(t *testing.T, c Config) {
+				if c.ExportTimeout != 2500*time.Millisecond || c.Pipeline.BatchTimeout != 200*time.Millisecond ||
+					c.Pipeline.MaxQueueSize != 4096 || c.Pipeline.MetricInterval != time.Second {
+					t.Errorf("got %v %v %d %v", c.ExportTimeout, c.Pipeline.BatchTimeout, c.Pipeline.MaxQueueSize, c.Pipeline.MetricInterval)
+				}
+			},
+		},
+		{
+			name: "sampler with arg",
+			env:  map[string]string{EnvOTELTracesSampler: "parentbased_traceidratio", EnvOTELTracesSamplerArg: "0.1"},
+			check: func(t *testing.T, c Config) {
+				if c.Sampler != "ratio" || c.SampleRatio != 0.1 {
+					t.Errorf("sampler = %q %v", c.Sampler, c.SampleRatio)
+				}
+			},
+		},
+		{
+			name: "ratio sampler defaults to 1",
+			env:  map[string]string{EnvOTELTracesSampler: "traceidratio"},
+			check: func(t *testing.T, c Config) {
+				if c.SampleRatio != 1 {
+					t.Errorf("ratio = %v", c.SampleRatio)
+				}
+			},
+		},
+		{
+			name: "booleans, headers and levels",
+			env: map[string]string{
+				EnvAmpyEnableTracing: "true",
+				EnvAmpyEnableLogs:    "1",
+				EnvAmpyLogStdout:     "false",
+				EnvOTELHeaders:       "x-tenant=ampyfin,authorization=Bearer%20abc",
+				EnvAmpyLogLevels:     "oms=debug,md=warn",
+			},
+			check: func(t *testing.T, c Config) {
+				if !c.EnableTracing || !c.EnableLogs || c.EnableMetrics || !c.DisableLogStdout {
+					t.Errorf("enable = %v %v %v, stdout off %v", c.EnableTracing, c.EnableLogs, c.EnableMetrics, c.DisableLogStdout)
+				}
+				if c.Headers["authorization"] != "Bearer abc" || c.Headers["x-tenant"] != "ampyfin" {
+					t.Errorf("headers = %v", c.Headers)
+				}
+				if !reflect.DeepEqual(c.LogLevels, map[string]string{"oms": "debug", "md": "warn"}) {
+					t.Errorf("levels = %v", c.LogLevels)
+				}
+			},
+		},
+		{
+			name: "blank values are unset",
+			env:  map[string]string{EnvOTELServiceName: "  ", EnvOTELTimeout: ""},
+			check: func(t *testing.T, c Config) {
+				if c.ServiceName != "" || c.ExportTimeout != 0 {
+					t.Errorf("got %q %v", c.ServiceName, c.ExportTimeout)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := configFromLookup(lookupFrom(tt.env))
+			if err != nil {
+				t.Fatalf("configFromLookup: %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestConfigFromLookupErrors(t *testing.T) {
+	env := map[string]string{
+		EnvOTELServiceName:      "oms",
+		EnvOTELTimeout:          "soon",
+		EnvOTELBSPMaxQueueSize:  "-1",
+		EnvOTELExporterProtocol: "carrier-pigeon",
+		EnvAmpyEnableMetrics:    "sure",
+		EnvOTELHeaders:          "authorization",
+	}
+	c, err := configFromLookup(lookupFrom(env))
+	if c.ServiceName != "oms" {
+		t.Errorf("valid variables should still apply, ServiceName = %q", c.ServiceName)
+	}
+	var fields []string
+	for _, e := range unjoin(err) {
+		var ce *ConfigError
+		if !errors.As(e, &ce) || !errors.Is(e, ErrInvalidEnvVar) {
+			t.Fatalf("error %v is not a *ConfigError wrapping ErrInvalidEnvVar", e)
+		}
+		fields = append(fields, ce.Field)
+	}
+	want := []string{EnvOTELHeaders, EnvOTELTimeout, EnvOTELBSPMaxQueueSize, EnvOTELExporterProtocol, EnvAmpyEnableMetrics}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+	if strings.Contains(err.Error(), "authorization") {
+		t.Errorf("header value leaked into %q", err)
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	env := Config{
+		ServiceName:        "env-svc",
+		Environment:        "prod",
+		CollectorEndpoint:  "env:4317",
+		Sampler:            "ratio",
+		SampleRatio:        0.5,
+		EnableMetrics:      true,
+		ExportTimeout:      time.Second,
+		Headers:            map[string]string{"a": "env", "b": "env"},
+		ResourceAttributes: map[string]string{"team": "env"},
+	}
+	explicit := Config{
+		ServiceName: "explicit",
+		Sampler:     "always_on",
+		Headers:     map[string]string{"a": "explicit"},
+	}
+	got := explicit.withEnv(env)
+	if got.ServiceName != "explicit" || got.Environment != "prod" || got.CollectorEndpoint != "env:4317" {
+		t.Errorf("strings = %q %q %q", got.ServiceName, got.Environment, got.CollectorEndpoint)
+	}
+	if got.Sampler != "always_on" || got.SampleRatio != 0 {
+		t.Errorf("sampler = %q %v, want explicit sampler without the env ratio", got.Sampler, got.SampleRatio)
+	}
+	if !got.EnableMetrics || got.ExportTimeout != time.Second {
+		t.Errorf("env defaults not applied: %v %v", got.EnableMetrics, got.ExportTimeout)
+	}
+	if !reflect.DeepEqual(got.Headers, map[string]string{"a": "explicit", "b": "env"}) {
+		t.Errorf("headers = %v", got.Headers)
+	}
+	if explicit.Headers["b"] != "" {
+		t.Error("withEnv modified the explicit headers")
+	}
+}
+
+func TestNewEnvErrors(t *testing.T) {
+	base := Config{ServiceName: "svc", Environment: "dev"}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		cfg     func(Config) Config
+		wantErr bool
+	}{
+		{
+			name:    "strict",
+			env:     map[string]string{EnvOTELTimeout: "soon"},
+			wantErr: true,
+		},
+		{
+			name: "lenient",
+			env:  map[string]string{EnvOTELTimeout: "soon"},
+			cfg:  func(c Config) Config { c.LenientValidation = true; return c },
+		},
+		{
+			name: "overridden by explicit field",
+			env:  map[string]string{EnvOTELTimeout: "soon", EnvOTELTracesSampler: "coin"},
+			cfg:  func(c Config) Config { c.ExportTimeout = time.Second; c.Sampler = "always_on"; return c },
+		},
+		{
+			name:    "bool left false does not override",
+			env:     map[string]string{EnvAmpyEnableMetrics: "sure"},
+			wantErr: true,
+		},
+		{
+			name: "overridden bool",
+			env:  map[string]string{EnvAmpyEnablePrometheus: "sure"},
+			cfg:  func(c Config) Config { c.EnablePrometheus = true; return c },
+		},
+		{
+			name:    "merged map is never overridden",
+			env:     map[string]string{EnvOTELResourceAttributes: "=x"},
+			cfg:     func(c Config) Config { c.ResourceAttributes = map[string]string{"team": "exec"}; return c },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := base
+			if tt.cfg != nil {
+				cfg = tt.cfg(cfg)
+			}
+			o, err := New(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidEnvVar) {
+				t.Errorf("error %v does not wrap ErrInvalidEnvVar", err)
+			}
+			if o != nil {
+				_ = o.Shutdown(context.Background())
+			}
+		})
+	}
+}
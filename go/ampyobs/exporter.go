@@ -0,0 +1,309 @@
+package ampyobs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// Protocols accepted by Config.Protocol and the per-signal overrides. The
+// first three are OTLP wire protocols; the rest export locally.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolHTTPJSON     = "http/json"
+	ProtocolStdout       = "stdout" // write to stdout (Config.ExportFormat, default pretty)
+	ProtocolFile         = "file"   // append to Config.ExportFile (default OTLP/JSON lines)
+	ProtocolNone         = "none"   // discard
+)
+
+// normalizeProtocol maps accepted spellings to a Protocol* constant.
+// "http" is kept as an alias of http/protobuf for older configs.
+func normalizeProtocol(p string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(p)) {
+	case "":
+		return "", true
+	case "grpc":
+		return ProtocolGRPC, true
+	case "http", "http/protobuf":
+		return ProtocolHTTPProtobuf, true
+	case "http/json":
+		return ProtocolHTTPJSON, true
+	case "stdout", "console":
+		return ProtocolStdout, true
+	case "file":
+		return ProtocolFile, true
+	case "none":
+		return ProtocolNone, true
+	default:
+		return "", false
+	}
+}
+
+// signalProtocol resolves the protocol for one signal: the per-signal
+// override, then the shared Protocol, then gRPC.
+func (c Config) signalProtocol(override string) string {
+	for _, p := range []string{override, c.Protocol} {
+		if n, ok := normalizeProtocol(p); ok && n != "" {
+			return n
+		}
+	}
+	return ProtocolGRPC
+}
+
+func isLocalProtocol(p string) bool {
+	return p == ProtocolStdout || p == ProtocolFile || p == ProtocolNone
+}
+
+// signalEndpoint resolves the endpoint for one signal. A per-signal endpoint
+// may carry a full URL path (e.g. ".../v1/traces") used by HTTP exporters;
+// as in the OTLP spec, a path on CollectorEndpoint is a base to which
+// signalPath (e.g. "/v1/traces") is appended. A bare "host:port" is dialed
+// with TLS when Config.TLS is set.
+func (c Config) signalEndpoint(override, protocol, signalPath string) endpoint {
+	var ep endpoint
+	if override != "" {
+		ep = parseEndpoint(override, protocol)
+	} else {
+		ep = parseEndpoint(c.CollectorEndpoint, protocol)
+		if ep.path != "" {
+			ep.path += signalPath
+		}
+	}
+	if ep.schemeless && c.TLS.enabled() {
+		ep.insecure = false
+	}
+	return ep
+}
+
+// exporterTLS returns the TLS client config shared by the exporters, or nil
+// to use the transport defaults.
+func (c Config) exporterTLS() (*tls.Config, error) {
+	if !c.TLS.enabled() {
+		return nil, nil
+	}
+	return newTLSClientConfig(c.TLS)
+}
+
+// grpcCredentials returns transport credentials for a secure gRPC exporter.
+func grpcCredentials(tc *tls.Config) credentials.TransportCredentials {
+	if tc == nil {
+		tc = &tls.Config{}
+	}
+	return credentials.NewTLS(tc)
+}
+
+// endpoint is a parsed collector address.
+type endpoint struct {
+	hostport   string
+	insecure   bool
+	schemeless bool   // given as "host:port" without http:// or https://
+	path       string // URL path for HTTP exporters; empty means the signal default
+}
+
+func parseEndpoint(raw, protocol string) endpoint {
+	if raw == "" {
+		if protocol == ProtocolGRPC {
+			return endpoint{hostport: "localhost:4317", insecure: true, schemeless: true}
+		}
+		return endpoint{hostport: "localhost:4318", insecure: true, schemeless: true}
+	}
+	if !strings.Contains(raw, "://") {
+		// likely "host:port"
+		host, port, _ := net.SplitHostPort(raw)
+		if port != "" && host == "" {
+			return endpoint{hostport: "localhost:" + port, insecure: true, schemeless: true}
+		}
+		return endpoint{hostport: raw, insecure: true, schemeless: true}
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return endpoint{hostport: raw, insecure: true}
+	}
+	ep := endpoint{hostport: u.Host, insecure: u.Scheme == "http"}
+	if p := strings.TrimRight(u.Path, "/"); p != "" {
+		ep.path = p
+	}
+	return ep
+}
+
+func newSpanExporter(cfg Config) (sdktrace.SpanExporter, error) {
+	protocol := cfg.signalProtocol(cfg.TraceProtocol)
+	if isLocalProtocol(protocol) {
+		return newLocalSpanExporter(cfg, protocol)
+	}
+	if rs := cfg.routes(cfg.Routing.TraceEndpoints, func(c *Config) *string { return &c.TraceEndpoint }); rs != nil {
+		return newRoutedSpanExporter(cfg, rs)
+	}
+	ep := cfg.signalEndpoint(cfg.TraceEndpoint, protocol, "/v1/traces")
+	tc, err := cfg.exporterTLS()
+	if err != nil {
+		return nil, err
+	}
+	es, err := cfg.exportSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case ProtocolHTTPProtobuf:
+		// HTTP exporter (port 4318)
+		opts := es.traceHTTPOptions()
+		opts = append(opts, otlptracehttp.WithEndpoint(ep.hostport))
+		if ep.path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(ep.path))
+		}
+		if ep.insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		} else if tc != nil {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tc))
+		}
+		exp, err := otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlptrace http exporter: %w", err)
+		}
+		return exp, nil
+	case ProtocolHTTPJSON:
+		return &jsonSpanExporter{c: newJSONHTTPClient(ep, "/v1/traces", tc, es)}, nil
+	case ProtocolGRPC:
+		// gRPC exporter (port 4317)
+		opts := es.traceGRPCOptions()
+		opts = append(opts, otlptracegrpc.WithEndpoint(ep.hostport))
+		if ep.insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlptracegrpc.WithTLSCredentials(grpcCredentials(tc)))
+		}
+		exp, err := otlptracegrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlptrace grpc exporter: %w", err)
+		}
+		return exp, nil
+	default:
+		return nil, fmt.Errorf("unsupported trace protocol: %s", protocol)
+	}
+}
+
+func newMetricExporter(cfg Config) (sdkmetric.Exporter, error) {
+	protocol := cfg.signalProtocol(cfg.MetricProtocol)
+	if isLocalProtocol(protocol) {
+		return newLocalMetricExporter(cfg, protocol)
+	}
+	if rs := cfg.routes(cfg.Routing.MetricEndpoints, func(c *Config) *string { return &c.MetricEndpoint }); rs != nil {
+		return newRoutedMetricExporter(cfg, rs)
+	}
+	ep := cfg.signalEndpoint(cfg.MetricEndpoint, protocol, "/v1/metrics")
+	tc, err := cfg.exporterTLS()
+	if err != nil {
+		return nil, err
+	}
+	es, err := cfg.exportSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case ProtocolHTTPProtobuf:
+		opts := es.metricHTTPOptions()
+		opts = append(opts, otlpmetrichttp.WithEndpoint(ep.hostport))
+		if ep.path != "" {
+			opts = append(opts, otlpmetrichttp.WithURLPath(ep.path))
+		}
+		if ep.insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		} else if tc != nil {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tc))
+		}
+		exp, err := otlpmetrichttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlpmetric http exporter: %w", err)
+		}
+		return exp, nil
+	case ProtocolHTTPJSON:
+		return &jsonMetricExporter{c: newJSONHTTPClient(ep, "/v1/metrics", tc, es)}, nil
+	case ProtocolGRPC:
+		opts := es.metricGRPCOptions()
+		opts = append(opts, otlpmetricgrpc.WithEndpoint(ep.hostport))
+		if ep.insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(grpcCredentials(tc)))
+		}
+		exp, err := otlpmetricgrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlpmetric grpc exporter: %w", err)
+		}
+		return exp, nil
+	default:
+		return nil, fmt.Errorf("unsupported metric protocol: %s", protocol)
+	}
+}
+
+func newLogExporter(cfg Config) (sdklog.Exporter, error) {
+	protocol := cfg.signalProtocol(cfg.LogProtocol)
+	if isLocalProtocol(protocol) {
+		return newLocalLogExporter(cfg, protocol)
+	}
+	if rs := cfg.routes(cfg.Routing.LogEndpoints, func(c *Config) *string { return &c.LogEndpoint }); rs != nil {
+		return newRoutedLogExporter(cfg, rs)
+	}
+	ep := cfg.signalEndpoint(cfg.LogEndpoint, protocol, "/v1/logs")
+	tc, err := cfg.exporterTLS()
+	if err != nil {
+		return nil, err
+	}
+	es, err := cfg.exportSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case ProtocolHTTPProtobuf:
+		opts := es.logHTTPOptions()
+		opts = append(opts, otlploghttp.WithEndpoint(ep.hostport))
+		if ep.path != "" {
+			opts = append(opts, otlploghttp.WithURLPath(ep.path))
+		}
+		if ep.insecure {
+			opts = append(opts, otlploghttp.WithInsecure())
+		} else if tc != nil {
+			opts = append(opts, otlploghttp.WithTLSClientConfig(tc))
+		}
+		exp, err := otlploghttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlplog http exporter: %w", err)
+		}
+		return exp, nil
+	case ProtocolHTTPJSON:
+		return &jsonLogExporter{c: newJSONHTTPClient(ep, "/v1/logs", tc, es)}, nil
+	case ProtocolGRPC:
+		opts := es.logGRPCOptions()
+		opts = append(opts, otlploggrpc.WithEndpoint(ep.hostport))
+		if ep.insecure {
+			opts = append(opts, otlploggrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlploggrpc.WithTLSCredentials(grpcCredentials(tc)))
+		}
+		exp, err := otlploggrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlplog grpc exporter: %w", err)
+		}
+		return exp, nil
+	default:
+		return nil, fmt.Errorf("unsupported log protocol: %s", protocol)
+	}
+}
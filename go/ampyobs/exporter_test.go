@@ -0,0 +1,81 @@
+package ampyobs
+
+import "testing"
+
+func TestSignalEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		override string
+		protocol string
+		want     endpoint
+	}{
+		{
+			name:     "default grpc",
+			protocol: ProtocolGRPC,
+			want:     endpoint{hostport: "localhost:4317", insecure: true, schemeless: true},
+		},
+		{
+			name:     "default http",
+			protocol: ProtocolHTTPProtobuf,
+			want:     endpoint{hostport: "localhost:4318", insecure: true, schemeless: true},
+		},
+		{
+			name:     "port only",
+			cfg:      Config{CollectorEndpoint: ":4317"},
+			protocol: ProtocolGRPC,
+			want:     endpoint{hostport: "localhost:4317", insecure: true, schemeless: true},
+		},
+		{
+			name:     "schemeless with TLS",
+			cfg:      Config{CollectorEndpoint: "collector:4317", TLS: TLSConfig{CAFile: "ca.pem"}},
+			protocol: ProtocolGRPC,
+			want:     endpoint{hostport: "collector:4317", schemeless: true},
+		},
+		{
+			name:     "https without path",
+			cfg:      Config{CollectorEndpoint: "https://collector:4318"},
+			protocol: ProtocolHTTPProtobuf,
+			want:     endpoint{hostport: "collector:4318"},
+		},
+		{
+			name:     "base path gets the signal suffix",
+			cfg:      Config{CollectorEndpoint: "http://gateway:8080/otlp/"},
+			protocol: ProtocolHTTPProtobuf,
+			want:     endpoint{hostport: "gateway:8080", insecure: true, path: "/otlp/v1/traces"},
+		},
+		{
+			name:     "per-signal endpoint is used as is",
+			cfg:      Config{CollectorEndpoint: "http://gateway:8080/otlp"},
+			override: "http://traces:4318/custom/traces",
+			protocol: ProtocolHTTPProtobuf,
+			want:     endpoint{hostport: "traces:4318", insecure: true, path: "/custom/traces"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cfg.signalEndpoint(tt.override, tt.protocol, "/v1/traces")
+			if got != tt.want {
+				t.Errorf("signalEndpoint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONClientURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"", "http://localhost:4318/v1/metrics"},
+		{"https://collector:4318", "https://collector:4318/v1/metrics"},
+		{"http://gateway/otlp", "http://gateway/otlp/v1/metrics"},
+	}
+	for _, tt := range tests {
+		cfg := Config{CollectorEndpoint: tt.endpoint}
+		c := newJSONHTTPClient(cfg.signalEndpoint("", ProtocolHTTPJSON, "/v1/metrics"), "/v1/metrics", nil, exportSettings{})
+		if c.url != tt.want {
+			t.Errorf("%q: url = %q, want %q", tt.endpoint, c.url, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package ampyobs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+)
+
+// Compression values accepted by Config.Compression.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// Retry defaults, matching the OTLP exporters.
+const (
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryMaxElapsedTime  = time.Minute
+	defaultExportTimeout        = 10 * time.Second
+)
+
+// RetryConfig is the backoff policy applied to failed exports. Zero
+// durations fall back to the OTLP defaults (5s initial, 30s max, 1m elapsed).
+type RetryConfig struct {
+	Disabled        bool          // send each batch once and drop it on failure
+	InitialInterval time.Duration // wait after the first failure
+	MaxInterval     time.Duration // cap on the exponential backoff
+	MaxElapsedTime  time.Duration // give up on a batch after this long
+}
+
+// withDefaults fills zero durations with the OTLP defaults.
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.InitialInterval <= 0 {
+		r.InitialInterval = defaultRetryInitialInterval
+	}
+	if r.MaxInterval <= 0 {
+		r.MaxInterval = defaultRetryMaxInterval
+	}
+	if r.MaxElapsedTime <= 0 {
+		r.MaxElapsedTime = defaultRetryMaxElapsedTime
+	}
+	return r
+}
+
+// exportSettings holds the options shared by every exporter, resolved once
+// per exporter from Config.
+type exportSettings struct {
+	headers map[string]string
+	gzip    bool
+	timeout time.Duration // zero keeps the exporter default
+	retry   RetryConfig
+}
+
+// exportSettings resolves headers (file first, then Config.Headers on top),
+// compression, timeout and retry for the exporters.
+func (c Config) exportSettings() (exportSettings, error) {
+	s := exportSettings{
+		gzip:    strings.EqualFold(c.Compression, CompressionGzip),
+		timeout: c.ExportTimeout,
+		retry:   c.Retry.withDefaults(),
+	}
+	if c.HeadersFile != "" {
+		h, err := readHeadersFile(c.HeadersFile)
+		if err != nil {
+			return s, err
+		}
+		s.headers = h
+	}
+	if len(c.Headers) > 0 {
+		if s.headers == nil {
+			s.headers = map[string]string{}
+		}
+		maps.Copy(s.headers, c.Headers)
+	}
+	return s, nil
+}
+
+func (s exportSettings) traceHTTPOptions() []otlptracehttp.Option {
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlptracehttp.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+func (s exportSettings) traceGRPCOptions() []otlptracegrpc.Option {
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlptracegrpc.WithCompressor(CompressionGzip))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlptracegrpc.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+func (s exportSettings) metricHTTPOptions() []otlpmetrichttp.Option {
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlpmetrichttp.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlpmetrichttp.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+func (s exportSettings) metricGRPCOptions() []otlpmetricgrpc.Option {
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlpmetricgrpc.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlpmetricgrpc.WithCompressor(CompressionGzip))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlpmetricgrpc.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+func (s exportSettings) logHTTPOptions() []otlploghttp.Option {
+	opts := []otlploghttp.Option{
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlploghttp.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlploghttp.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+func (s exportSettings) logGRPCOptions() []otlploggrpc.Option {
+	opts := []otlploggrpc.Option{
+		otlploggrpc.WithRetry(otlploggrpc.RetryConfig{
+			Enabled:         !s.retry.Disabled,
+			InitialInterval: s.retry.InitialInterval,
+			MaxInterval:     s.retry.MaxInterval,
+			MaxElapsedTime:  s.retry.MaxElapsedTime,
+		}),
+	}
+	if len(s.headers) > 0 {
+		opts = append(opts, otlploggrpc.WithHeaders(s.headers))
+	}
+	if s.gzip {
+		opts = append(opts, otlploggrpc.WithCompressor(CompressionGzip))
+	}
+	if s.timeout > 0 {
+		opts = append(opts, otlploggrpc.WithTimeout(s.timeout))
+	}
+	return opts
+}
+
+// readHeadersFile reads exporter headers from path. The file holds
+// "key=value" pairs, one per line or comma-separated as in
+// OTEL_EXPORTER_OTLP_HEADERS; blank lines and lines starting with "#" are
+// ignored. Values may be percent-encoded.
+func readHeadersFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("headers file: %w", err)
+	}
+	out := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		h, err := parseHeaders(line)
+		if err != nil {
+			// Do not echo the line: it most likely holds a secret.
+			return nil, fmt.Errorf("headers file %s: line %d: malformed header", path, n)
+		}
+		maps.Copy(out, h)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("headers file: %w", err)
+	}
+	return out, nil
+}
+
+// parseHeaders parses the "k1=v1,k2=v2" format of OTEL_EXPORTER_OTLP_HEADERS.
+func parseHeaders(s string) (map[string]string, error) {
+	return parseResourceAttributes(s)
+}
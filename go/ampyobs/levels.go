@@ -0,0 +1,195 @@
+package ampyobs
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/loglevels"
+)
+
+// LoggerKey is the record attribute naming the logger a record comes from.
+// Loggers carrying it (see Named) follow the level set for that name.
+const LoggerKey = "logger"
+
+// allLevels lets every record through the output handlers; levelHandler
+// filters above them.
+const allLevels = slog.Level(math.MinInt)
+
+// LoggerLevel is the level of one logger as reported by Levels.
+type LoggerLevel struct {
+	Logger   string     `json:"logger"` // "" for the root logger
+	Level    slog.Level `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"` // set while a temporary level applies
+}
+
+// Named returns L() tagged with LoggerKey, so its level can be set apart
+// from the root's, e.g. Named("oms") or Named("md.ingest"). A dotted name
+// without a level of its own follows its parent ("md"), then the root. Use
+// C(ctx).With(LoggerKey, name) for a context-aware named logger.
+func (o *Obs) Named(name string) *slog.Logger {
+	return o.L().With(slog.String(LoggerKey, name))
+}
+
+// Level returns the level in effect for the named logger ("" for the root).
+func (o *Obs) Level(name string) slog.Level {
+	return o.levels.level(name)
+}
+
+// SetLevel sets the level of the named logger ("" for the root). With a
+// positive ttl the previous level comes back once it elapses, e.g. to
+// debug a component for 15 minutes without leaving it noisy.
+func (o *Obs) SetLevel(name string, level slog.Level, ttl time.Duration) {
+	o.levels.set(name, level, ttl)
+}
+
+// ResetLevel restores the configured level of the named logger (see
+// Config.LogLevels); a name without one follows its parent again.
+func (o *Obs) ResetLevel(name string) {
+	o.levels.reset(name)
+}
+
+// Levels lists the root level followed by every named level, by name.
+func (o *Obs) Levels() []LoggerLevel {
+	return o.levels.list()
+}
+
+// LevelHandler reads and changes log levels over HTTP:
+//
+//	GET                                       lists the levels as JSON
+//	PUT|POST ?logger=oms&level=debug&ttl=15m  sets a level (logger and ttl optional)
+//	DELETE   ?logger=oms                      restores the configured level
+//
+// Parameters may also be sent as a form body. Every call responds with the
+// levels after the change. Mount it on an internal admin port only.
+func (o *Obs) LevelHandler() http.Handler {
+	return o.levels.shared.Handler(
+		func(s string) (int, error) {
+			l, err := parseLevel(s)
+			return int(l), err
+		},
+		func(l int) string { return slog.Level(l).String() },
+	)
+}
+
+// Named returns the Default instance's logger for name.
+func Named(name string) *slog.Logger { return Default().Named(name) }
+
+// SetLevel sets a log level on the Default instance.
+func SetLevel(name string, level slog.Level, ttl time.Duration) {
+	Default().SetLevel(name, level, ttl)
+}
+
+// LevelHandler serves the Default instance's log levels. The instance is
+// resolved per request, so the handler can be mounted before Init.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Default().LevelHandler().ServeHTTP(w, r)
+	})
+}
+
+// parseLevel parses "debug", "INFO", "warn+2" and the like.
+func parseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return l, err
+}
+
+// configuredLevels returns the valid levels of Config.LogLevel and
+// Config.LogLevels by logger name, the root under "".
+func configuredLevels(root string, named map[string]string) map[string]slog.Level {
+	out := make(map[string]slog.Level, len(named)+1)
+	if l, err := parseLevel(root); err == nil {
+		out[""] = l
+	}
+	for name, s := range named {
+		if l, err := parseLevel(s); err == nil && name != "" {
+			out[name] = l
+		}
+	}
+	return out
+}
+
+// ----------- Levels -----------
+
+// logLevels holds the root and named levels of an instance, shared with the
+// zap flavor through the loglevels package.
+type logLevels struct {
+	shared *loglevels.Levels
+}
+
+// newLogLevels applies cfg's levels; entries Validate rejects are skipped.
+// The root defaults to info.
+func newLogLevels(cfg Config) *logLevels {
+	configured := make(map[string]int)
+	for name, level := range configuredLevels(cfg.LogLevel, cfg.LogLevels) {
+		configured[name] = int(level)
+	}
+	return &logLevels{shared: loglevels.New(configured)}
+}
+
+func (l *logLevels) level(name string) slog.Level {
+	return slog.Level(l.shared.Level(name))
+}
+
+func (l *logLevels) set(name string, level slog.Level, ttl time.Duration) {
+	l.shared.Set(name, int(level), ttl)
+}
+
+func (l *logLevels) reset(name string) {
+	l.shared.Reset(name)
+}
+
+func (l *logLevels) list() []LoggerLevel {
+	entries := l.shared.List()
+	out := make([]LoggerLevel, len(entries))
+	for i, e := range entries {
+		out[i] = LoggerLevel{Logger: e.Logger, Level: slog.Level(e.Level), RevertAt: e.RevertAt}
+	}
+	return out
+}
+
+// levelHandler drops records below the level of the logger they come from,
+// named by a LoggerKey attribute added with With.
+type levelHandler struct {
+	slog.Handler
+	levels  *logLevels
+	name    string
+	grouped bool // attributes now land in a group, so LoggerKey no longer names the logger
+}
+
+// newLevelHandler filters h, whose own level should be allLevels, by levels
+// and lets debugged flows through (see debugHandler).
+func newLevelHandler(h slog.Handler, levels *logLevels, debug *debugTargets) slog.Handler {
+	return debugHandler{Handler: levelHandler{Handler: h, levels: levels}, debug: debug}
+}
+
+func (h levelHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.levels.level(h.name) && h.Handler.Enabled(ctx, l)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := h
+	h2.Handler = h.Handler.WithAttrs(attrs)
+	if !h.grouped {
+		for _, a := range attrs {
+			if a.Key == LoggerKey {
+				h2.name = a.Value.String()
+			}
+		}
+	}
+	return h2
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h
+	h2.Handler = h.Handler.WithGroup(name)
+	h2.grouped = true
+	return h2
+}
@@ -0,0 +1,147 @@
+package ampyobs
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestLogLevels(t *testing.T) {
+	l := newLogLevels(Config{LogLevel: "warn", LogLevels: map[string]string{"md": "error", "oms": "bogus"}})
+	l.set("md.ingest", slog.LevelDebug, 0)
+
+	tests := []struct {
+		name string
+		want slog.Level
+	}{
+		{"", slog.LevelWarn},
+		{"md", slog.LevelError},
+		{"md.bars", slog.LevelError},
+		{"md.ingest", slog.LevelDebug},
+		{"md.ingest.kafka", slog.LevelDebug},
+		{"oms", slog.LevelWarn}, // invalid levels are skipped
+		{"mdx", slog.LevelWarn},
+	}
+	for _, tt := range tests {
+		if got := l.level(tt.name); got != tt.want {
+			t.Errorf("level(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	l.set("md", slog.LevelInfo, 0)
+	l.reset("md")
+	l.reset("md.ingest")
+	l.set("", slog.LevelDebug, 0)
+	l.reset("")
+	want := []LoggerLevel{{Logger: "", Level: slog.LevelWarn}, {Logger: "md", Level: slog.LevelError}}
+	if got := l.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after reset list() = %+v, want %+v", got, want)
+	}
+}
+
+// waitLevel polls until name is at want or fails the test.
+func waitLevel(t *testing.T, o *Obs, name string, want slog.Level) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for o.Level(name) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("level(%q) = %v, want %v", name, o.Level(name), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLevelTTLRevert(t *testing.T) {
+	logs := &recordCounter{}
+	o := newTestObs(t, func(c *Config) {
+		c.LogLevel = "info"
+		c.LogLevels = map[string]string{"md": "warn"}
+		c.LogProcessors = []sdklog.Processor{logs}
+	})
+
+	// A name without a configured level goes away, so it follows its parent.
+	o.SetLevel("md.ingest", slog.LevelDebug, 20*time.Millisecond)
+	o.Named("md.ingest").Debug("while debugging")
+	if ll := o.Levels(); len(ll) != 3 || ll[2].Logger != "md.ingest" || ll[2].RevertAt == nil {
+		t.Errorf("Levels() = %+v, want md.ingest with revert_at", ll)
+	}
+	waitLevel(t, o, "md.ingest", slog.LevelWarn)
+	o.Named("md.ingest").Info("after revert")
+	if ll := o.Levels(); len(ll) != 2 {
+		t.Errorf("Levels() after revert = %+v", ll)
+	}
+
+	// A configured name and the root go back to their previous level.
+	o.SetLevel("md", slog.LevelDebug, 20*time.Millisecond)
+	o.SetLevel("", slog.LevelError, 20*time.Millisecond)
+	waitLevel(t, o, "md", slog.LevelWarn)
+	waitLevel(t, o, "", slog.LevelInfo)
+	for _, ll := range o.Levels() {
+		if ll.RevertAt != nil {
+			t.Errorf("%q still reverts at %v", ll.Logger, ll.RevertAt)
+		}
+	}
+
+	// A later permanent level cancels the pending revert.
+	o.SetLevel("oms", slog.LevelDebug, 20*time.Millisecond)
+	o.SetLevel("oms", slog.LevelWarn, 0)
+	time.Sleep(60 * time.Millisecond)
+	if got := o.Level("oms"); got != slog.LevelWarn {
+		t.Errorf("oms = %v after a superseded ttl, want WARN", got)
+	}
+
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	if want := []string{"while debugging"}; !reflect.DeepEqual(logs.msgs, want) {
+		t.Errorf("logged %q, want %q", logs.msgs, want)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	o := newTestObs(t, func(c *Config) { c.LogLevel = "info" })
+	h := o.LevelHandler()
+	tests := []struct {
+		method, query string
+		wantCode      int
+		want          map[string]string // logger -> level in the response
+	}{
+		{http.MethodGet, "", http.StatusOK, map[string]string{"": "INFO"}},
+		{http.MethodPut, "logger=oms&level=debug&ttl=1h", http.StatusOK, map[string]string{"": "INFO", "oms": "DEBUG"}},
+		{http.MethodPost, "level=warn%2B2", http.StatusOK, map[string]string{"": "WARN+2", "oms": "DEBUG"}},
+		{http.MethodDelete, "logger=oms", http.StatusOK, map[string]string{"": "WARN+2"}},
+		{http.MethodPut, "level=loud", http.StatusBadRequest, nil},
+		{http.MethodPut, "level=info&ttl=-1s", http.StatusBadRequest, nil},
+		{http.MethodPatch, "", http.StatusMethodNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/loglevel?"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s ?%s: status = %d, want %d", tt.method, tt.query, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		var ll []LoggerLevel
+		if err := json.Unmarshal(rec.Body.Bytes(), &ll); err != nil {
+			t.Fatalf("%s ?%s: %v", tt.method, tt.query, err)
+		}
+		got := make(map[string]string, len(ll))
+		for _, l := range ll {
+			got[l.Logger] = l.Level.String()
+			if (l.Logger == "oms") != (l.RevertAt != nil) {
+				t.Errorf("%s ?%s: %q revert_at = %v", tt.method, tt.query, l.Logger, l.RevertAt)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s ?%s: levels = %v, want %v", tt.method, tt.query, got, tt.want)
+		}
+	}
+}
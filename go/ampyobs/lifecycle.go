@@ -0,0 +1,366 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// ForceFlush exports everything buffered by the instance's providers without
+// shutting them down: logs first, then traces, then metrics, so metrics
+// recorded while flushing the others are included.
+func (o *Obs) ForceFlush(ctx context.Context) error {
+	var errs []error
+	if o.lp != nil {
+		if err := o.lp.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush logs: %w", err))
+		}
+	}
+	if o.tp != nil {
+		if err := o.tp.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush traces: %w", err))
+		}
+	}
+	if o.mp != nil {
+		if err := o.mp.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush metrics: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ForceFlush flushes the Default instance.
+func ForceFlush(ctx context.Context) error {
+	return Default().ForceFlush(ctx)
+}
+
+// Shutdown flushes and stops the instance's providers in the same order as
+// ForceFlush. Every provider is shut down even if an earlier one fails; the
+// errors are joined. With Pipeline.FlushOnShutdown (profile "job") a full
+// ForceFlush runs first, so the final metric collection is forced after
+// every span and log has been exported. Only the first call does the work;
+// later calls return its result.
+func (o *Obs) Shutdown(ctx context.Context) error {
+	o.shutdownOnce.Do(func() { o.shutdownErr = o.shutdown(ctx) })
+	return o.shutdownErr
+}
+
+func (o *Obs) shutdown(ctx context.Context) error {
+	var errs []error
+	if o.cfg.Pipeline.settings().flushOnShutdown {
+		if err := o.ForceFlush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if o.logSampler != nil {
+		o.logSampler.shutdown() // final summary, before logs stop
+	}
+	if o.lp != nil {
+		if err := o.lp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown logs: %w", err))
+		}
+	}
+	if o.tp != nil {
+		if err := o.tp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown traces: %w", err))
+		}
+	}
+	if r := o.stats.spans.remote; r != nil {
+		r.shutdown()
+	}
+	if o.mp != nil {
+		if err := o.mp.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown metrics: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts down the Default instance.
+func Shutdown(ctx context.Context) error {
+	return Default().Shutdown(ctx)
+}
+
+// SignalReport counts what one signal handed to its exporter.
+type SignalReport struct {
+	Exported  int64 // items accepted by the collector
+	Failed    int64 // items dropped after the exporter gave up retrying
+	Dropped   int64 // items still buffered when shutdown ended (spans and logs only)
+	Persisted int64 // items left in the WAL for the next run
+}
+
+// ShutdownReport summarizes a RunUntilSignal drain.
+type ShutdownReport struct {
+	Signal   os.Signal     // the signal received, or nil if fn returned on its own
+	Duration time.Duration // time spent flushing and shutting down
+	Logs     SignalReport
+	Spans    SignalReport
+	Metrics  SignalReport // counts data points
+}
+
+// RunUntilSignal runs fn until it returns, ctx is done, or SIGINT/SIGTERM
+// arrives; the context passed to fn is canceled in the latter two cases.
+// It then shuts the instance down, which flushes it, within
+// Config.ShutdownTimeout and reports what was exported or dropped. The error
+// joins fn's error with any shutdown error.
+func (o *Obs) RunUntilSignal(ctx context.Context, fn func(context.Context) error) (ShutdownReport, error) {
+	timeout := o.cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- fn(runCtx) }()
+
+	var report ShutdownReport
+	var fnErr error
+	select {
+	case fnErr = <-done:
+	case report.Signal = <-sigCh:
+		cancel()
+		fnErr = waitFor(done, timeout)
+	case <-ctx.Done():
+		fnErr = waitFor(done, timeout)
+	}
+
+	start := time.Now()
+	drainCtx, drainCancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer drainCancel()
+	drainErr := o.Shutdown(drainCtx)
+	report.Duration = time.Since(start)
+	report.Logs, report.Spans, report.Metrics = o.stats.report()
+
+	return report, errors.Join(fnErr, drainErr)
+}
+
+// RunUntilSignal runs fn with the Default instance; see Obs.RunUntilSignal.
+func RunUntilSignal(ctx context.Context, fn func(context.Context) error) (ShutdownReport, error) {
+	return Default().RunUntilSignal(ctx, fn)
+}
+
+// waitFor gives fn up to timeout to return after cancellation so the drain
+// still happens when fn ignores its context.
+func waitFor(done <-chan error, timeout time.Duration) error {
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("run: did not return within %s of cancellation", timeout)
+	}
+}
+
+// exportStats counts items flowing through one signal's pipeline.
+type exportStats struct {
+	started  atomic.Int64 // spans started
+	ended    atomic.Int64 // spans ended, log records emitted
+	queued   atomic.Int64 // handed to the batching processor (spans, logs)
+	dropped  atomic.Int64 // refused because the batch queue was full
+	exported atomic.Int64
+	failed   atomic.Int64
+
+	// persisted counts items held in the WAL after a failed export. They
+	// are neither exported nor failed until replayed or evicted.
+	persisted atomic.Int64
+
+	batchesOK     atomic.Int64
+	batchesFailed atomic.Int64
+	lastOK        atomic.Int64 // unix nanos of the last successful export
+	lastFailure   atomic.Pointer[exportFailure]
+
+	latency atomic.Pointer[latencyRecorder] // set once the meter provider exists
+	wal     *walQueue                       // nil unless Config.WAL is enabled
+	router  *router                         // nil unless Config.Routing lists endpoints
+	tail    *tailSampler                    // spans only; nil unless Config.TailSampling is enabled
+	remote  *remoteSampler                  // spans only; nil unless Config.RemoteSampling is set
+}
+
+type exportFailure struct {
+	err error
+	at  time.Time
+}
+
+// record counts n items as exported or failed by one export call.
+func (s *exportStats) record(n int, err error, took time.Duration) {
+	s.recordAttempt(err, took)
+	if err != nil {
+		s.failed.Add(int64(n))
+		return
+	}
+	s.exported.Add(int64(n))
+}
+
+// recordAttempt records the latency and outcome of one export call without
+// counting its items.
+func (s *exportStats) recordAttempt(err error, took time.Duration) {
+	if l := s.latency.Load(); l != nil {
+		l.record(took)
+	}
+	if err != nil {
+		s.batchesFailed.Add(1)
+		s.lastFailure.Store(&exportFailure{err: err, at: time.Now()})
+		return
+	}
+	s.batchesOK.Add(1)
+	s.lastOK.Store(time.Now().UnixNano())
+}
+
+// pending is the number of items handed to the batcher and not yet exported,
+// failed or persisted, i.e. the queue size including the batch being exported.
+func (s *exportStats) pending() int64 {
+	return max(s.queued.Load()-s.exported.Load()-s.failed.Load()-s.persisted.Load(), 0)
+}
+
+func (s *exportStats) report() SignalReport {
+	// Whatever is still pending after shutdown never left the process.
+	return SignalReport{
+		Exported:  s.exported.Load(),
+		Failed:    s.failed.Load(),
+		Dropped:   s.dropped.Load() + s.pending(),
+		Persisted: s.persisted.Load(),
+	}
+}
+
+// obsStats holds the per-signal counters of one Obs.
+type obsStats struct {
+	logs, spans, metrics exportStats
+}
+
+func (s *obsStats) report() (logs, spans, metrics SignalReport) {
+	return s.logs.report(), s.spans.report(), s.metrics.report()
+}
+
+// countingSpanExporter records export outcomes in stats.
+type countingSpanExporter struct {
+	sdktrace.SpanExporter
+	stats *exportStats
+}
+
+func (e countingSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	start := time.Now()
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.stats.record(len(spans), err, time.Since(start))
+	return err
+}
+
+// spanGate sits in front of the batch span processor. It counts started and
+// ended spans and refuses sampled spans once the batch queue is full, so
+// drops are counted here instead of vanishing inside the batcher.
+type spanGate struct {
+	next  sdktrace.SpanProcessor
+	stats *exportStats
+	limit int64
+}
+
+func (p spanGate) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.stats.started.Add(1)
+	p.next.OnStart(ctx, s)
+}
+
+func (p spanGate) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.stats.ended.Add(1)
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	if p.stats.pending() >= p.limit {
+		p.stats.dropped.Add(1)
+		return
+	}
+	p.stats.queued.Add(1)
+	p.next.OnEnd(s)
+}
+
+func (p spanGate) Shutdown(ctx context.Context) error   { return p.next.Shutdown(ctx) }
+func (p spanGate) ForceFlush(ctx context.Context) error { return p.next.ForceFlush(ctx) }
+
+// countingLogExporter records export outcomes in stats.
+type countingLogExporter struct {
+	sdklog.Exporter
+	stats *exportStats
+}
+
+func (e countingLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	start := time.Now()
+	err := e.Exporter.Export(ctx, records)
+	e.stats.record(len(records), err, time.Since(start))
+	return err
+}
+
+// logGate is the log counterpart of spanGate.
+type logGate struct {
+	next  sdklog.Processor
+	stats *exportStats
+	limit int64
+}
+
+func (p logGate) OnEmit(ctx context.Context, r *sdklog.Record) error {
+	p.stats.ended.Add(1)
+	if p.stats.pending() >= p.limit {
+		p.stats.dropped.Add(1)
+		return nil
+	}
+	p.stats.queued.Add(1)
+	return p.next.OnEmit(ctx, r)
+}
+
+func (p logGate) Shutdown(ctx context.Context) error   { return p.next.Shutdown(ctx) }
+func (p logGate) ForceFlush(ctx context.Context) error { return p.next.ForceFlush(ctx) }
+
+// countingMetricExporter records export outcomes in stats, counting data points.
+type countingMetricExporter struct {
+	sdkmetric.Exporter
+	stats *exportStats
+}
+
+func (e countingMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	start := time.Now()
+	err := e.Exporter.Export(ctx, rm)
+	e.stats.record(dataPoints(rm), err, time.Since(start))
+	return err
+}
+
+func dataPoints(rm *metricdata.ResourceMetrics) int {
+	n := 0
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Sum[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Gauge[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Gauge[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Histogram[int64]:
+				n += len(d.DataPoints)
+			case metricdata.Histogram[float64]:
+				n += len(d.DataPoints)
+			case metricdata.ExponentialHistogram[int64]:
+				n += len(d.DataPoints)
+			case metricdata.ExponentialHistogram[float64]:
+				n += len(d.DataPoints)
+			case metricdata.Summary:
+				n += len(d.DataPoints)
+			}
+		}
+	}
+	return n
+}
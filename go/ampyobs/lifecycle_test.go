@@ -0,0 +1,122 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestShutdownOnce(t *testing.T) {
+	for _, profile := range []string{"", "job"} {
+		t.Run("profile="+profile, func(t *testing.T) {
+			o := newTestObs(t, func(c *Config) { c.Pipeline.Profile = profile })
+			_, span := o.StartSpan(context.Background(), "work", trace.SpanKindInternal)
+			span.End()
+			for i := 0; i < 3; i++ {
+				if err := o.Shutdown(context.Background()); err != nil {
+					t.Fatalf("Shutdown #%d: %v", i+1, err)
+				}
+			}
+			if got := o.stats.spans.exported.Load(); got != 1 {
+				t.Errorf("exported %d spans, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRunUntilSignal(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name       string
+		ctx        func() (context.Context, context.CancelFunc)
+		fn         func(ctx context.Context) error
+		wantSignal os.Signal
+		wantErr    error
+	}{
+		{
+			name: "fn returns",
+			fn:   func(context.Context) error { return nil },
+		},
+		{
+			name:    "fn fails",
+			fn:      func(context.Context) error { return errBoom },
+			wantErr: errBoom,
+		},
+		{
+			name: "context canceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name: "SIGTERM",
+			fn: func(ctx context.Context) error {
+				if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+					return err
+				}
+				<-ctx.Done()
+				return nil
+			},
+			wantSignal: syscall.SIGTERM,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestObs(t)
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			report, err := o.RunUntilSignal(ctx, func(ctx context.Context) error {
+				for i := 0; i < 3; i++ {
+					_, span := o.StartSpan(ctx, "step", trace.SpanKindInternal)
+					span.End()
+				}
+				o.L().Info("done")
+				return tt.fn(ctx)
+			})
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RunUntilSignal() error = %v, want %v", err, tt.wantErr)
+			}
+			if report.Signal != tt.wantSignal {
+				t.Errorf("Signal = %v, want %v", report.Signal, tt.wantSignal)
+			}
+			if report.Spans != (SignalReport{Exported: 3}) || report.Logs != (SignalReport{Exported: 1}) {
+				t.Errorf("report spans %+v, logs %+v", report.Spans, report.Logs)
+			}
+			if err := o.Shutdown(context.Background()); err != nil {
+				t.Errorf("Shutdown after RunUntilSignal: %v", err)
+			}
+		})
+	}
+}
+
+func TestExportStatsReport(t *testing.T) {
+	var s exportStats
+	s.queued.Add(10)
+	s.dropped.Add(2)
+	s.record(6, nil, time.Millisecond)
+	s.record(1, errors.New("unavailable"), time.Millisecond)
+
+	if got := s.pending(); got != 3 {
+		t.Errorf("pending() = %d, want 3", got)
+	}
+	if got, want := s.report(), (SignalReport{Exported: 6, Failed: 1, Dropped: 5}); got != want {
+		t.Errorf("report() = %+v, want %+v", got, want)
+	}
+	if s.batchesOK.Load() != 1 || s.batchesFailed.Load() != 1 || s.lastFailure.Load() == nil {
+		t.Errorf("batches ok %d, failed %d", s.batchesOK.Load(), s.batchesFailed.Load())
+	}
+}
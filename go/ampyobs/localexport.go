@@ -0,0 +1,387 @@
+package ampyobs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Output formats for the stdout and file exporters.
+const (
+	FormatPretty   = "pretty"    // one human-readable line per span, data point or log record
+	FormatOTLPJSON = "otlp-json" // one OTLP/JSON export request per line
+)
+
+const defaultExportFile = "ampyobs-telemetry.jsonl"
+
+// localFormat resolves Config.ExportFormat for protocol: pretty on stdout
+// and OTLP/JSON in files unless set explicitly.
+func (c Config) localFormat(protocol string) string {
+	if f := strings.ToLower(c.ExportFormat); f != "" {
+		return f
+	}
+	if protocol == ProtocolFile {
+		return FormatOTLPJSON
+	}
+	return FormatPretty
+}
+
+// ----------- Sinks -----------
+
+// sink serializes line writes from several exporters to one destination.
+// File sinks are shared per path and closed when the last exporter shuts down.
+type sink struct {
+	mu   sync.Mutex
+	w    io.Writer
+	c    io.Closer // nil for stdout
+	path string
+	refs int
+}
+
+var (
+	sinksMu sync.Mutex
+	sinks   = map[string]*sink{}
+)
+
+func openSink(protocol, path string) (*sink, error) {
+	if protocol == ProtocolStdout {
+		return &sink{w: os.Stdout}, nil
+	}
+	if path == "" {
+		path = defaultExportFile
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("export file: %w", err)
+	}
+
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if s, ok := sinks[abs]; ok {
+		s.refs++
+		return s, nil
+	}
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("export file: %w", err)
+	}
+	s := &sink{w: f, c: f, path: abs, refs: 1}
+	sinks[abs] = s
+	return s, nil
+}
+
+func (s *sink) write(lines []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(lines)
+	return err
+}
+
+func (s *sink) close() error {
+	if s.c == nil {
+		return nil
+	}
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	if s.refs--; s.refs > 0 {
+		return nil
+	}
+	delete(sinks, s.path)
+	return s.c.Close()
+}
+
+// writeOTLPJSON writes msg as a single OTLP/JSON line.
+func (s *sink) writeOTLPJSON(msg proto.Message) error {
+	b, err := marshalOTLPJSON(msg)
+	if err != nil {
+		return fmt.Errorf("otlp/json encode: %w", err)
+	}
+	return s.write(append(b, '\n'))
+}
+
+// ----------- Exporters -----------
+
+type localSpanExporter struct {
+	sink   *sink
+	format string
+}
+
+func (e *localSpanExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	if e.format == FormatOTLPJSON {
+		return e.sink.writeOTLPJSON(&collectortracepb.ExportTraceServiceRequest{ResourceSpans: otlpconv.SpansToProto(spans)})
+	}
+	var b strings.Builder
+	for _, s := range spans {
+		prettySpan(&b, s)
+	}
+	return e.sink.write([]byte(b.String()))
+}
+
+func (e *localSpanExporter) Shutdown(context.Context) error { return e.sink.close() }
+
+type localMetricExporter struct {
+	sink   *sink
+	format string
+}
+
+func (e *localMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *localMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *localMetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	if e.format == FormatOTLPJSON {
+		return e.sink.writeOTLPJSON(&collectormetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{otlpconv.MetricsToProto(rm)},
+		})
+	}
+	var b strings.Builder
+	now := time.Now()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			prettyMetric(&b, now, m)
+		}
+	}
+	return e.sink.write([]byte(b.String()))
+}
+
+func (e *localMetricExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *localMetricExporter) Shutdown(context.Context) error { return e.sink.close() }
+
+type localLogExporter struct {
+	sink   *sink
+	format string
+}
+
+func (e *localLogExporter) Export(_ context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if e.format == FormatOTLPJSON {
+		return e.sink.writeOTLPJSON(&collectorlogspb.ExportLogsServiceRequest{ResourceLogs: logsToProto(records)})
+	}
+	var b strings.Builder
+	for i := range records {
+		prettyLog(&b, &records[i])
+	}
+	return e.sink.write([]byte(b.String()))
+}
+
+func (e *localLogExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *localLogExporter) Shutdown(context.Context) error { return e.sink.close() }
+
+// discardSpanExporter and friends implement the "none" protocol.
+type discardSpanExporter struct{}
+
+func (discardSpanExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+func (discardSpanExporter) Shutdown(context.Context) error                             { return nil }
+
+type discardMetricExporter struct{}
+
+func (discardMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (discardMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (discardMetricExporter) Export(context.Context, *metricdata.ResourceMetrics) error { return nil }
+func (discardMetricExporter) ForceFlush(context.Context) error                          { return nil }
+func (discardMetricExporter) Shutdown(context.Context) error                            { return nil }
+
+type discardLogExporter struct{}
+
+func (discardLogExporter) Export(context.Context, []sdklog.Record) error { return nil }
+func (discardLogExporter) ForceFlush(context.Context) error              { return nil }
+func (discardLogExporter) Shutdown(context.Context) error                { return nil }
+
+func newLocalSpanExporter(cfg Config, protocol string) (sdktrace.SpanExporter, error) {
+	if protocol == ProtocolNone {
+		return discardSpanExporter{}, nil
+	}
+	s, err := openSink(protocol, cfg.ExportFile)
+	if err != nil {
+		return nil, err
+	}
+	return &localSpanExporter{sink: s, format: cfg.localFormat(protocol)}, nil
+}
+
+func newLocalMetricExporter(cfg Config, protocol string) (sdkmetric.Exporter, error) {
+	if protocol == ProtocolNone {
+		return discardMetricExporter{}, nil
+	}
+	s, err := openSink(protocol, cfg.ExportFile)
+	if err != nil {
+		return nil, err
+	}
+	return &localMetricExporter{sink: s, format: cfg.localFormat(protocol)}, nil
+}
+
+func newLocalLogExporter(cfg Config, protocol string) (sdklog.Exporter, error) {
+	if protocol == ProtocolNone {
+		return discardLogExporter{}, nil
+	}
+	s, err := openSink(protocol, cfg.ExportFile)
+	if err != nil {
+		return nil, err
+	}
+	return &localLogExporter{sink: s, format: cfg.localFormat(protocol)}, nil
+}
+
+// ----------- Pretty printer -----------
+
+const prettyTime = "15:04:05.000"
+
+// prettySpan writes e.g.
+//
+//	15:04:05.123 SPAN  bus.publish ampy/dev/bars/v1 producer 12.3ms trace=4bf9…4736 span=00f0…02b7 status=Error(boom) messaging.system=kafka
+func prettySpan(b *strings.Builder, s sdktrace.ReadOnlySpan) {
+	sc := s.SpanContext()
+	fmt.Fprintf(b, "%s SPAN  %s %s %s trace=%s span=%s",
+		s.StartTime().Local().Format(prettyTime), s.Name(), s.SpanKind(),
+		s.EndTime().Sub(s.StartTime()).Round(time.Microsecond),
+		sc.TraceID(), sc.SpanID())
+	if p := s.Parent(); p.IsValid() {
+		fmt.Fprintf(b, " parent=%s", p.SpanID())
+	}
+	if st := s.Status(); st.Code != 0 {
+		fmt.Fprintf(b, " status=%s", st.Code)
+		if st.Description != "" {
+			fmt.Fprintf(b, "(%s)", st.Description)
+		}
+	}
+	prettyAttrs(b, s.Attributes())
+	b.WriteByte('\n')
+	for _, e := range s.Events() {
+		fmt.Fprintf(b, "    event %s %s", e.Time.Local().Format(prettyTime), e.Name)
+		prettyAttrs(b, e.Attributes)
+		b.WriteByte('\n')
+	}
+}
+
+// prettyMetric writes one line per data point, e.g.
+//
+//	15:04:05.123 METRIC ampy.bus.produced_total{topic=ampy/dev/bars/v1} = 5
+func prettyMetric(b *strings.Builder, now time.Time, m metricdata.Metrics) {
+	line := func(attrs attribute.Set, value string) {
+		fmt.Fprintf(b, "%s METRIC %s{", now.Local().Format(prettyTime), m.Name)
+		for i, kv := range attrs.ToSlice() {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=%s", kv.Key, kv.Value.Emit())
+		}
+		fmt.Fprintf(b, "} %s\n", value)
+	}
+	switch d := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, fmt.Sprintf("= %d", dp.Value))
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, fmt.Sprintf("= %g", dp.Value))
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, fmt.Sprintf("= %d", dp.Value))
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, fmt.Sprintf("= %g", dp.Value))
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, prettyHistogram(dp.Count, float64(dp.Sum), dp.Min, dp.Max))
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, prettyHistogram(dp.Count, dp.Sum, dp.Min, dp.Max))
+		}
+	case metricdata.ExponentialHistogram[int64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, prettyHistogram(dp.Count, float64(dp.Sum), dp.Min, dp.Max))
+		}
+	case metricdata.ExponentialHistogram[float64]:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, prettyHistogram(dp.Count, dp.Sum, dp.Min, dp.Max))
+		}
+	case metricdata.Summary:
+		for _, dp := range d.DataPoints {
+			line(dp.Attributes, fmt.Sprintf("count=%d sum=%g", dp.Count, dp.Sum))
+		}
+	}
+}
+
+func prettyHistogram[N int64 | float64](count uint64, sum float64, minV, maxV metricdata.Extrema[N]) string {
+	out := fmt.Sprintf("count=%d sum=%g", count, sum)
+	if count > 0 {
+		out += fmt.Sprintf(" avg=%g", sum/float64(count))
+	}
+	if v, ok := minV.Value(); ok {
+		out += fmt.Sprintf(" min=%v", v)
+	}
+	if v, ok := maxV.Value(); ok {
+		out += fmt.Sprintf(" max=%v", v)
+	}
+	return out
+}
+
+// prettyLog writes e.g.
+//
+//	15:04:05.123 INFO  order submitted trace=4bf9…4736 symbol=AAPL
+func prettyLog(b *strings.Builder, r *sdklog.Record) {
+	sev := r.SeverityText()
+	if sev == "" {
+		sev = r.Severity().String()
+	}
+	fmt.Fprintf(b, "%s %-5s %s", r.Timestamp().Local().Format(prettyTime), sev, r.Body().String())
+	if tid := r.TraceID(); tid.IsValid() {
+		fmt.Fprintf(b, " trace=%s span=%s", tid, r.SpanID())
+	}
+	var kvs []string
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		kvs = append(kvs, kv.Key+"="+kv.Value.String())
+		return true
+	})
+	sort.Strings(kvs)
+	for _, kv := range kvs {
+		b.WriteByte(' ')
+		b.WriteString(kv)
+	}
+	b.WriteByte('\n')
+}
+
+func prettyAttrs(b *strings.Builder, attrs []attribute.KeyValue) {
+	for _, kv := range attrs {
+		fmt.Fprintf(b, " %s=%s", kv.Key, kv.Value.Emit())
+	}
+}
@@ -0,0 +1,187 @@
+package ampyobs
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestLocalFormat(t *testing.T) {
+	tests := []struct {
+		format, protocol, want string
+	}{
+		{"", ProtocolStdout, FormatPretty},
+		{"", ProtocolFile, FormatOTLPJSON},
+		{"OTLP-JSON", ProtocolStdout, FormatOTLPJSON},
+		{FormatPretty, ProtocolFile, FormatPretty},
+	}
+	for _, tt := range tests {
+		if got := (Config{ExportFormat: tt.format}).localFormat(tt.protocol); got != tt.want {
+			t.Errorf("localFormat(%q, %s) = %s, want %s", tt.format, tt.protocol, got, tt.want)
+		}
+	}
+}
+
+// emitLocal publishes one message through a local-export instance and shuts
+// it down.
+func emitLocal(t *testing.T, configure func(*Config)) trace.SpanContext {
+	t.Helper()
+	cfg := Config{
+		ServiceName:      "svc",
+		Environment:      "dev",
+		EnableTracing:    true,
+		EnableMetrics:    true,
+		EnableLogs:       true,
+		DisableLogStdout: true,
+		Sampler:          "always_on",
+	}
+	configure(&cfg)
+	o, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := o.StartSpan(context.Background(), "bus.publish", trace.SpanKindProducer)
+	o.BusProducedAdd(ctx, "ampy/dev/bars/v1", 2)
+	o.L().InfoContext(ctx, "published", "topic", "ampy/dev/bars/v1")
+	span.End()
+	if err := o.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return span.SpanContext()
+}
+
+func TestFileExportOTLPJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	sc := emitLocal(t, func(c *Config) { c.Protocol, c.ExportFile = ProtocolFile, path })
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// Ids are hex in OTLP/JSON, not protojson's base64, so they are matched
+	// on the raw line.
+	traceID := `"traceId":"` + sc.TraceID().String() + `"`
+	spanID := `"spanId":"` + sc.SpanID().String() + `"`
+	var spans, metrics, logs int
+	lines := bufio.NewScanner(f)
+	lines.Buffer(nil, 1<<20)
+	for lines.Scan() {
+		line := lines.Bytes()
+		switch {
+		case strings.Contains(string(line), `"resourceSpans"`):
+			var req collectortracepb.ExportTraceServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				t.Fatalf("span line: %v", err)
+			}
+			for _, s := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+				if s.Name == "bus.publish" && strings.Contains(string(line), traceID) {
+					spans++
+				}
+			}
+		case strings.Contains(string(line), `"resourceMetrics"`):
+			var req collectormetricspb.ExportMetricsServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				t.Fatalf("metric line: %v", err)
+			}
+			for _, sm := range req.ResourceMetrics[0].ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name == "ampy.bus.produced_total" {
+						metrics++
+					}
+				}
+			}
+		case strings.Contains(string(line), `"resourceLogs"`):
+			var req collectorlogspb.ExportLogsServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				t.Fatalf("log line: %v", err)
+			}
+			for _, r := range req.ResourceLogs[0].ScopeLogs[0].LogRecords {
+				if r.Body.GetStringValue() == "published" && strings.Contains(string(line), spanID) {
+					logs++
+				}
+			}
+		default:
+			t.Errorf("unexpected line %s", line)
+		}
+	}
+	if spans != 1 || metrics != 1 || logs != 1 {
+		t.Errorf("found %d spans, %d metrics and %d logs, want one of each", spans, metrics, logs)
+	}
+}
+
+func TestStdoutExportPretty(t *testing.T) {
+	var sc trace.SpanContext
+	out := captureStdout(t, func() {
+		sc = emitLocal(t, func(c *Config) { c.Protocol = ProtocolStdout })
+	})
+	for _, want := range []string{
+		" SPAN  bus.publish producer ",
+		" trace=" + sc.TraceID().String() + " span=" + sc.SpanID().String(),
+		" METRIC ampy.bus.produced_total{",
+		"topic=ampy/dev/bars/v1} = 2\n",
+		" INFO  published trace=" + sc.TraceID().String(),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout has no %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSinkSharing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.jsonl")
+	a, err := openSink(ProtocolFile, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := openSink(ProtocolFile, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("exporters of one file do not share a sink")
+	}
+	if err := a.close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.write([]byte("still open\n")); err != nil {
+		t.Errorf("write after the first close: %v", err)
+	}
+	if err := b.close(); err != nil {
+		t.Fatal(err)
+	}
+	sinksMu.Lock()
+	_, ok := sinks[a.path]
+	sinksMu.Unlock()
+	if ok {
+		t.Error("sink kept after its last exporter shut down")
+	}
+}
+
+func TestPrettyHistogram(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    uint64
+		sum      float64
+		min, max metricdata.Extrema[float64]
+		want     string
+	}{
+		{"empty", 0, 0, metricdata.Extrema[float64]{}, metricdata.Extrema[float64]{}, "count=0 sum=0"},
+		{"with extrema", 4, 10, metricdata.NewExtrema(1.5), metricdata.NewExtrema(4.0), "count=4 sum=10 avg=2.5 min=1.5 max=4"},
+	}
+	for _, tt := range tests {
+		if got := prettyHistogram(tt.count, tt.sum, tt.min, tt.max); got != tt.want {
+			t.Errorf("%s: prettyHistogram = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
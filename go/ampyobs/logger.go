@@ -9,12 +9,14 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-var rootLogger *slog.Logger
+func newSlogLogger() *slog.Logger {
+	return slog.New(newStdoutHandler(slog.LevelInfo))
+}
 
-func setupSlog(_ any) {
-	// JSON handler, info level default
-	rootLogger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+func newStdoutHandler(level slog.Leveler) slog.Handler {
+	// JSON handler; instances filter with levelHandler and pass allLevels
+	return slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: level,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 			// Force ISO8601 for time
 			if a.Key == slog.TimeKey {
@@ -24,24 +26,24 @@ func setupSlog(_ any) {
 			}
 			return a
 		},
-	}))
+	})
 }
 
 // L returns a *slog.Logger without context.
-func L() *slog.Logger {
-	if rootLogger == nil {
-		setupSlog(nil)
-	}
-	return rootLogger.With(
-		slog.String("service", globalCfg.ServiceName),
-		slog.String("env", globalCfg.Environment),
-		slog.String("service_version", globalCfg.ServiceVersion),
+func (o *Obs) L() *slog.Logger {
+	return o.logger.With(
+		slog.String("service", o.cfg.ServiceName),
+		slog.String("env", o.cfg.Environment),
+		slog.String("service_version", o.cfg.ServiceVersion),
 	)
 }
 
-// C returns a context-aware logger that enriches with trace/span if present.
-func C(ctx context.Context) *slog.Logger {
-	l := L()
+// C returns a context-aware logger that enriches with trace/span and
+// DomainContext fields if present. Records logged without a context use ctx,
+// so those exported over OTLP carry it and debugged flows (see
+// AddDebugTarget) log at debug level.
+func (o *Obs) C(ctx context.Context) *slog.Logger {
+	l := o.L()
 	sc := trace.SpanContextFromContext(ctx)
 	if sc.IsValid() {
 		l = l.With(
@@ -49,5 +51,18 @@ func C(ctx context.Context) *slog.Logger {
 			slog.String("span_id", sc.SpanID().String()),
 		)
 	}
-	return l
+	if dc, ok := FromDomainContext(ctx); ok {
+		var args []any
+		for _, kv := range dc.fields() {
+			args = append(args, slog.String(string(kv.Key), kv.Value.AsString()))
+		}
+		l = l.With(args...)
+	}
+	return slog.New(contextHandler{Handler: l.Handler(), ctx: ctx})
 }
+
+// L returns the Default instance's logger without context.
+func L() *slog.Logger { return Default().L() }
+
+// C returns the Default instance's context-aware logger.
+func C(ctx context.Context) *slog.Logger { return Default().C(ctx) }
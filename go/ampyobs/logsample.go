@@ -0,0 +1,118 @@
+package ampyobs
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/logsample"
+)
+
+// LogSamplingConfig protects the log pipeline from floods, such as a broker
+// reconnect loop logging the same error millions of times. Records are
+// counted per level and message in windows of Interval: the first First are
+// logged, then every Thereafter-th. RateLimit caps what is left across all
+// messages. Suppressed records are counted and reported every
+// SummaryInterval as one warning per message, e.g.
+//
+//	suppressed 12,345 occurrences of "broker disconnected"
+type LogSamplingConfig struct {
+	Enabled         bool
+	Interval        time.Duration // counting window per message (default: 1s)
+	First           int           // records per message and window always logged (default: 100)
+	Thereafter      int           // then every Thereafter-th is logged (0: none)
+	RateLimit       float64       // records per second across all messages, after sampling (0: unlimited)
+	SummaryInterval time.Duration // how often suppressed counts are logged (default: 1m)
+}
+
+// logSuppressKey labels ampy.obs.logs.suppressed_total; reason is
+// logsample.ReasonSampling or logsample.ReasonRateLimit.
+type logSuppressKey struct {
+	level  slog.Level
+	reason string
+}
+
+// logSampler adapts the shared logsample core to slog: summaries go to out
+// as warnings and suppressed counts are kept for metrics.
+type logSampler struct {
+	core  *logsample.Sampler
+	out   slog.Handler // receives the summaries
+	clock Clock        // stamps the summaries
+
+	mu     sync.Mutex
+	totals map[logSuppressKey]int64 // since start, for metrics
+}
+
+// newLogSampler reports to out. Counting windows and the rate limit follow
+// clock; summaries are still written every SummaryInterval of wall time.
+func newLogSampler(cfg LogSamplingConfig, out slog.Handler, clock Clock) *logSampler {
+	s := &logSampler{out: out, clock: clock, totals: make(map[logSuppressKey]int64)}
+	s.core = logsample.New(logsample.Config{
+		Interval:        cfg.Interval,
+		First:           cfg.First,
+		Thereafter:      cfg.Thereafter,
+		RateLimit:       cfg.RateLimit,
+		SummaryInterval: cfg.SummaryInterval,
+		Now:             clock.Now,
+	}, s.report, s.count)
+	return s
+}
+
+// allow reports whether a record is logged, counting it otherwise.
+func (s *logSampler) allow(level slog.Level, msg string) bool {
+	return s.core.Allow(int(level), msg)
+}
+
+// count tallies a suppressed record for metrics.
+func (s *logSampler) count(level int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.totals[logSuppressKey{slog.Level(level), reason}]++
+}
+
+// report logs one summary as a warning.
+func (s *logSampler) report(sum logsample.Summary) {
+	r := slog.NewRecord(s.clock.Now(), slog.LevelWarn, sum.Text(), 0)
+	r.AddAttrs(
+		slog.String("suppressed_message", sum.Message),
+		slog.String("suppressed_level", slog.Level(sum.Level).String()),
+		slog.Int64("suppressed", sum.Suppressed),
+	)
+	_ = s.out.Handle(context.Background(), r)
+}
+
+// summarize logs one warning per message suppressed since the last call.
+func (s *logSampler) summarize() { s.core.Summarize() }
+
+// totalsSnapshot returns the suppressed counts by level and reason.
+func (s *logSampler) totalsSnapshot() map[logSuppressKey]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.totals)
+}
+
+// shutdown stops the summary loop and reports what is still pending.
+func (s *logSampler) shutdown() { s.core.Shutdown() }
+
+// samplingHandler drops records the sampler suppresses.
+type samplingHandler struct {
+	slog.Handler
+	sampler *logSampler
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.allow(r.Level, r.Message) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,160 @@
+package ampyobs
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/logsample"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// recordHandler keeps the message and attributes of every record.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []map[string]string // "msg" plus the attributes
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	m := map[string]string{"msg": r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value.String()
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, m)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestLogSampler(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         LogSamplingConfig
+		logged      int
+		totals      map[logSuppressKey]int64
+		summaries   []map[string]string
+		otherLogged bool
+	}{
+		{
+			name:   "first then every thereafter",
+			cfg:    LogSamplingConfig{First: 3, Thereafter: 5},
+			logged: 6, // 1, 2, 3, 8, 13 and 18
+			totals: map[logSuppressKey]int64{{slog.LevelError, logsample.ReasonSampling}: 14},
+			summaries: []map[string]string{{
+				"msg":                `suppressed 14 occurrences of "broker disconnected"`,
+				"suppressed_message": "broker disconnected",
+				"suppressed_level":   "ERROR",
+				"suppressed":         "14",
+			}},
+			otherLogged: true,
+		},
+		{
+			name:   "rate limit across messages",
+			cfg:    LogSamplingConfig{RateLimit: 0.01}, // one token, not refilled during the test
+			logged: 1,
+			totals: map[logSuppressKey]int64{
+				{slog.LevelError, logsample.ReasonRateLimit}: 19,
+				{slog.LevelInfo, logsample.ReasonRateLimit}:  1,
+			},
+			summaries: []map[string]string{
+				{"msg": `suppressed 19 occurrences of "broker disconnected"`, "suppressed_message": "broker disconnected", "suppressed_level": "ERROR", "suppressed": "19"},
+				{"msg": `suppressed 1 occurrences of "reconnected"`, "suppressed_message": "reconnected", "suppressed_level": "INFO", "suppressed": "1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordHandler{}
+			s := newLogSampler(tt.cfg, out, wallClock{})
+			logged := 0
+			for range 20 {
+				if s.allow(slog.LevelError, "broker disconnected") {
+					logged++
+				}
+			}
+			if logged != tt.logged {
+				t.Errorf("logged %d of 20, want %d", logged, tt.logged)
+			}
+			if got := s.allow(slog.LevelInfo, "reconnected"); got != tt.otherLogged {
+				t.Errorf("another message logged = %v, want %v", got, tt.otherLogged)
+			}
+			if got := s.totalsSnapshot(); !reflect.DeepEqual(got, tt.totals) {
+				t.Errorf("totals = %v, want %v", got, tt.totals)
+			}
+
+			s.shutdown()
+			if !reflect.DeepEqual(out.records, tt.summaries) {
+				t.Errorf("summaries = %v, want %v", out.records, tt.summaries)
+			}
+			// Counts reset with every summary.
+			s.summarize()
+			if len(out.records) != len(tt.summaries) {
+				t.Errorf("second summary repeated counts: %v", out.records[len(tt.summaries):])
+			}
+		})
+	}
+}
+
+func TestLogSamplerWindow(t *testing.T) {
+	s := newLogSampler(LogSamplingConfig{Interval: 20 * time.Millisecond, First: 1, SummaryInterval: time.Hour}, &recordHandler{}, wallClock{})
+	defer s.shutdown()
+	if !s.allow(slog.LevelWarn, "slow") || s.allow(slog.LevelWarn, "slow") {
+		t.Fatal("want only the first record of the window")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !s.allow(slog.LevelWarn, "slow") {
+		t.Error("first record of a new window suppressed")
+	}
+}
+
+func TestLogSamplerSummaryInterval(t *testing.T) {
+	out := &recordHandler{}
+	s := newLogSampler(LogSamplingConfig{First: 1, SummaryInterval: 10 * time.Millisecond}, out, wallClock{})
+	defer s.shutdown()
+	s.allow(slog.LevelError, "flood")
+	s.allow(slog.LevelError, "flood")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		out.mu.Lock()
+		n := len(out.records)
+		out.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no summary before shutdown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLogSamplingPipeline(t *testing.T) {
+	logs := &recordCounter{}
+	o := newTestObs(t, func(c *Config) {
+		c.LogSampling = LogSamplingConfig{Enabled: true, First: 2}
+		c.LogProcessors = []sdklog.Processor{logs}
+	})
+	for range 5 {
+		o.L().With("attempt", 1).Error("broker disconnected")
+	}
+	if err := o.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	want := []string{"broker disconnected", "broker disconnected", `suppressed 3 occurrences of "broker disconnected"`}
+	if !reflect.DeepEqual(logs.msgs, want) {
+		t.Errorf("logged %q, want %q", logs.msgs, want)
+	}
+}
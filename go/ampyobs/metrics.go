@@ -3,16 +3,13 @@ package ampyobs
 import (
 	"context"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 )
 
-// Global meter assigned after Init sets the meter provider.
-var globalMeter metric.Meter
-
-// Instruments
-var (
+// instruments holds the domain instruments of one Obs instance.
+type instruments struct {
 	busProduced        metric.Int64Counter
 	busConsumed        metric.Int64Counter
 	busDeliveryLatency metric.Float64Histogram
@@ -20,7 +17,7 @@ var (
 	omsOrderSubmit  metric.Int64Counter
 	omsOrderLatency metric.Float64Histogram
 	omsRejections   metric.Int64Counter
-)
+}
 
 // Public enums (bounded label values)
 const (
@@ -30,135 +27,170 @@ const (
 	OutcomeReject = "reject"
 )
 
-// initMetrics constructs instruments. Call once after MeterProvider is set.
-func initMetrics() error {
-	if globalMeter == nil {
-		globalMeter = otel.Meter("ampyobs")
-	}
-
+// newInstruments constructs the domain instruments on meter.
+func newInstruments(meter metric.Meter) (instruments, error) {
+	var in instruments
 	var err error
 
 	// Bus
-	busProduced, err = globalMeter.Int64Counter(
+	in.busProduced, err = meter.Int64Counter(
 		"ampy.bus.produced_total",
 		metric.WithDescription("Messages produced to ampy-bus"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
-	busConsumed, err = globalMeter.Int64Counter(
+	in.busConsumed, err = meter.Int64Counter(
 		"ampy.bus.consumed_total",
 		metric.WithDescription("Messages consumed from ampy-bus"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
-	busDeliveryLatency, err = globalMeter.Float64Histogram(
+	in.busDeliveryLatency, err = meter.Float64Histogram(
 		"ampy.bus.delivery_latency_ms",
 		metric.WithDescription("Bus end-to-end delivery latency in milliseconds"),
 		metric.WithUnit("ms"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
 	// OMS
-	omsOrderSubmit, err = globalMeter.Int64Counter(
+	in.omsOrderSubmit, err = meter.Int64Counter(
 		"ampy.oms.order_submit_total",
 		metric.WithDescription("Order submissions by outcome"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
-	omsOrderLatency, err = globalMeter.Float64Histogram(
+	in.omsOrderLatency, err = meter.Float64Histogram(
 		"ampy.oms.order_latency_ms",
 		metric.WithDescription("OMS order latency (submit→ack) in milliseconds"),
 		metric.WithUnit("ms"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
-	omsRejections, err = globalMeter.Int64Counter(
+	in.omsRejections, err = meter.Int64Counter(
 		"ampy.oms.rejections_total",
 		metric.WithDescription("Order rejections by reason"),
 	)
 	if err != nil {
-		return err
+		return in, err
 	}
 
-	return nil
+	return in, nil
+}
+
+// noopInstruments returns instruments that record nothing, used when metrics are disabled.
+func noopInstruments() instruments {
+	in, _ := newInstruments(noop.NewMeterProvider().Meter("ampyobs"))
+	return in
 }
 
 // ----------- Helper Recording Functions (safe labels only) -----------
 
 // BusProducedAdd increments produced counter for a topic.
-func BusProducedAdd(ctx context.Context, topic string, n int64) {
-	busProduced.Add(ctx, n,
+func (o *Obs) BusProducedAdd(ctx context.Context, topic string, n int64) {
+	o.inst.busProduced.Add(ctx, n,
 		metric.WithAttributes(
 			attribute.String("topic", topic),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
 
 // BusConsumedAdd increments consumed counter for a topic.
-func BusConsumedAdd(ctx context.Context, topic string, n int64) {
-	busConsumed.Add(ctx, n,
+func (o *Obs) BusConsumedAdd(ctx context.Context, topic string, n int64) {
+	o.inst.busConsumed.Add(ctx, n,
 		metric.WithAttributes(
 			attribute.String("topic", topic),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
 
 // BusDeliveryLatencyMs records bus delivery latency for a topic.
-func BusDeliveryLatencyMs(ctx context.Context, topic string, ms float64) {
-	busDeliveryLatency.Record(ctx, ms,
+func (o *Obs) BusDeliveryLatencyMs(ctx context.Context, topic string, ms float64) {
+	o.inst.busDeliveryLatency.Record(ctx, ms,
 		metric.WithAttributes(
 			attribute.String("topic", topic),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
 
 // OMSOrderSubmitAdd increments order submit counter for a broker+outcome.
-func OMSOrderSubmitAdd(ctx context.Context, broker string, outcome string) {
-	omsOrderSubmit.Add(ctx, 1,
+func (o *Obs) OMSOrderSubmitAdd(ctx context.Context, broker string, outcome string) {
+	o.inst.omsOrderSubmit.Add(ctx, 1,
 		metric.WithAttributes(
 			attribute.String("broker", broker),
 			attribute.String("outcome", outcome),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
 
 // OMSOrderLatencyMs records order latency for a broker.
-func OMSOrderLatencyMs(ctx context.Context, broker string, ms float64) {
-	omsOrderLatency.Record(ctx, ms,
+func (o *Obs) OMSOrderLatencyMs(ctx context.Context, broker string, ms float64) {
+	o.inst.omsOrderLatency.Record(ctx, ms,
 		metric.WithAttributes(
 			attribute.String("broker", broker),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
 
 // OMSRejectAdd increments rejection counter for a broker+reason.
-func OMSRejectAdd(ctx context.Context, broker string, reason string) {
-	omsRejections.Add(ctx, 1,
+func (o *Obs) OMSRejectAdd(ctx context.Context, broker string, reason string) {
+	o.inst.omsRejections.Add(ctx, 1,
 		metric.WithAttributes(
 			attribute.String("broker", broker),
 			attribute.String("reason", reason),
-			attribute.String("service", globalCfg.ServiceName),
-			attribute.String("env", globalCfg.Environment),
+			attribute.String("service", o.cfg.ServiceName),
+			attribute.String("env", o.cfg.Environment),
 		),
 	)
 }
+
+// ----------- Package-level wrappers over the Default instance -----------
+
+// BusProducedAdd increments produced counter for a topic.
+func BusProducedAdd(ctx context.Context, topic string, n int64) {
+	Default().BusProducedAdd(ctx, topic, n)
+}
+
+// BusConsumedAdd increments consumed counter for a topic.
+func BusConsumedAdd(ctx context.Context, topic string, n int64) {
+	Default().BusConsumedAdd(ctx, topic, n)
+}
+
+// BusDeliveryLatencyMs records bus delivery latency for a topic.
+func BusDeliveryLatencyMs(ctx context.Context, topic string, ms float64) {
+	Default().BusDeliveryLatencyMs(ctx, topic, ms)
+}
+
+// OMSOrderSubmitAdd increments order submit counter for a broker+outcome.
+func OMSOrderSubmitAdd(ctx context.Context, broker string, outcome string) {
+	Default().OMSOrderSubmitAdd(ctx, broker, outcome)
+}
+
+// OMSOrderLatencyMs records order latency for a broker.
+func OMSOrderLatencyMs(ctx context.Context, broker string, ms float64) {
+	Default().OMSOrderLatencyMs(ctx, broker, ms)
+}
+
+// OMSRejectAdd increments rejection counter for a broker+reason.
+func OMSRejectAdd(ctx context.Context, broker string, reason string) {
+	Default().OMSRejectAdd(ctx, broker, reason)
+}
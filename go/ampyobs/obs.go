@@ -2,46 +2,185 @@ package ampyobs
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
-	"net"
-	"net/url"
-	"strings"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log/global"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
-	"google.golang.org/grpc/credentials"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
 	ServiceName       string
 	ServiceVersion    string
-	Environment       string // dev | paper | prod
-	CollectorEndpoint string // e.g. "http://localhost:4317" or "localhost:4317"
-	TraceProtocol     string // "grpc" | "http" (default: "grpc")
-	EnableLogs        bool   // JSON logs via slog (stdout)
-	EnableMetrics     bool   // OTLP metrics to collector
-	EnableTracing     bool   // OTLP traces to collector
-	Sampler           string // "parent" | "ratio"
+	Environment       string        // dev | paper | prod
+	CollectorEndpoint string        // e.g. "http://localhost:4317" or "localhost:4317"
+	Protocol          string        // "grpc" | "http/protobuf" | "http/json" | "stdout" | "file" | "none" (default: "grpc")
+	TraceProtocol     string        // overrides Protocol for traces ("http" = "http/protobuf")
+	MetricProtocol    string        // overrides Protocol for metrics
+	TraceEndpoint     string        // overrides CollectorEndpoint for traces
+	MetricEndpoint    string        // overrides CollectorEndpoint for metrics
+	LogProtocol       string        // overrides Protocol for logs
+	LogEndpoint       string        // overrides CollectorEndpoint for logs
+	ExportFile        string        // destination of the "file" protocol (default: ampyobs-telemetry.jsonl)
+	ExportFormat      string        // "pretty" | "otlp-json" for stdout/file (default: pretty on stdout, otlp-json in files)
+	TLS               TLSConfig     // TLS/mTLS toward the collector for every exporter
+	Compression       string        // "gzip" | "none" (default: "none")
+	ExportTimeout     time.Duration // per-export deadline (default: 10s)
+	Retry             RetryConfig   // backoff for failed exports
+	WAL               WALConfig     // disk-backed queue for batches the collector did not accept
+	EnableLogs        bool          // JSON logs to stdout, plus OTLP logs via the slog bridge
+	DisableLogStdout  bool          // with EnableLogs, export over OTLP only
+	EnableMetrics     bool          // OTLP metrics to collector
+	EnableTracing     bool          // OTLP traces to collector
+	EnablePrometheus  bool          // serve metrics for scraping via MetricsHandler (OTLP push still needs EnableMetrics)
+	Sampler           string        // "parent" | "ratio" | "always_on" | "always_off" | "ratelimit" | "rules"
 	SampleRatio       float64
+
+	// RateLimit caps the "ratelimit" sampler at this many new traces per
+	// second, letting RateLimitBurst through at once (default: one second's
+	// worth). Leaving Sampler empty and setting RateLimit selects it.
+	RateLimit      float64
+	RateLimitBurst int
+
+	// Headers are sent with every export request (e.g. an auth token).
+	// HeadersFile names a file of "key=value" lines read when exporters are
+	// built, so secrets can be mounted instead of compiled in; Headers win
+	// over matching keys from the file.
+	Headers     map[string]string
+	HeadersFile string
+
+	// LogLevel is the initial root log level: "debug", "info", "warn",
+	// "error" or an offset such as "info+2" (default: info). LogLevels sets
+	// loggers by name (see Obs.Named), e.g. {"oms": "debug", "md": "warn"}.
+	// Both can be changed at runtime with SetLevel or LevelHandler.
+	LogLevel  string
+	LogLevels map[string]string
+
+	// LogSampling caps repeated log lines and summarises what it drops
+	// (see LogSamplingConfig).
+	LogSampling LogSamplingConfig
+
+	// SamplingRules drive the "rules" sampler. SamplingRulesFile names a JSON
+	// file of the same shape read when the tracer provider is built; its
+	// rules are tried after the inline ones.
+	SamplingRules     SamplingRules
+	SamplingRulesFile string
+
+	// RemoteSampling polls sampling strategies from a Jaeger-compatible
+	// endpoint or a file; Sampler applies until the first successful fetch
+	// and whenever the source is unreachable (see RemoteSamplingConfig).
+	RemoteSampling RemoteSamplingConfig
+
+	// TailSampling decides per trace once it completes, keeping errors,
+	// rejects and slow traces (see TailSamplingConfig).
+	TailSampling TailSamplingConfig
+
+	// Routing sends signals to several collectors with a failover or fanout
+	// policy (see RoutingConfig).
+	Routing RoutingConfig
+
+	// Replay makes backtest telemetry reproducible: ids seeded from the run
+	// id and timestamps from a simulated clock (see ReplayConfig).
+	Replay ReplayConfig
+
+	// Pipeline tunes the span and log batchers and the metric reader, or
+	// picks a named profile.
+	Pipeline PipelineConfig
+
+	// ShutdownTimeout bounds the drain performed by RunUntilSignal (default: 10s).
+	ShutdownTimeout time.Duration
+
+	// LenientValidation logs Validate problems as warnings instead of failing New.
+	LenientValidation bool
+
+	// ResourceAttributes are extra resource attributes (e.g. from OTEL_RESOURCE_ATTRIBUTES),
+	// such as team, region or desk tags. ServiceName, ServiceVersion and
+	// Environment take precedence over matching keys.
+	ResourceAttributes map[string]string
+
+	// Detectors adds host, OS, process, container, Kubernetes and build
+	// attributes to the resource (see DetectorsConfig).
+	Detectors DetectorsConfig
+
+	// SpanProcessors, MetricReaders and LogProcessors are attached next to
+	// the configured exporters, e.g. in-memory ones in tests (see the
+	// ampyobstest package). They are shut down with the instance.
+	SpanProcessors []sdktrace.SpanProcessor
+	MetricReaders  []sdkmetric.Reader
+	LogProcessors  []sdklog.Processor
+}
+
+// Obs is an isolated observability handle. It owns its resource, providers,
+// logger and domain instruments, so several can live in one process.
+type Obs struct {
+	cfg    Config
+	res    *resource.Resource
+	prop   propagation.TextMapPropagator
+	tp     *sdktrace.TracerProvider
+	mp     *sdkmetric.MeterProvider
+	lp     *sdklog.LoggerProvider
+	logger *slog.Logger
+	inst   instruments
+	stats  *obsStats
+	debug  *debugTargets
+	levels *logLevels
+
+	logSampler  *logSampler  // nil unless Config.LogSampling is enabled
+	promHandler http.Handler // nil unless Config.EnablePrometheus
+
+	shutdownOnce sync.Once
+	shutdownErr  error // result of the first Shutdown
 }
 
 var (
-	globalCfg       Config
-	tracerProvider  *sdktrace.TracerProvider
-	meterProvider   *sdkmetric.MeterProvider
-	globalResources *resource.Resource
+	defaultObs atomic.Pointer[Obs]
+	noopOnce   sync.Once
+	noopObs    *Obs
 )
 
+// Default returns the instance used by the package-level helpers. Before
+// Init (or SetDefault) it is a no-op instance that logs to stdout and
+// delegates tracing and propagation to the OTel globals.
+func Default() *Obs {
+	if o := defaultObs.Load(); o != nil {
+		return o
+	}
+	noopOnce.Do(func() {
+		debug, levels := newDebugTargets(), newLogLevels(Config{})
+		noopObs = &Obs{
+			prop:   otel.GetTextMapPropagator(),
+			logger: slog.New(newLevelHandler(newStdoutHandler(allLevels), levels, debug)),
+			inst:   noopInstruments(),
+			stats:  &obsStats{},
+			debug:  debug,
+			levels: levels,
+		}
+	})
+	return noopObs
+}
+
+// SetDefault makes o the instance used by the package-level helpers.
+// Passing nil restores the no-op instance.
+func SetDefault(o *Obs) {
+	defaultObs.Store(o)
+}
+
 // SetErrorHandler sets a custom error handler for OTel errors
 func SetErrorHandler(handler func(error)) {
 	otel.SetErrorHandler(errorHandlerFunc(handler))
@@ -75,190 +214,384 @@ func getMetricViews() []sdkmetric.View {
 				},
 			},
 		),
+		sdkmetric.NewView(
+			sdkmetric.Instrument{Name: "ampy.obs.export.latency_ms"},
+			sdkmetric.Stream{
+				Aggregation: sdkmetric.AggregationExplicitBucketHistogram{
+					Boundaries: histogramBoundariesMs(),
+				},
+			},
+		),
 	}
 }
 
+// Init creates an instance via New, installs its providers and propagator
+// as the OTel globals and makes it the Default for package-level helpers.
 func Init(cfg Config) error {
-	globalCfg = cfg
+	o, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	otel.SetTextMapPropagator(o.prop)
+	if o.tp != nil {
+		otel.SetTracerProvider(o.TracerProvider())
+	}
+	if o.mp != nil {
+		otel.SetMeterProvider(o.mp)
+	}
+	if o.lp != nil {
+		global.SetLoggerProvider(o.lp)
+	}
+	SetDefault(o)
+	return nil
+}
+
+// New resolves cfg against the environment (see ConfigFromEnv), validates it
+// (see Config.Validate) and builds an instance with its own providers. Fields
+// set explicitly in cfg win over env. Unlike Init, New does not touch the
+// OTel globals.
+func New(cfg Config) (*Obs, error) {
+	env, envErr := ConfigFromEnv()
+	explicit := cfg
+	cfg = cfg.clone().withEnv(env)
+
+	// ----- Logging -----
+	// JSON stdout; adds trace/span when ctx provided
+	logger := newSlogLogger()
+
+	// ----- Validation -----
+	// Malformed env vars count as config problems unless an explicit field
+	// overrides them.
+	var problems []error
+	for _, e := range unjoin(envErr) {
+		var ce *ConfigError
+		if errors.As(e, &ce) && explicit.overridesEnv(ce.Field) {
+			continue
+		}
+		problems = append(problems, e)
+	}
+	problems = append(problems, unjoin(cfg.Validate())...)
+	if len(problems) > 0 {
+		if !cfg.LenientValidation {
+			return nil, errors.Join(problems...)
+		}
+		for _, e := range problems {
+			logger.Warn("ampyobs config warning", slog.String("error", e.Error()))
+		}
+	}
 
 	// ----- Resource -----
-	res, err := resource.Merge(
-		resource.Default(),
-		resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceName(cfg.ServiceName),
-			semconv.ServiceVersion(cfg.ServiceVersion),
-			semconv.DeploymentEnvironmentName(cfg.Environment),
-		),
-	)
+	res, err := newResource(cfg)
 	if err != nil {
-		return fmt.Errorf("resource: %w", err)
+		return nil, fmt.Errorf("resource: %w", err)
 	}
-	globalResources = res
 
-	// ----- Propagation (W3C) -----
-	otel.SetTextMapPropagator(
-		propagation.NewCompositeTextMapPropagator(
+	debug, levels := newDebugTargets(), newLogLevels(cfg)
+	o := &Obs{
+		cfg: cfg,
+		res: res,
+		// ----- Propagation (W3C) -----
+		prop: propagation.NewCompositeTextMapPropagator(
 			propagation.TraceContext{},
 			propagation.Baggage{},
 		),
-	)
+		logger: slog.New(newLevelHandler(newStdoutHandler(allLevels), levels, debug)),
+		inst:   noopInstruments(),
+		stats:  &obsStats{},
+		debug:  debug,
+		levels: levels,
+	}
 
-	// ----- Logging -----
+	// ----- Log export (OTLP via slog bridge) -----
 	if cfg.EnableLogs {
-		setupSlog(res) // JSON stdout with resource attrs; adds trace/span when ctx provided
+		lp, err := newLoggerProvider(cfg, res, &o.stats.logs)
+		if err != nil {
+			return nil, err
+		}
+		o.lp = lp
+		oh := newOTelHandler(lp.Logger("ampyobs"), allLevels)
+		oh.clock = cfg.Replay.Clock
+		var h slog.Handler = oh
+		if !cfg.DisableLogStdout {
+			h = teeHandler{newStdoutHandler(allLevels), h}
+		}
+		o.logger = slog.New(newLevelHandler(h, levels, debug))
+	}
+	if cfg.LogSampling.Enabled {
+		out := o.logger.Handler().WithAttrs([]slog.Attr{
+			slog.String("service", cfg.ServiceName),
+			slog.String("env", cfg.Environment),
+			slog.String("service_version", cfg.ServiceVersion),
+		})
+		o.logSampler = newLogSampler(cfg.LogSampling, out, cfg.Replay.clock())
+		o.logger = slog.New(samplingHandler{Handler: o.logger.Handler(), sampler: o.logSampler})
+	}
+	if cfg.Replay.Clock != nil {
+		o.logger = slog.New(clockHandler{Handler: o.logger.Handler(), clock: cfg.Replay.Clock})
 	}
 
 	// ----- Tracing -----
 	if cfg.EnableTracing {
-		tp, err := newTracerProvider(cfg, res)
+		tp, err := newTracerProvider(cfg, res, &o.stats.spans, debug)
 		if err != nil {
-			return err
+			_ = o.Shutdown(context.Background())
+			return nil, err
 		}
-		tracerProvider = tp
-		otel.SetTracerProvider(tp)
+		o.tp = tp
 	}
 
 	// ----- Metrics -----
-	if cfg.EnableMetrics {
-		mp, err := newMeterProvider(cfg, res)
+	if cfg.EnableMetrics || cfg.EnablePrometheus {
+		var prom sdkmetric.Reader
+		if cfg.EnablePrometheus {
+			prom, o.promHandler, err = newPrometheusReader()
+			if err != nil {
+				_ = o.Shutdown(context.Background())
+				return nil, err
+			}
+		}
+		mp, err := newMeterProvider(cfg, res, &o.stats.metrics, prom)
 		if err != nil {
-			return err
+			_ = o.Shutdown(context.Background())
+			return nil, err
 		}
-		meterProvider = mp
-		otel.SetMeterProvider(mp)
+		o.mp = mp
 
 		// Domain metrics helpers (counters/histograms with safe labels)
-		globalMeter = otel.Meter("ampyobs")
-		if err := initMetrics(); err != nil {
-			return fmt.Errorf("init metrics: %w", err)
+		inst, err := newInstruments(mp.Meter("ampyobs"))
+		if err != nil {
+			_ = o.Shutdown(context.Background())
+			return nil, fmt.Errorf("init metrics: %w", err)
 		}
+		o.inst = inst
+
+		// Host-dependent metrics would make replays differ.
+		if !cfg.Replay.enabled() {
+			// Pipeline self-metrics (ampy.obs.*)
+			if err := o.registerSelfMetrics(mp.Meter("ampyobs")); err != nil {
+				_ = o.Shutdown(context.Background())
+				return nil, fmt.Errorf("init self metrics: %w", err)
+			}
 
-		// Runtime metrics (GC, mem, goroutines, etc.)
-		_ = runtime.Start(
-			runtime.WithMinimumReadMemStatsInterval(10*time.Second),
-			runtime.WithMeterProvider(mp),
-		)
+			// Runtime metrics (GC, mem, goroutines, etc.)
+			_ = runtime.Start(
+				runtime.WithMinimumReadMemStatsInterval(10*time.Second),
+				runtime.WithMeterProvider(mp),
+			)
+		}
 	}
 
-	return nil
+	return o, nil
+}
+
+// Config returns the effective configuration after New merged explicit
+// fields with the environment. Useful for debugging deployments.
+func (o *Obs) Config() Config {
+	return o.cfg.clone()
+}
+
+// ResolvedConfig returns the effective configuration of the Default instance.
+func ResolvedConfig() Config {
+	return Default().Config()
 }
 
-func newTracerProvider(cfg Config, res *resource.Resource) (*sdktrace.TracerProvider, error) {
-	endpoint, insecure := parseEndpoint(cfg.CollectorEndpoint)
-	protocol := strings.ToLower(cfg.TraceProtocol)
-	if protocol == "" {
-		protocol = "grpc" // default
+// TracerProvider returns the instance's tracer provider, or nil when tracing
+// is disabled. With Replay.Clock set, its spans are stamped with that clock.
+// Flush and shut it down through ForceFlush and Shutdown.
+func (o *Obs) TracerProvider() trace.TracerProvider {
+	if o.tp == nil {
+		return nil
 	}
+	if c := o.cfg.Replay.Clock; c != nil {
+		return clockTracerProvider{tp: o.tp, clock: c}
+	}
+	return o.tp
+}
 
-	var exp sdktrace.SpanExporter
-	var err error
+// MeterProvider returns the instance's meter provider, or nil when metrics are disabled.
+func (o *Obs) MeterProvider() *sdkmetric.MeterProvider { return o.mp }
 
-	switch protocol {
-	case "http":
-		// HTTP exporter (port 4318)
-		opts := []otlptracehttp.Option{
-			otlptracehttp.WithEndpoint(endpoint),
-		}
-		if insecure {
-			opts = append(opts, otlptracehttp.WithInsecure())
-		}
-		exp, err = otlptracehttp.New(context.Background(), opts...)
+// LoggerProvider returns the instance's OTel logger provider, or nil when logs are disabled.
+func (o *Obs) LoggerProvider() *sdklog.LoggerProvider { return o.lp }
+
+func newResource(cfg Config) (*resource.Resource, error) {
+	attrs := make([]attribute.KeyValue, 0, len(cfg.ResourceAttributes)+3)
+	keys := make([]string, 0, len(cfg.ResourceAttributes))
+	for k := range cfg.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, attribute.String(k, cfg.ResourceAttributes[k]))
+	}
+	if cfg.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(cfg.ServiceName))
+	}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.ServiceVersion))
+	}
+	if cfg.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironmentName(cfg.Environment))
+	}
+	if cfg.Replay.RunID != "" {
+		attrs = append(attrs, attribute.String("ampy.run_id", cfg.Replay.RunID))
+	}
+	base := resource.Default()
+	if cfg.Detectors.any() {
+		detected, err := detectResource(context.Background(), cfg.Detectors)
 		if err != nil {
-			return nil, fmt.Errorf("otlptrace http exporter: %w", err)
+			return nil, fmt.Errorf("detect resource: %w", err)
 		}
-	case "grpc":
-		// gRPC exporter (port 4317)
-		opts := []otlptracegrpc.Option{
-			otlptracegrpc.WithEndpoint(endpoint),
+		if base, err = resource.Merge(base, detected); err != nil {
+			return nil, err
 		}
-		if insecure {
-			opts = append(opts, otlptracegrpc.WithInsecure())
-		} else {
-			opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{})))
-		}
-		exp, err = otlptracegrpc.New(context.Background(), opts...)
+	}
+	return resource.Merge(
+		base,
+		resource.NewWithAttributes(semconv.SchemaURL, attrs...),
+	)
+}
+
+func newTracerProvider(cfg Config, res *resource.Resource, stats *exportStats, debug *debugTargets) (*sdktrace.TracerProvider, error) {
+	exp, err := newSpanExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	stats.router = routerOf(exp)
+	if cfg.WAL.enabled() {
+		// The WAL records export outcomes itself: persisted is not failed.
+		w, err := newWALSpanExporter(exp, cfg.WAL, stats)
 		if err != nil {
-			return nil, fmt.Errorf("otlptrace grpc exporter: %w", err)
+			_ = exp.Shutdown(context.Background())
+			return nil, err
 		}
-	default:
-		return nil, fmt.Errorf("unsupported trace protocol: %s (use 'grpc' or 'http')", cfg.TraceProtocol)
+		stats.wal, exp = w.core.q, w
+	} else {
+		exp = countingSpanExporter{SpanExporter: exp, stats: stats}
 	}
 
-	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.25))
-	switch strings.ToLower(cfg.Sampler) {
-	case "ratio":
-		if cfg.SampleRatio >= 0 && cfg.SampleRatio <= 1 {
-			sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+	// With tail sampling every trace is recorded and the tail sampler decides
+	// what is exported; Validate rejects a head sampler alongside it.
+	sampler := sdktrace.ParentBased(sdktrace.AlwaysSample())
+	if !cfg.TailSampling.Enabled {
+		var err error
+		if sampler, err = newSampler(cfg, stats); err != nil {
+			_ = exp.Shutdown(context.Background())
+			return nil, err
 		}
-	case "parent", "":
-		// keep default
 	}
 
-	tp := sdktrace.NewTracerProvider(
+	ps := cfg.Pipeline.settings()
+	var sp sdktrace.SpanProcessor = spanGate{
+		next: sdktrace.NewBatchSpanProcessor(exp,
+			sdktrace.WithMaxQueueSize(ps.maxQueueSize),
+			sdktrace.WithMaxExportBatchSize(ps.maxExportBatchSize),
+			sdktrace.WithBatchTimeout(ps.spanBatchTimeout),
+			sdktrace.WithExportTimeout(ps.batchExportTimeout)),
+		stats: stats,
+		limit: int64(ps.maxQueueSize),
+	}
+	if cfg.TailSampling.Enabled {
+		stats.tail = newTailSampler(cfg.TailSampling, sp, cfg.Replay.clock())
+		sp = stats.tail
+	}
+	sampler = debugSampler{next: sampler, debug: debug}
+
+	opts := []sdktrace.TracerProviderOption{
 		sdktrace.WithResource(res),
 		sdktrace.WithSampler(sampler),
-		sdktrace.WithBatcher(exp,
-			sdktrace.WithMaxExportBatchSize(512),
-			sdktrace.WithBatchTimeout(5*time.Second)),
-	)
-	return tp, nil
-}
-
-func newMeterProvider(cfg Config, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
-	endpoint, insecure := parseEndpoint(cfg.CollectorEndpoint)
-
-	opts := []otlpmetricgrpc.Option{
-		otlpmetricgrpc.WithEndpoint(endpoint),
+		sdktrace.WithSpanProcessor(sp),
 	}
-	if insecure {
-		opts = append(opts, otlpmetricgrpc.WithInsecure())
-	} else {
-		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{})))
+	if cfg.Replay.RunID != "" {
+		opts = append(opts, sdktrace.WithIDGenerator(newSeededIDGenerator(cfg.Replay.RunID)))
 	}
-
-	exp, err := otlpmetricgrpc.New(context.Background(), opts...)
-	if err != nil {
-		return nil, fmt.Errorf("otlpmetric exporter: %w", err)
+	for _, sp := range cfg.SpanProcessors {
+		opts = append(opts, sdktrace.WithSpanProcessor(sp))
 	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
 
-	reader := sdkmetric.NewPeriodicReader(exp,
-		sdkmetric.WithInterval(10*time.Second),
-	)
-	mp := sdkmetric.NewMeterProvider(
+// newMeterProvider attaches the OTLP push reader when cfg.EnableMetrics is
+// set and prom (the Prometheus pull reader) when non-nil.
+func newMeterProvider(cfg Config, res *resource.Resource, stats *exportStats, prom sdkmetric.Reader) (*sdkmetric.MeterProvider, error) {
+	opts := []sdkmetric.Option{
 		sdkmetric.WithResource(res),
-		sdkmetric.WithReader(reader),
 		sdkmetric.WithView(getMetricViews()...),
-	)
-	return mp, nil
-}
-
-func Shutdown(ctx context.Context) error {
-	if meterProvider != nil {
-		_ = meterProvider.Shutdown(ctx)
 	}
-	if tracerProvider != nil {
-		return tracerProvider.Shutdown(ctx)
+	if cfg.EnableMetrics {
+		exp, err := newMetricExporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		stats.router = routerOf(exp)
+		if cfg.WAL.enabled() {
+			w, err := newWALMetricExporter(exp, cfg.WAL, stats)
+			if err != nil {
+				_ = exp.Shutdown(context.Background())
+				return nil, err
+			}
+			stats.wal, exp = w.core.q, w
+		} else {
+			exp = countingMetricExporter{Exporter: exp, stats: stats}
+		}
+		ps := cfg.Pipeline.settings()
+		interval := ps.metricInterval
+		if cfg.Replay.enabled() {
+			interval = replayMetricInterval
+			if cfg.Replay.Clock != nil {
+				exp = clockMetricExporter{Exporter: exp, clock: cfg.Replay.Clock, start: cfg.Replay.Clock.Now()}
+			}
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp,
+			sdkmetric.WithInterval(interval),
+			sdkmetric.WithTimeout(ps.metricTimeout),
+		)))
+	}
+	if cfg.Replay.enabled() {
+		opts = append(opts, sdkmetric.WithExemplarFilter(exemplar.AlwaysOffFilter))
 	}
-	return nil
+	if prom != nil {
+		opts = append(opts, sdkmetric.WithReader(prom))
+	}
+	for _, r := range cfg.MetricReaders {
+		opts = append(opts, sdkmetric.WithReader(r))
+	}
+	return sdkmetric.NewMeterProvider(opts...), nil
 }
 
-func parseEndpoint(raw string) (hostport string, insecure bool) {
-	if raw == "" {
-		return "localhost:4317", true
+func newLoggerProvider(cfg Config, res *resource.Resource, stats *exportStats) (*sdklog.LoggerProvider, error) {
+	exp, err := newLogExporter(cfg)
+	if err != nil {
+		return nil, err
 	}
-	u, err := url.Parse(raw)
-	if err != nil || u.Scheme == "" {
-		// likely "host:port"
-		host, port, _ := net.SplitHostPort(raw)
-		if port == "" {
-			return raw, true
-		}
-		if host == "" {
-			return "localhost:" + port, true
+	stats.router = routerOf(exp)
+	if cfg.WAL.enabled() {
+		w, err := newWALLogExporter(exp, cfg.WAL, stats)
+		if err != nil {
+			_ = exp.Shutdown(context.Background())
+			return nil, err
 		}
-		return raw, true
+		stats.wal, exp = w.core.q, w
+	} else {
+		exp = countingLogExporter{Exporter: exp, stats: stats}
+	}
+
+	ps := cfg.Pipeline.settings()
+	opts := []sdklog.LoggerProviderOption{
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(logGate{
+			next: sdklog.NewBatchProcessor(exp,
+				sdklog.WithMaxQueueSize(ps.maxQueueSize),
+				sdklog.WithExportMaxBatchSize(ps.maxExportBatchSize),
+				sdklog.WithExportInterval(ps.logBatchTimeout),
+				sdklog.WithExportTimeout(ps.batchExportTimeout),
+				sdklog.WithExportBufferSize(ps.logExportBuffer)),
+			stats: stats,
+			limit: int64(ps.maxQueueSize),
+		}),
+	}
+	for _, p := range cfg.LogProcessors {
+		opts = append(opts, sdklog.WithProcessor(p))
 	}
-	insecure = (u.Scheme == "http")
-	return u.Host, insecure
+	return sdklog.NewLoggerProvider(opts...), nil
 }
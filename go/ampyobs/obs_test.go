@@ -0,0 +1,124 @@
+package ampyobs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+// captureStdout returns what fn wrote to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&buf, r)
+		close(done)
+	}()
+	fn()
+	_ = w.Close()
+	<-done
+	return buf.String()
+}
+
+// recordCounter is a log processor counting the records it sees.
+type recordCounter struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (p *recordCounter) OnEmit(_ context.Context, r *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, r.Body().AsString())
+	return nil
+}
+
+func (p *recordCounter) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+func (p *recordCounter) Shutdown(context.Context) error                         { return nil }
+func (p *recordCounter) ForceFlush(context.Context) error                       { return nil }
+
+func (p *recordCounter) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.msgs)
+}
+
+func TestNewLogOutputs(t *testing.T) {
+	tests := []struct {
+		name       string
+		enableLogs bool
+		noStdout   bool
+		wantStdout bool
+		wantOTLP   bool
+	}{
+		{name: "logs disabled", wantStdout: true},
+		{name: "logs enabled", enableLogs: true, wantStdout: true, wantOTLP: true},
+		{name: "otlp only", enableLogs: true, noStdout: true, wantOTLP: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &recordCounter{}
+			out := captureStdout(t, func() {
+				o, err := New(Config{
+					ServiceName:      "svc",
+					Environment:      "dev",
+					Protocol:         ProtocolNone,
+					EnableLogs:       tt.enableLogs,
+					DisableLogStdout: tt.noStdout,
+					LogProcessors:    []sdklog.Processor{rc},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				o.L().Info("order accepted")
+				_ = o.Shutdown(context.Background())
+			})
+			if got := strings.Contains(out, "order accepted"); got != tt.wantStdout {
+				t.Errorf("stdout has record = %v, want %v (stdout %q)", got, tt.wantStdout, out)
+			}
+			if got := rc.count() == 1; got != tt.wantOTLP {
+				t.Errorf("exported %d records, want OTLP %v", rc.count(), tt.wantOTLP)
+			}
+		})
+	}
+}
+
+// newTestObs returns an instance with every signal enabled and exported
+// nowhere.
+func newTestObs(t *testing.T, configure ...func(*Config)) *Obs {
+	t.Helper()
+	cfg := Config{
+		ServiceName:      "svc",
+		Environment:      "dev",
+		Protocol:         ProtocolNone,
+		EnableTracing:    true,
+		EnableMetrics:    true,
+		EnableLogs:       true,
+		DisableLogStdout: true,
+		Sampler:          "always_on",
+	}
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+	o, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = o.Shutdown(context.Background()) })
+	return o
+}
@@ -0,0 +1,148 @@
+package ampyobs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// This file is the inverse of otlpjson.go: it turns OTLP protobuf log
+// records back into SDK records so persisted batches (see wal.go) can be
+// replayed through any exporter.
+
+// ----------- Logs -----------
+
+// logsFromProto rebuilds SDK log records. sdklog.Record has no setters for
+// resource and scope, so records are re-emitted through a LoggerProvider for
+// their resource and captured on the way out.
+func logsFromProto(rls []*logspb.ResourceLogs) []sdklog.Record {
+	logDecoder.mu.Lock()
+	defer logDecoder.mu.Unlock()
+
+	logDecoder.capture.records = nil
+	for _, rl := range rls {
+		lp := logDecoder.provider(otlpconv.ResourceFromProto(rl.GetResource(), rl.GetSchemaUrl()))
+		for _, sl := range rl.GetScopeLogs() {
+			scope := sl.GetScope()
+			logger := lp.Logger(scope.GetName(),
+				otellog.WithInstrumentationVersion(scope.GetVersion()),
+				otellog.WithSchemaURL(sl.GetSchemaUrl()),
+				otellog.WithInstrumentationAttributes(otlpconv.AttrsFromProto(scope.GetAttributes())...),
+			)
+			for _, lr := range sl.GetLogRecords() {
+				ctx := trace.ContextWithSpanContext(context.Background(),
+					otlpconv.SpanContextFromProto(lr.GetTraceId(), lr.GetSpanId(), lr.GetFlags(), ""))
+				logger.Emit(ctx, logRecordFromProto(lr))
+			}
+		}
+	}
+	records := logDecoder.capture.records
+	logDecoder.capture.records = nil
+	return records
+}
+
+// maxLogDecodeProviders bounds the providers kept by logDecoder. A process
+// replays its own resource, plus those of earlier runs left in the WAL.
+const maxLogDecodeProviders = 8
+
+// logDecoder keeps one capturing LoggerProvider per resource, so replaying
+// a batch does not build and tear down a provider each time.
+var logDecoder = &logDecodeCache{providers: map[resourceKey]*sdklog.LoggerProvider{}}
+
+type logDecodeCache struct {
+	mu        sync.Mutex // held for a whole logsFromProto call
+	capture   captureProcessor
+	providers map[resourceKey]*sdklog.LoggerProvider
+}
+
+type resourceKey struct {
+	attrs     attribute.Distinct
+	schemaURL string
+}
+
+// provider returns the provider for res. The caller holds d.mu.
+func (d *logDecodeCache) provider(res *resource.Resource) *sdklog.LoggerProvider {
+	key := resourceKey{attrs: res.Equivalent(), schemaURL: res.SchemaURL()}
+	if lp, ok := d.providers[key]; ok {
+		return lp
+	}
+	if len(d.providers) >= maxLogDecodeProviders {
+		for k, lp := range d.providers {
+			_ = lp.Shutdown(context.Background())
+			delete(d.providers, k)
+		}
+	}
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(&d.capture),
+		// Records were already limited when first emitted.
+		sdklog.WithAttributeCountLimit(-1),
+		sdklog.WithAttributeValueLengthLimit(-1),
+	)
+	d.providers[key] = lp
+	return lp
+}
+
+func logRecordFromProto(lr *logspb.LogRecord) otellog.Record {
+	var r otellog.Record
+	r.SetEventName(lr.GetEventName())
+	r.SetTimestamp(otlpconv.FromUnixNano(lr.GetTimeUnixNano()))
+	r.SetObservedTimestamp(otlpconv.FromUnixNano(lr.GetObservedTimeUnixNano()))
+	r.SetSeverity(otellog.Severity(lr.GetSeverityNumber()))
+	r.SetSeverityText(lr.GetSeverityText())
+	if lr.GetBody() != nil {
+		r.SetBody(logValueFromProto(lr.GetBody()))
+	}
+	for _, kv := range lr.GetAttributes() {
+		r.AddAttributes(otellog.KeyValue{Key: kv.GetKey(), Value: logValueFromProto(kv.GetValue())})
+	}
+	return r
+}
+
+func logValueFromProto(v *commonpb.AnyValue) otellog.Value {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		return otellog.BoolValue(x.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return otellog.Int64Value(x.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return otellog.Float64Value(x.DoubleValue)
+	case *commonpb.AnyValue_StringValue:
+		return otellog.StringValue(x.StringValue)
+	case *commonpb.AnyValue_BytesValue:
+		return otellog.BytesValue(x.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		vals := make([]otellog.Value, 0, len(x.ArrayValue.GetValues()))
+		for _, e := range x.ArrayValue.GetValues() {
+			vals = append(vals, logValueFromProto(e))
+		}
+		return otellog.SliceValue(vals...)
+	case *commonpb.AnyValue_KvlistValue:
+		kvs := make([]otellog.KeyValue, 0, len(x.KvlistValue.GetValues()))
+		for _, kv := range x.KvlistValue.GetValues() {
+			kvs = append(kvs, otellog.KeyValue{Key: kv.GetKey(), Value: logValueFromProto(kv.GetValue())})
+		}
+		return otellog.MapValue(kvs...)
+	default:
+		return otellog.Value{}
+	}
+}
+
+// captureProcessor collects emitted records instead of exporting them.
+type captureProcessor struct{ records []sdklog.Record }
+
+func (p *captureProcessor) OnEmit(_ context.Context, r *sdklog.Record) error {
+	p.records = append(p.records, r.Clone())
+	return nil
+}
+
+func (p *captureProcessor) Shutdown(context.Context) error   { return nil }
+func (p *captureProcessor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,81 @@
+package ampyobs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// emitRecords returns records emitted through a provider for res.
+func emitRecords(t *testing.T, res *resource.Resource, records ...otellog.Record) []sdklog.Record {
+	t.Helper()
+	capture := &captureProcessor{}
+	lp := sdklog.NewLoggerProvider(sdklog.WithResource(res), sdklog.WithProcessor(capture), sdklog.WithAttributeCountLimit(-1))
+	defer func() { _ = lp.Shutdown(context.Background()) }()
+	logger := lp.Logger("ampy", otellog.WithInstrumentationVersion("1.0"))
+	for _, r := range records {
+		logger.Emit(context.Background(), r)
+	}
+	return capture.records
+}
+
+func TestLogsRoundTrip(t *testing.T) {
+	var many otellog.Record
+	many.SetBody(otellog.StringValue("many attributes"))
+	for i := 0; i < 200; i++ { // beyond the SDK's default limit of 128
+		many.AddAttributes(otellog.Int(fmt.Sprintf("k%d", i), i))
+	}
+	var nested otellog.Record
+	nested.SetTimestamp(time.Unix(1700000000, 0))
+	nested.SetSeverity(otellog.SeverityWarn)
+	nested.SetSeverityText("WARN")
+	nested.SetBody(otellog.MapValue(otellog.String("order", "o-1"), otellog.Slice("fills", otellog.Int64Value(1), otellog.BytesValue([]byte{9}))))
+
+	resA := resource.NewSchemaless(attribute.String("service.name", "oms"))
+	resB := resource.NewSchemaless(attribute.String("service.name", "risk"))
+	tests := []struct {
+		name    string
+		records []sdklog.Record
+	}{
+		{"many attributes", emitRecords(t, resA, many)},
+		{"nested body", emitRecords(t, resA, nested)},
+		{"two resources", append(emitRecords(t, resA, nested), emitRecords(t, resB, nested)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logsFromProto(logsToProto(tt.records))
+			if len(got) != len(tt.records) {
+				t.Fatalf("decoded %d records, want %d", len(got), len(tt.records))
+			}
+			for i, want := range tt.records {
+				g := got[i]
+				if !g.Body().Equal(want.Body()) || g.Severity() != want.Severity() || g.SeverityText() != want.SeverityText() || !g.Timestamp().Equal(want.Timestamp()) {
+					t.Errorf("record %d = %v, want %v", i, g.Body(), want.Body())
+				}
+				if g.AttributesLen() != want.AttributesLen() {
+					t.Errorf("record %d has %d attributes, want %d", i, g.AttributesLen(), want.AttributesLen())
+				}
+				if !g.Resource().Equal(want.Resource()) {
+					t.Errorf("record %d resource = %v, want %v", i, g.Resource(), want.Resource())
+				}
+				if g.InstrumentationScope().Name != "ampy" || g.InstrumentationScope().Version != "1.0" {
+					t.Errorf("record %d scope = %+v", i, g.InstrumentationScope())
+				}
+			}
+		})
+	}
+
+	// Providers are kept per resource rather than built per batch.
+	logDecoder.mu.Lock()
+	n := len(logDecoder.providers)
+	logDecoder.mu.Unlock()
+	if n != 2 {
+		t.Errorf("log decoder holds %d providers, want 2", n)
+	}
+}
@@ -0,0 +1,212 @@
+package ampyobs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var errExporterShutdown = errors.New("exporter is shut down")
+
+// jsonHTTPClient posts OTLP/JSON payloads to a single collector URL.
+type jsonHTTPClient struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+	gzip    bool
+	retry   RetryConfig
+
+	mu       sync.RWMutex
+	shutdown bool
+}
+
+func newJSONHTTPClient(ep endpoint, defaultPath string, tc *tls.Config, es exportSettings) *jsonHTTPClient {
+	scheme := "https"
+	if ep.insecure {
+		scheme = "http"
+	}
+	path := ep.path
+	if path == "" {
+		path = defaultPath
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tc != nil {
+		transport.TLSClientConfig = tc
+	}
+	timeout := es.timeout
+	if timeout <= 0 {
+		timeout = defaultExportTimeout
+	}
+	return &jsonHTTPClient{
+		url:     scheme + "://" + ep.hostport + path,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+		headers: es.headers,
+		gzip:    es.gzip,
+		retry:   es.retry,
+	}
+}
+
+// post sends msg, retrying throttled and unavailable responses with
+// exponential backoff until the retry policy's MaxElapsedTime runs out.
+func (c *jsonHTTPClient) post(ctx context.Context, msg proto.Message) error {
+	if c.closed() {
+		return errExporterShutdown
+	}
+
+	body, err := marshalOTLPJSON(msg)
+	if err != nil {
+		return fmt.Errorf("otlp/json encode: %w", err)
+	}
+	if c.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return fmt.Errorf("otlp/json gzip: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("otlp/json gzip: %w", err)
+		}
+		body = buf.Bytes()
+	}
+
+	deadline := time.Now().Add(c.retry.MaxElapsedTime)
+	wait := c.retry.InitialInterval
+	for {
+		retryable, err := c.send(ctx, body)
+		if err == nil || !retryable || c.retry.Disabled {
+			return err
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		if c.closed() {
+			return errors.Join(err, errExporterShutdown)
+		}
+		wait = min(2*wait, c.retry.MaxInterval)
+	}
+}
+
+// send makes a single attempt and reports whether a failure may be retried.
+func (c *jsonHTTPClient) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("otlp/json post %s: %w", c.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("otlp/json post %s: %s: %s", c.url, resp.Status, bytes.TrimSpace(msg))
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, err
+		}
+		return false, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return false, nil
+}
+
+func (c *jsonHTTPClient) closed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.shutdown
+}
+
+func (c *jsonHTTPClient) close() {
+	c.mu.Lock()
+	c.shutdown = true
+	c.mu.Unlock()
+	c.client.CloseIdleConnections()
+}
+
+// jsonSpanExporter exports spans as OTLP/JSON over HTTP.
+type jsonSpanExporter struct{ c *jsonHTTPClient }
+
+func (e *jsonSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return e.c.post(ctx, &collectortracepb.ExportTraceServiceRequest{ResourceSpans: otlpconv.SpansToProto(spans)})
+}
+
+func (e *jsonSpanExporter) Shutdown(context.Context) error {
+	e.c.close()
+	return nil
+}
+
+// jsonMetricExporter exports metrics as OTLP/JSON over HTTP.
+type jsonMetricExporter struct{ c *jsonHTTPClient }
+
+func (e *jsonMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *jsonMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+func (e *jsonMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.c.post(ctx, &collectormetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{otlpconv.MetricsToProto(rm)},
+	})
+}
+
+func (e *jsonMetricExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *jsonMetricExporter) Shutdown(context.Context) error {
+	e.c.close()
+	return nil
+}
+
+// jsonLogExporter exports log records as OTLP/JSON over HTTP.
+type jsonLogExporter struct{ c *jsonHTTPClient }
+
+func (e *jsonLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return e.c.post(ctx, &collectorlogspb.ExportLogsServiceRequest{ResourceLogs: logsToProto(records)})
+}
+
+func (e *jsonLogExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *jsonLogExporter) Shutdown(context.Context) error {
+	e.c.close()
+	return nil
+}
@@ -0,0 +1,156 @@
+package ampyobs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// This file converts log records into OTLP protobuf messages (spans and
+// metrics are converted by internal/otlpconv, shared with the zap flavor) and
+// encodes them as OTLP/JSON. The OTel Go exporters only speak OTLP/protobuf,
+// so the http/json protocol (and anything else that wants OTLP/JSON) goes
+// through here.
+
+// marshalOTLPJSON encodes m per the OTLP/JSON spec: lowerCamelCase keys,
+// enums as integers and trace/span ids as hex rather than base64.
+func marshalOTLPJSON(m proto.Message) ([]byte, error) {
+	raw, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	hexIDs(doc)
+	return json.Marshal(doc)
+}
+
+// hexIDs rewrites base64 id fields produced by protojson into hex in place.
+func hexIDs(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			switch k {
+			case "traceId", "spanId", "parentSpanId":
+				if s, ok := val.(string); ok {
+					if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+						t[k] = hex.EncodeToString(b)
+					}
+				}
+			default:
+				hexIDs(val)
+			}
+		}
+	case []any:
+		for _, val := range t {
+			hexIDs(val)
+		}
+	}
+}
+
+// ----------- Logs -----------
+
+// logsToProto groups log records by resource and instrumentation scope.
+func logsToProto(records []sdklog.Record) []*logspb.ResourceLogs {
+	var out []*logspb.ResourceLogs
+	byRes := map[attribute.Distinct]*logspb.ResourceLogs{}
+	byScope := map[attribute.Distinct]map[instrumentation.Scope]*logspb.ScopeLogs{}
+
+	for i := range records {
+		r := &records[i]
+		res := r.Resource()
+		var key attribute.Distinct
+		if res != nil {
+			key = res.Equivalent()
+		}
+		rl, ok := byRes[key]
+		if !ok {
+			rl = &logspb.ResourceLogs{Resource: otlpconv.ResourceToProto(res)}
+			if res != nil {
+				rl.SchemaUrl = res.SchemaURL()
+			}
+			byRes[key] = rl
+			byScope[key] = map[instrumentation.Scope]*logspb.ScopeLogs{}
+			out = append(out, rl)
+		}
+		scope := r.InstrumentationScope()
+		sl, ok := byScope[key][scope]
+		if !ok {
+			sl = &logspb.ScopeLogs{Scope: otlpconv.ScopeToProto(scope), SchemaUrl: scope.SchemaURL}
+			byScope[key][scope] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, logRecordToProto(r))
+	}
+	return out
+}
+
+func logRecordToProto(r *sdklog.Record) *logspb.LogRecord {
+	lr := &logspb.LogRecord{
+		TimeUnixNano:           otlpconv.UnixNano(r.Timestamp()),
+		ObservedTimeUnixNano:   otlpconv.UnixNano(r.ObservedTimestamp()),
+		SeverityNumber:         logspb.SeverityNumber(r.Severity()),
+		SeverityText:           r.SeverityText(),
+		DroppedAttributesCount: uint32(r.DroppedAttributes()),
+		Flags:                  uint32(r.TraceFlags()),
+		EventName:              r.EventName(),
+	}
+	if body := r.Body(); !body.Empty() {
+		lr.Body = logValueToProto(body)
+	}
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: kv.Key, Value: logValueToProto(kv.Value)})
+		return true
+	})
+	if tid := r.TraceID(); tid.IsValid() {
+		lr.TraceId = tid[:]
+	}
+	if sid := r.SpanID(); sid.IsValid() {
+		lr.SpanId = sid[:]
+	}
+	return lr
+}
+
+func logValueToProto(v otellog.Value) *commonpb.AnyValue {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case otellog.KindInt64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case otellog.KindFloat64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case otellog.KindString:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case otellog.KindBytes:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case otellog.KindSlice:
+		arr := &commonpb.ArrayValue{}
+		for _, e := range v.AsSlice() {
+			arr.Values = append(arr.Values, logValueToProto(e))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+	case otellog.KindMap:
+		kvs := &commonpb.KeyValueList{}
+		for _, kv := range v.AsMap() {
+			kvs.Values = append(kvs.Values, &commonpb.KeyValue{Key: kv.Key, Value: logValueToProto(kv.Value)})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kvs}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
@@ -0,0 +1,104 @@
+package ampyobs
+
+import (
+	"strings"
+	"time"
+)
+
+// Pipeline profiles accepted by PipelineConfig.Profile.
+const (
+	ProfileOMS  = "oms"  // low latency: sub-second span and log export
+	ProfileBulk = "bulk" // high throughput: large queues and batches
+	ProfileJob  = "job"  // short-lived jobs: frequent export, flush everything on Shutdown
+)
+
+// PipelineConfig tunes the span and log batchers and the metric reader.
+// Zero fields take the Profile's value, then the defaults below.
+type PipelineConfig struct {
+	Profile string // "oms" | "bulk" | "job" (default: none)
+
+	MaxQueueSize        int           // spans or logs buffered before new ones are dropped (default: 2048)
+	MaxExportBatchSize  int           // items per export request (default: 512)
+	BatchTimeout        time.Duration // max wait before exporting a partial batch (default: 5s spans, 1s logs)
+	BatchExportTimeout  time.Duration // bound on one batch export, retries included (default: 30s)
+	LogExportBufferSize int           // full log batches waiting for the exporter (default: 1)
+	MetricInterval      time.Duration // OTLP metric export interval (default: 10s)
+	MetricTimeout       time.Duration // bound on one metric collection and export (default: 30s)
+
+	// FlushOnShutdown makes Shutdown run ForceFlush first, so the final
+	// metric collection also counts the spans and logs flushed on the way out.
+	FlushOnShutdown bool
+}
+
+var pipelineProfiles = map[string]PipelineConfig{
+	ProfileOMS: {
+		MaxExportBatchSize: 128,
+		BatchTimeout:       200 * time.Millisecond,
+		BatchExportTimeout: 5 * time.Second,
+		MetricInterval:     time.Second,
+		MetricTimeout:      5 * time.Second,
+	},
+	ProfileBulk: {
+		MaxQueueSize:        16384,
+		MaxExportBatchSize:  4096,
+		BatchTimeout:        10 * time.Second,
+		LogExportBufferSize: 4,
+		MetricInterval:      30 * time.Second,
+	},
+	ProfileJob: {
+		BatchTimeout:    time.Second,
+		MetricInterval:  5 * time.Second,
+		FlushOnShutdown: true,
+	},
+}
+
+// pipelineSettings is a PipelineConfig with every knob resolved.
+type pipelineSettings struct {
+	maxQueueSize       int
+	maxExportBatchSize int
+	spanBatchTimeout   time.Duration
+	logBatchTimeout    time.Duration
+	batchExportTimeout time.Duration
+	logExportBuffer    int
+	metricInterval     time.Duration
+	metricTimeout      time.Duration
+	flushOnShutdown    bool
+}
+
+// settings resolves p against its profile and the defaults.
+func (p PipelineConfig) settings() pipelineSettings {
+	p = p.or(pipelineProfiles[strings.ToLower(p.Profile)])
+	s := pipelineSettings{
+		maxQueueSize:       valueOr(p.MaxQueueSize, defaultMaxQueueSize),
+		maxExportBatchSize: valueOr(p.MaxExportBatchSize, 512),
+		spanBatchTimeout:   valueOr(p.BatchTimeout, 5*time.Second),
+		logBatchTimeout:    valueOr(p.BatchTimeout, time.Second),
+		batchExportTimeout: valueOr(p.BatchExportTimeout, 30*time.Second),
+		logExportBuffer:    valueOr(p.LogExportBufferSize, 1),
+		metricInterval:     valueOr(p.MetricInterval, 10*time.Second),
+		metricTimeout:      valueOr(p.MetricTimeout, 30*time.Second),
+		flushOnShutdown:    p.FlushOnShutdown,
+	}
+	s.maxExportBatchSize = min(s.maxExportBatchSize, s.maxQueueSize)
+	return s
+}
+
+// or fills the zero fields of p from base. Profile is kept from p.
+func (p PipelineConfig) or(base PipelineConfig) PipelineConfig {
+	p.MaxQueueSize = valueOr(p.MaxQueueSize, base.MaxQueueSize)
+	p.MaxExportBatchSize = valueOr(p.MaxExportBatchSize, base.MaxExportBatchSize)
+	p.BatchTimeout = valueOr(p.BatchTimeout, base.BatchTimeout)
+	p.BatchExportTimeout = valueOr(p.BatchExportTimeout, base.BatchExportTimeout)
+	p.LogExportBufferSize = valueOr(p.LogExportBufferSize, base.LogExportBufferSize)
+	p.MetricInterval = valueOr(p.MetricInterval, base.MetricInterval)
+	p.MetricTimeout = valueOr(p.MetricTimeout, base.MetricTimeout)
+	p.FlushOnShutdown = p.FlushOnShutdown || base.FlushOnShutdown
+	return p
+}
+
+func valueOr[T int | time.Duration](v, dflt T) T {
+	if v == 0 {
+		return dflt
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package ampyobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineSettings(t *testing.T) {
+	defaults := pipelineSettings{
+		maxQueueSize:       2048,
+		maxExportBatchSize: 512,
+		spanBatchTimeout:   5 * time.Second,
+		logBatchTimeout:    time.Second,
+		batchExportTimeout: 30 * time.Second,
+		logExportBuffer:    1,
+		metricInterval:     10 * time.Second,
+		metricTimeout:      30 * time.Second,
+	}
+	with := func(change func(*pipelineSettings)) pipelineSettings {
+		s := defaults
+		change(&s)
+		return s
+	}
+	tests := []struct {
+		name string
+		cfg  PipelineConfig
+		want pipelineSettings
+	}{
+		{
+			name: "defaults",
+			want: defaults,
+		},
+		{
+			name: "oms",
+			cfg:  PipelineConfig{Profile: ProfileOMS},
+			want: with(func(s *pipelineSettings) {
+				s.maxExportBatchSize = 128
+				s.spanBatchTimeout, s.logBatchTimeout = 200*time.Millisecond, 200*time.Millisecond
+				s.batchExportTimeout = 5 * time.Second
+				s.metricInterval, s.metricTimeout = time.Second, 5*time.Second
+			}),
+		},
+		{
+			name: "profile names are case-insensitive",
+			cfg:  PipelineConfig{Profile: "JOB"},
+			want: with(func(s *pipelineSettings) {
+				s.spanBatchTimeout, s.logBatchTimeout = time.Second, time.Second
+				s.metricInterval = 5 * time.Second
+				s.flushOnShutdown = true
+			}),
+		},
+		{
+			name: "explicit fields win over the profile",
+			cfg:  PipelineConfig{Profile: ProfileBulk, MaxExportBatchSize: 1000, MetricInterval: time.Minute},
+			want: with(func(s *pipelineSettings) {
+				s.maxQueueSize, s.maxExportBatchSize = 16384, 1000
+				s.spanBatchTimeout, s.logBatchTimeout = 10*time.Second, 10*time.Second
+				s.logExportBuffer = 4
+				s.metricInterval = time.Minute
+			}),
+		},
+		{
+			name: "batch size capped by the queue",
+			cfg:  PipelineConfig{MaxQueueSize: 100},
+			want: with(func(s *pipelineSettings) { s.maxQueueSize, s.maxExportBatchSize = 100, 100 }),
+		},
+		{
+			name: "flush on shutdown without a profile",
+			cfg:  PipelineConfig{FlushOnShutdown: true},
+			want: with(func(s *pipelineSettings) { s.flushOnShutdown = true }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.settings(); got != tt.want {
+				t.Errorf("settings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
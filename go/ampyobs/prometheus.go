@@ -0,0 +1,50 @@
+package ampyobs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/otlptranslator"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// newPrometheusReader returns a metric reader that serves the instance's
+// metrics from its own registry, so several instances do not collide.
+//
+// Names are escaped to underscores without unit or _total suffixes: the
+// domain instruments already end in _total or _ms, which yields the names
+// used by deploy/prometheus/ampy.rules.yml (e.g. ampy_bus_delivery_latency_ms_bucket).
+func newPrometheusReader() (sdkmetric.Reader, http.Handler, error) {
+	reg := prometheus.NewRegistry()
+	reader, err := otelprom.New(
+		otelprom.WithRegisterer(reg),
+		otelprom.WithTranslationStrategy(otlptranslator.UnderscoreEscapingWithoutSuffixes),
+		otelprom.WithoutScopeInfo(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prometheus exporter: %w", err)
+	}
+	return reader, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}), nil
+}
+
+// MetricsHandler serves the instance's metrics in the Prometheus text
+// format. It responds 404 unless Config.EnablePrometheus is set.
+func (o *Obs) MetricsHandler() http.Handler {
+	if o.promHandler == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "prometheus endpoint disabled (set EnablePrometheus)", http.StatusNotFound)
+		})
+	}
+	return o.promHandler
+}
+
+// MetricsHandler serves the Default instance's metrics. The instance is
+// resolved per request, so the handler can be mounted before Init.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Default().MetricsHandler().ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,88 @@
+package ampyobs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape returns the status and body of a GET on h.
+func scrape(h http.Handler) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestMetricsHandler(t *testing.T) {
+	const topic = "ampy/dev/bars/v1"
+	tests := []struct {
+		name       string
+		metrics    bool // EnableMetrics
+		prometheus bool // EnablePrometheus
+		wantCode   int
+	}{
+		{name: "disabled", metrics: true, wantCode: http.StatusNotFound},
+		{name: "alongside OTLP push", metrics: true, prometheus: true, wantCode: http.StatusOK},
+		{name: "pull only", prometheus: true, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestObs(t, func(c *Config) { c.EnableMetrics, c.EnablePrometheus = tt.metrics, tt.prometheus })
+			ctx := context.Background()
+			o.BusProducedAdd(ctx, topic, 3)
+			o.BusDeliveryLatencyMs(ctx, topic, 12.5)
+
+			code, body := scrape(o.MetricsHandler())
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+			if code != http.StatusOK {
+				return
+			}
+			for _, want := range []string{
+				`ampy_bus_produced_total{`,
+				`topic="ampy/dev/bars/v1"} 3`,
+				`ampy_bus_delivery_latency_ms_bucket{`,
+				`ampy_bus_delivery_latency_ms_count{`,
+			} {
+				if !strings.Contains(body, want) {
+					t.Errorf("scrape has no %q", want)
+				}
+			}
+			for _, unwanted := range []string{"otel_scope_info", "ampy_bus_produced_total_total", "_milliseconds"} {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("scrape contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandlerPerInstance(t *testing.T) {
+	a := newTestObs(t, func(c *Config) { c.EnablePrometheus = true })
+	b := newTestObs(t, func(c *Config) { c.EnablePrometheus = true })
+	a.BusProducedAdd(context.Background(), "t", 1)
+	b.BusConsumedAdd(context.Background(), "t", 1)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		want    string
+		other   string
+	}{
+		{"first instance", a.MetricsHandler(), "ampy_bus_produced_total", "ampy_bus_consumed_total"},
+		{"second instance", b.MetricsHandler(), "ampy_bus_consumed_total", "ampy_bus_produced_total"},
+		{"package-level handler follows Default", MetricsHandler(), "ampy_bus_consumed_total", "ampy_bus_produced_total"},
+	}
+	prev := Default()
+	SetDefault(b)
+	defer SetDefault(prev)
+	for _, tt := range tests {
+		_, body := scrape(tt.handler)
+		if !strings.Contains(body, tt.want) || strings.Contains(body, tt.other) {
+			t.Errorf("%s: want %s and not %s in\n%s", tt.name, tt.want, tt.other, body)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package ampyobs
 import (
 	"context"
 
-	"go.opentelemetry.io/otel"
+	"github.com/AmpyFin/ampy-observability/internal/debugtarget"
 	"go.opentelemetry.io/otel/propagation"
 )
 
@@ -17,12 +17,27 @@ const (
 	HeaderAsOf       = "as_of"
 )
 
-// InjectTrace injects W3C trace context into key/value headers.
-func InjectTrace(ctx context.Context, headers map[string]string) {
-	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(headers))
+// InjectTrace injects W3C trace context into key/value headers. Baggage
+// marks flows matching a debug target (see AddDebugTarget) so downstream
+// services debug them too.
+func (o *Obs) InjectTrace(ctx context.Context, headers map[string]string) {
+	if !debugtarget.HasBaggage(ctx) && o.debug.match(ctx, nil) {
+		ctx = WithDebug(ctx)
+	}
+	o.prop.Inject(ctx, propagation.MapCarrier(headers))
 }
 
 // ExtractTrace extracts W3C trace context from headers and returns a child context.
+func (o *Obs) ExtractTrace(parent context.Context, headers map[string]string) context.Context {
+	return o.prop.Extract(parent, propagation.MapCarrier(headers))
+}
+
+// InjectTrace injects W3C trace context into key/value headers using the Default instance.
+func InjectTrace(ctx context.Context, headers map[string]string) {
+	Default().InjectTrace(ctx, headers)
+}
+
+// ExtractTrace extracts W3C trace context from headers using the Default instance.
 func ExtractTrace(parent context.Context, headers map[string]string) context.Context {
-	return otel.GetTextMapPropagator().Extract(parent, propagation.MapCarrier(headers))
+	return Default().ExtractTrace(parent, headers)
 }
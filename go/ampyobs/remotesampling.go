@@ -0,0 +1,370 @@
+package ampyobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// RemoteSamplingConfig polls sampling strategies so they can change without
+// a redeploy. URL is one of:
+//
+//   - a Jaeger remote sampling endpoint, e.g. "http://jaeger-agent:5778/sampling"
+//     (queried with ?service=<ServiceName>);
+//   - any http(s) URL or local file ("file:///etc/ampy/strategies.json" or a
+//     plain path) holding either one strategy in the same JSON form or a
+//     Jaeger strategies file with "service_strategies" and "default_strategy".
+//
+// A fetched strategy replaces the previous one atomically; an unchanged
+// strategy keeps its rate-limit state. Until the first successful fetch,
+// which runs in the background right after New, and whenever a fetch fails,
+// Config.Sampler applies.
+type RemoteSamplingConfig struct {
+	URL          string
+	PollInterval time.Duration // default: 60s
+	Timeout      time.Duration // bound on one fetch (default: 5s)
+}
+
+// remoteSampler delegates to the strategy last fetched from the source, or
+// to local.
+type remoteSampler struct {
+	cfg     RemoteSamplingConfig
+	service string
+	local   sdktrace.Sampler
+	clock   Clock // refills remote rate limits
+	client  *http.Client
+
+	active  atomic.Pointer[samplingStrategy]
+	applied *strategyResponse // source of the active remote strategy; used by poll only
+
+	pollsOK     atomic.Int64
+	pollsFailed atomic.Int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// samplingStrategy is one applied strategy.
+type samplingStrategy struct {
+	sampler sdktrace.Sampler
+	kind    string // "probabilistic" | "ratelimiting" | "per_operation" | "local"
+	remote  bool
+}
+
+func newRemoteSampler(cfg RemoteSamplingConfig, service string, local sdktrace.Sampler, clock Clock) *remoteSampler {
+	cfg.PollInterval = valueOr(cfg.PollInterval, 60*time.Second)
+	cfg.Timeout = valueOr(cfg.Timeout, 5*time.Second)
+	s := &remoteSampler{
+		cfg:     cfg,
+		service: service,
+		local:   local,
+		clock:   clock,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.active.Store(&samplingStrategy{sampler: local, kind: "local"})
+	go s.run()
+	return s
+}
+
+func (s *remoteSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.active.Load().sampler.ShouldSample(p)
+}
+
+func (s *remoteSampler) Description() string {
+	return "RemoteSampler{" + s.active.Load().sampler.Description() + "}"
+}
+
+// run fetches the strategy at once, then every PollInterval. Shutdown
+// cancels a fetch in flight.
+func (s *remoteSampler) run() {
+	defer close(s.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	s.poll(ctx)
+	tick := time.NewTicker(s.cfg.PollInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.poll(ctx)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *remoteSampler) shutdown() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+// poll fetches and applies the strategy, falling back to the local sampler
+// on any error. A strategy equal to the active one is kept as is, so its
+// rate limiters are not refilled by every poll.
+func (s *remoteSampler) poll(ctx context.Context) {
+	resp, err := s.fetch(ctx)
+	var st *samplingStrategy
+	if err == nil && (s.applied == nil || !reflect.DeepEqual(*resp, *s.applied)) {
+		st, err = resp.strategy(s.clock)
+	}
+	// The counters go last, so whoever sees a poll counted sees its result.
+	if err != nil {
+		if s.active.Load().remote {
+			s.active.Store(&samplingStrategy{sampler: s.local, kind: "local"})
+		}
+		s.applied = nil
+		s.pollsFailed.Add(1)
+		return
+	}
+	if st != nil {
+		s.active.Store(st)
+		s.applied = resp
+	}
+	s.pollsOK.Add(1)
+}
+
+// fetch reads the strategy for the service.
+func (s *remoteSampler) fetch(ctx context.Context) (*strategyResponse, error) {
+	b, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc strategyDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("remote sampling: decode: %w", err)
+	}
+	resp := doc.forService(s.service)
+	if resp == nil {
+		return nil, errors.New("remote sampling: no strategy for service " + s.service)
+	}
+	return resp, nil
+}
+
+func (s *remoteSampler) read(ctx context.Context) ([]byte, error) {
+	raw := s.cfg.URL
+	if path, ok := strings.CutPrefix(raw, "file://"); ok {
+		return os.ReadFile(path)
+	}
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		return os.ReadFile(raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("remote sampling: %w", err)
+	}
+	q := u.Query()
+	q.Set("service", s.service)
+	u.RawQuery = q.Encode()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("remote sampling: %w", err)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote sampling: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("remote sampling: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote sampling: GET %s: %s", u.Redacted(), res.Status)
+	}
+	return body, nil
+}
+
+// ----------- Jaeger strategy JSON -----------
+
+// strategyDoc is either a sampling endpoint response or a strategies file.
+type strategyDoc struct {
+	strategyResponse
+	ServiceStrategies []fileStrategy `json:"service_strategies"`
+	DefaultStrategy   *fileStrategy  `json:"default_strategy"`
+}
+
+func (d strategyDoc) forService(service string) *strategyResponse {
+	if d.ServiceStrategies == nil && d.DefaultStrategy == nil {
+		return &d.strategyResponse
+	}
+	for _, fs := range d.ServiceStrategies {
+		if fs.Service == service {
+			return fs.response()
+		}
+	}
+	if d.DefaultStrategy != nil {
+		return d.DefaultStrategy.response()
+	}
+	return nil
+}
+
+// strategyResponse is the JSON returned by Jaeger's /sampling endpoint.
+type strategyResponse struct {
+	StrategyType          json.RawMessage         `json:"strategyType"` // "PROBABILISTIC" | "RATE_LIMITING" | 0 | 1
+	ProbabilisticSampling *probabilisticStrategy  `json:"probabilisticSampling"`
+	RateLimitingSampling  *rateLimitingStrategy   `json:"rateLimitingSampling"`
+	OperationSampling     *perOperationStrategies `json:"operationSampling"`
+}
+
+type probabilisticStrategy struct {
+	SamplingRate float64 `json:"samplingRate"`
+}
+
+type rateLimitingStrategy struct {
+	MaxTracesPerSecond float64 `json:"maxTracesPerSecond"`
+}
+
+type perOperationStrategies struct {
+	DefaultSamplingProbability       float64             `json:"defaultSamplingProbability"`
+	DefaultLowerBoundTracesPerSecond float64             `json:"defaultLowerBoundTracesPerSecond"`
+	PerOperationStrategies           []operationStrategy `json:"perOperationStrategies"`
+}
+
+type operationStrategy struct {
+	Operation             string                `json:"operation"`
+	ProbabilisticSampling probabilisticStrategy `json:"probabilisticSampling"`
+}
+
+// fileStrategy is an entry of a Jaeger strategies file.
+type fileStrategy struct {
+	Service             string  `json:"service"`
+	Type                string  `json:"type"` // "probabilistic" | "ratelimiting"
+	Param               float64 `json:"param"`
+	OperationStrategies []struct {
+		Operation string  `json:"operation"`
+		Type      string  `json:"type"`
+		Param     float64 `json:"param"`
+	} `json:"operation_strategies"`
+}
+
+func (fs fileStrategy) response() *strategyResponse {
+	r := &strategyResponse{}
+	if strings.EqualFold(fs.Type, "ratelimiting") {
+		r.StrategyType = json.RawMessage(`"RATE_LIMITING"`)
+		r.RateLimitingSampling = &rateLimitingStrategy{MaxTracesPerSecond: fs.Param}
+	} else {
+		r.StrategyType = json.RawMessage(`"PROBABILISTIC"`)
+		r.ProbabilisticSampling = &probabilisticStrategy{SamplingRate: fs.Param}
+	}
+	if len(fs.OperationStrategies) > 0 && r.ProbabilisticSampling != nil {
+		ops := &perOperationStrategies{DefaultSamplingProbability: fs.Param}
+		for _, op := range fs.OperationStrategies {
+			if strings.EqualFold(op.Type, "ratelimiting") {
+				continue // Jaeger only supports probabilistic per-operation strategies
+			}
+			ops.PerOperationStrategies = append(ops.PerOperationStrategies,
+				operationStrategy{Operation: op.Operation, ProbabilisticSampling: probabilisticStrategy{SamplingRate: op.Param}})
+		}
+		r.OperationSampling = ops
+	}
+	return r
+}
+
+// strategy turns the response into a sampler. Per-operation strategies win
+// over the service-wide one, as in the Jaeger clients.
+// Rate limits refill by clock.
+func (r *strategyResponse) strategy(clock Clock) (*samplingStrategy, error) {
+	validRate := func(v float64) error {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("remote sampling: rate %v: %w", v, ErrInvalidSampleRatio)
+		}
+		return nil
+	}
+	if ops := r.OperationSampling; ops != nil {
+		if err := validRate(ops.DefaultSamplingProbability); err != nil {
+			return nil, err
+		}
+		lowerBound := ops.DefaultLowerBoundTracesPerSecond
+		s := &perOperationSampler{
+			byName: make(map[string]operationSampler, len(ops.PerOperationStrategies)),
+			dflt:   newOperationSampler(ops.DefaultSamplingProbability, lowerBound, clock),
+		}
+		for _, op := range ops.PerOperationStrategies {
+			if err := validRate(op.ProbabilisticSampling.SamplingRate); err != nil {
+				return nil, err
+			}
+			s.byName[op.Operation] = newOperationSampler(op.ProbabilisticSampling.SamplingRate, lowerBound, clock)
+		}
+		return &samplingStrategy{sampler: s, kind: "per_operation", remote: true}, nil
+	}
+
+	typ := strings.Trim(string(r.StrategyType), `"`)
+	switch {
+	case (typ == "RATE_LIMITING" || typ == "1") && r.RateLimitingSampling != nil:
+		if r.RateLimitingSampling.MaxTracesPerSecond < 0 {
+			return nil, fmt.Errorf("remote sampling: negative rate limit: %w", ErrInvalidSampleRule)
+		}
+		if r.RateLimitingSampling.MaxTracesPerSecond == 0 {
+			return &samplingStrategy{sampler: sdktrace.NeverSample(), kind: "ratelimiting", remote: true}, nil
+		}
+		return &samplingStrategy{sampler: newRateLimitSampler(r.RateLimitingSampling.MaxTracesPerSecond, 0, clock), kind: "ratelimiting", remote: true}, nil
+	case (typ == "PROBABILISTIC" || typ == "0" || typ == "") && r.ProbabilisticSampling != nil:
+		if err := validRate(r.ProbabilisticSampling.SamplingRate); err != nil {
+			return nil, err
+		}
+		return &samplingStrategy{sampler: sdktrace.TraceIDRatioBased(r.ProbabilisticSampling.SamplingRate), kind: "probabilistic", remote: true}, nil
+	}
+	return nil, fmt.Errorf("remote sampling: unsupported strategy %s", bytes.TrimSpace(r.StrategyType))
+}
+
+// perOperationSampler samples by span name. Operations without a strategy
+// share the default one, including its lower bound.
+type perOperationSampler struct {
+	byName map[string]operationSampler
+	dflt   operationSampler
+}
+
+// operationSampler samples at ratio and guarantees floor (when set) traces
+// per second on top, like Jaeger's lowerBoundTracesPerSecond.
+type operationSampler struct {
+	ratio sdktrace.Sampler
+	floor *rateLimitSampler
+}
+
+func newOperationSampler(ratio, lowerBound float64, clock Clock) operationSampler {
+	s := operationSampler{ratio: sdktrace.TraceIDRatioBased(ratio)}
+	if lowerBound > 0 {
+		s.floor = newRateLimitSampler(lowerBound, 0, clock)
+	}
+	return s
+}
+
+func (s *perOperationSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	op, ok := s.byName[p.Name]
+	if !ok {
+		op = s.dflt
+	}
+	res := op.ratio.ShouldSample(p)
+	if res.Decision == sdktrace.Drop && op.floor != nil {
+		return op.floor.ShouldSample(p)
+	}
+	return res
+}
+
+func (s *perOperationSampler) Description() string {
+	return fmt.Sprintf("PerOperationSampler{operations:%d,default:%s}", len(s.byName), s.dflt.ratio.Description())
+}
@@ -0,0 +1,266 @@
+package ampyobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRemoteStrategies(t *testing.T) {
+	type check struct {
+		span string
+		want sdktrace.SamplingDecision
+	}
+	tests := []struct {
+		name     string
+		doc      string
+		wantKind string
+		wantErr  error // nil with an empty wantKind: any error
+		checks   []check
+	}{
+		{
+			name:     "probabilistic",
+			doc:      `{"strategyType": "PROBABILISTIC", "probabilisticSampling": {"samplingRate": 1}}`,
+			wantKind: "probabilistic",
+			checks:   []check{{"oms.submit", sdktrace.RecordAndSample}},
+		},
+		{
+			name:     "probabilistic by number",
+			doc:      `{"strategyType": 0, "probabilisticSampling": {"samplingRate": 0}}`,
+			wantKind: "probabilistic",
+			checks:   []check{{"oms.submit", sdktrace.Drop}},
+		},
+		{
+			name:     "rate limiting",
+			doc:      `{"strategyType": "RATE_LIMITING", "rateLimitingSampling": {"maxTracesPerSecond": 2}}`,
+			wantKind: "ratelimiting",
+			checks:   []check{{"a", sdktrace.RecordAndSample}, {"b", sdktrace.RecordAndSample}, {"c", sdktrace.Drop}},
+		},
+		{
+			name: "per operation",
+			doc: `{"strategyType": "PROBABILISTIC", "probabilisticSampling": {"samplingRate": 1},
+			       "operationSampling": {"defaultSamplingProbability": 0,
+			         "perOperationStrategies": [{"operation": "oms.submit", "probabilisticSampling": {"samplingRate": 1}}]}}`,
+			wantKind: "per_operation",
+			checks:   []check{{"oms.submit", sdktrace.RecordAndSample}, {"md.tick", sdktrace.Drop}},
+		},
+		{
+			name: "per operation lower bound",
+			doc: `{"operationSampling": {"defaultSamplingProbability": 0, "defaultLowerBoundTracesPerSecond": 1,
+			         "perOperationStrategies": [{"operation": "oms.submit", "probabilisticSampling": {"samplingRate": 0}}]}}`,
+			wantKind: "per_operation",
+			checks:   []check{{"oms.submit", sdktrace.RecordAndSample}, {"oms.submit", sdktrace.Drop}, {"md.tick", sdktrace.RecordAndSample}},
+		},
+		{
+			name: "strategies file for the service",
+			doc: `{"service_strategies": [
+			         {"service": "other", "type": "probabilistic", "param": 1},
+			         {"service": "oms", "type": "probabilistic", "param": 0,
+			          "operation_strategies": [{"operation": "oms.submit", "type": "probabilistic", "param": 1}]}],
+			       "default_strategy": {"type": "probabilistic", "param": 1}}`,
+			wantKind: "per_operation",
+			checks:   []check{{"oms.submit", sdktrace.RecordAndSample}, {"oms.cancel", sdktrace.Drop}},
+		},
+		{
+			name:     "strategies file default",
+			doc:      `{"service_strategies": [{"service": "other", "type": "probabilistic", "param": 1}], "default_strategy": {"type": "ratelimiting", "param": 1}}`,
+			wantKind: "ratelimiting",
+			checks:   []check{{"a", sdktrace.RecordAndSample}, {"b", sdktrace.Drop}},
+		},
+		{
+			name: "strategies file without a match",
+			doc:  `{"service_strategies": [{"service": "other", "type": "probabilistic", "param": 1}]}`,
+		},
+		{
+			name:    "rate above 1",
+			doc:     `{"strategyType": "PROBABILISTIC", "probabilisticSampling": {"samplingRate": 2}}`,
+			wantErr: ErrInvalidSampleRatio,
+		},
+		{
+			name:     "rate limit 0",
+			doc:      `{"strategyType": "RATE_LIMITING", "rateLimitingSampling": {"maxTracesPerSecond": 0}}`,
+			wantKind: "ratelimiting",
+			checks:   []check{{"a", sdktrace.Drop}},
+		},
+		{
+			name:    "negative rate limit",
+			doc:     `{"strategyType": "RATE_LIMITING", "rateLimitingSampling": {"maxTracesPerSecond": -1}}`,
+			wantErr: ErrInvalidSampleRule,
+		},
+		{
+			name: "unsupported type",
+			doc:  `{"strategyType": "ADAPTIVE"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc strategyDoc
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			st, err := func() (*samplingStrategy, error) {
+				resp := doc.forService("oms")
+				if resp == nil {
+					return nil, errors.New("no strategy")
+				}
+				return resp.strategy(wallClock{})
+			}()
+			if tt.wantKind == "" {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("strategy() = %v, want error %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.kind != tt.wantKind || !st.remote {
+				t.Errorf("strategy kind = %s (remote %v), want %s", st.kind, st.remote, tt.wantKind)
+			}
+			for i, c := range tt.checks {
+				if got := st.sampler.ShouldSample(rootParams(c.span, trace.SpanKindInternal)).Decision; got != c.want {
+					t.Errorf("check %d: %s = %v, want %v", i, c.span, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// strategyServer serves a strategy document that the test can change.
+type strategyServer struct {
+	mu       sync.Mutex
+	body     string
+	status   int
+	services []string // ?service= of every request
+}
+
+func (s *strategyServer) set(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+func (s *strategyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = append(s.services, r.URL.Query().Get("service"))
+	w.WriteHeader(s.status)
+	_, _ = w.Write([]byte(s.body))
+}
+
+// waitPolls waits until s has finished n polls, successful or not.
+func waitPolls(t *testing.T, s *remoteSampler, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.pollsOK.Load()+s.pollsFailed.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d polls, want %d", s.pollsOK.Load()+s.pollsFailed.Load(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRemoteSamplerPolling(t *testing.T) {
+	const keepAll = `{"strategyType": "PROBABILISTIC", "probabilisticSampling": {"samplingRate": 1}}`
+	srv := &strategyServer{}
+	srv.set(http.StatusOK, keepAll)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s := newRemoteSampler(RemoteSamplingConfig{URL: ts.URL + "/sampling", PollInterval: time.Hour}, "oms", sdktrace.NeverSample(), wallClock{})
+	defer s.shutdown()
+	waitPolls(t, s, 1)
+
+	steps := []struct {
+		name     string
+		status   int
+		body     string
+		wantKind string
+		want     sdktrace.SamplingDecision
+	}{
+		{"first fetch in the background", 0, "", "probabilistic", sdktrace.RecordAndSample},
+		{"unreachable falls back to local", http.StatusServiceUnavailable, "", "local", sdktrace.Drop},
+		{"recovered", http.StatusOK, keepAll, "probabilistic", sdktrace.RecordAndSample},
+		{"undecodable falls back to local", http.StatusOK, "{", "local", sdktrace.Drop},
+	}
+	for i, st := range steps {
+		if i > 0 {
+			srv.set(st.status, st.body)
+			s.poll(context.Background())
+		}
+		if got := s.active.Load().kind; got != st.wantKind {
+			t.Errorf("%s: strategy = %s, want %s", st.name, got, st.wantKind)
+		}
+		if got := s.ShouldSample(rootParams("oms.submit", trace.SpanKindInternal)).Decision; got != st.want {
+			t.Errorf("%s: decision = %v, want %v", st.name, got, st.want)
+		}
+	}
+	if ok, failed := s.pollsOK.Load(), s.pollsFailed.Load(); ok != 2 || failed != 2 {
+		t.Errorf("polls ok/failed = %d/%d, want 2/2", ok, failed)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, svc := range srv.services {
+		if svc != "oms" {
+			t.Errorf("queried service %q, want oms", svc)
+		}
+	}
+}
+
+func TestRemoteSamplerFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "strategies.json")
+	if err := os.WriteFile(file, []byte(`{"default_strategy": {"type": "probabilistic", "param": 1}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{file, "file://" + file} {
+		s := newRemoteSampler(RemoteSamplingConfig{URL: url, PollInterval: time.Hour}, "oms", sdktrace.NeverSample(), wallClock{})
+		waitPolls(t, s, 1)
+		if got := s.active.Load().kind; got != "probabilistic" {
+			t.Errorf("%s: strategy = %s, want probabilistic", url, got)
+		}
+		s.shutdown()
+	}
+}
+
+func TestRemoteSamplerUnchangedStrategy(t *testing.T) {
+	const limit = `{"strategyType": "RATE_LIMITING", "rateLimitingSampling": {"maxTracesPerSecond": 1}}`
+	srv := &strategyServer{}
+	srv.set(http.StatusOK, limit)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// Starts with the local sampler, then applies the fetched strategy.
+	s := newRemoteSampler(RemoteSamplingConfig{URL: ts.URL, PollInterval: time.Hour}, "oms", sdktrace.AlwaysSample(), wallClock{})
+	defer s.shutdown()
+	waitPolls(t, s, 1)
+	applied := s.active.Load()
+	root := rootParams("oms.submit", trace.SpanKindInternal)
+	if got := s.ShouldSample(root).Decision; got != sdktrace.RecordAndSample {
+		t.Fatalf("first root = %v, want sampled", got)
+	}
+
+	// The same strategy again keeps the spent bucket.
+	s.poll(context.Background())
+	if s.active.Load() != applied {
+		t.Error("unchanged strategy replaced")
+	}
+	if got := s.ShouldSample(root).Decision; got != sdktrace.Drop {
+		t.Errorf("second root = %v, want dropped by the limit", got)
+	}
+
+	srv.set(http.StatusOK, `{"strategyType": "RATE_LIMITING", "rateLimitingSampling": {"maxTracesPerSecond": 2}}`)
+	s.poll(context.Background())
+	if s.active.Load() == applied {
+		t.Error("changed strategy not applied")
+	}
+}
@@ -0,0 +1,259 @@
+package ampyobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+)
+
+// ReplayConfig makes the telemetry of a backtest reproducible. Two runs with
+// the same RunID, the same Clock readings and the same inputs produce the
+// same trace trees, logs and metric streams, as long as spans are started in
+// the same order (e.g. from one goroutine).
+//
+// In replay mode metrics are only exported on ForceFlush and Shutdown, and
+// runtime metrics, ampy.obs.* self-metrics and exemplars are left out since
+// they depend on the host rather than on the inputs.
+//
+// Rate limits, the tail sampling window and the log sampling windows read
+// Clock as well. Background timers do not: remote sampling polls, the tail
+// sampling sweep and log sampling summaries run on wall time, so a strategy
+// fetched remotely makes a run irreproducible.
+type ReplayConfig struct {
+	RunID string // seeds trace and span ids; also set as the ampy.run_id resource attribute
+	Clock Clock  // timestamps and sampling time (default: wall clock)
+}
+
+func (r ReplayConfig) enabled() bool {
+	return r.RunID != "" || r.Clock != nil
+}
+
+// Clock supplies timestamps in replay mode.
+type Clock interface {
+	Now() time.Time
+}
+
+// clock returns Clock, or the wall clock when it is unset.
+func (r ReplayConfig) clock() Clock {
+	if r.Clock != nil {
+		return r.Clock
+	}
+	return wallClock{}
+}
+
+// wallClock reads time.Now.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+// SimClock is a Clock moved by the caller, e.g. to the as_of time of the bar
+// being processed. It never advances on its own. Safe for concurrent use.
+type SimClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewSimClock returns a SimClock reading start.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{now: start}
+}
+
+// Now returns the simulated time.
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *SimClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d.
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// ----------- IDs -----------
+
+// seededIDGenerator draws trace and span ids from a PRNG seeded with the run
+// id, so the n-th span of a run always gets the same ids.
+type seededIDGenerator struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newSeededIDGenerator(runID string) *seededIDGenerator {
+	sum := sha256.Sum256([]byte(runID))
+	seed1 := binary.BigEndian.Uint64(sum[:8])
+	seed2 := binary.BigEndian.Uint64(sum[8:16])
+	return &seededIDGenerator{rng: rand.New(rand.NewPCG(seed1, seed2))}
+}
+
+func (g *seededIDGenerator) NewIDs(context.Context) (trace.TraceID, trace.SpanID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var tid trace.TraceID
+	for !tid.IsValid() {
+		binary.BigEndian.PutUint64(tid[:8], g.rng.Uint64())
+		binary.BigEndian.PutUint64(tid[8:], g.rng.Uint64())
+	}
+	return tid, g.spanID()
+}
+
+func (g *seededIDGenerator) NewSpanID(context.Context, trace.TraceID) trace.SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.spanID()
+}
+
+func (g *seededIDGenerator) spanID() trace.SpanID {
+	var sid trace.SpanID
+	for !sid.IsValid() {
+		binary.BigEndian.PutUint64(sid[:], g.rng.Uint64())
+	}
+	return sid
+}
+
+// ----------- Span timestamps -----------
+
+// clockTracerProvider stamps span starts, ends and events with clock unless
+// the caller passes an explicit timestamp.
+type clockTracerProvider struct {
+	embedded.TracerProvider
+	tp    trace.TracerProvider
+	clock Clock
+}
+
+func (p clockTracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return clockTracer{tracer: p.tp.Tracer(name, opts...), clock: p.clock}
+}
+
+type clockTracer struct {
+	embedded.Tracer
+	tracer trace.Tracer
+	clock  Clock
+}
+
+func (t clockTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	// Later options win, so an explicit WithTimestamp from the caller is kept.
+	opts = append([]trace.SpanStartOption{trace.WithTimestamp(t.clock.Now())}, opts...)
+	ctx, s := t.tracer.Start(ctx, name, opts...)
+	cs := clockSpan{Span: s, clock: t.clock}
+	return trace.ContextWithSpan(ctx, cs), cs
+}
+
+type clockSpan struct {
+	trace.Span
+	clock Clock
+}
+
+func (s clockSpan) End(opts ...trace.SpanEndOption) {
+	s.Span.End(append([]trace.SpanEndOption{trace.WithTimestamp(s.clock.Now())}, opts...)...)
+}
+
+func (s clockSpan) AddEvent(name string, opts ...trace.EventOption) {
+	s.Span.AddEvent(name, append([]trace.EventOption{trace.WithTimestamp(s.clock.Now())}, opts...)...)
+}
+
+func (s clockSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.Span.RecordError(err, append([]trace.EventOption{trace.WithTimestamp(s.clock.Now())}, opts...)...)
+}
+
+func (s clockSpan) TracerProvider() trace.TracerProvider {
+	return clockTracerProvider{tp: s.Span.TracerProvider(), clock: s.clock}
+}
+
+// ----------- Log timestamps -----------
+
+// clockHandler stamps records with clock before passing them on.
+type clockHandler struct {
+	slog.Handler
+	clock Clock
+}
+
+func (h clockHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = h.clock.Now()
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h clockHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return clockHandler{Handler: h.Handler.WithAttrs(attrs), clock: h.clock}
+}
+
+func (h clockHandler) WithGroup(name string) slog.Handler {
+	return clockHandler{Handler: h.Handler.WithGroup(name), clock: h.clock}
+}
+
+// ----------- Metric timestamps -----------
+
+// clockMetricExporter rewrites data point timestamps: start times become the
+// clock reading when the instance was created, end times the reading at export.
+type clockMetricExporter struct {
+	sdkmetric.Exporter
+	clock Clock
+	start time.Time
+}
+
+func (e clockMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	now := e.clock.Now()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				stampPoints(d.DataPoints, e.start, now)
+			case metricdata.Sum[float64]:
+				stampPoints(d.DataPoints, e.start, now)
+			case metricdata.Gauge[int64]:
+				stampPoints(d.DataPoints, e.start, now)
+			case metricdata.Gauge[float64]:
+				stampPoints(d.DataPoints, e.start, now)
+			case metricdata.Histogram[int64]:
+				for i := range d.DataPoints {
+					d.DataPoints[i].StartTime, d.DataPoints[i].Time = e.start, now
+				}
+			case metricdata.Histogram[float64]:
+				for i := range d.DataPoints {
+					d.DataPoints[i].StartTime, d.DataPoints[i].Time = e.start, now
+				}
+			case metricdata.ExponentialHistogram[int64]:
+				for i := range d.DataPoints {
+					d.DataPoints[i].StartTime, d.DataPoints[i].Time = e.start, now
+				}
+			case metricdata.ExponentialHistogram[float64]:
+				for i := range d.DataPoints {
+					d.DataPoints[i].StartTime, d.DataPoints[i].Time = e.start, now
+				}
+			case metricdata.Summary:
+				for i := range d.DataPoints {
+					d.DataPoints[i].StartTime, d.DataPoints[i].Time = e.start, now
+				}
+			}
+		}
+	}
+	return e.Exporter.Export(ctx, rm)
+}
+
+func stampPoints[N int64 | float64](dps []metricdata.DataPoint[N], start, now time.Time) {
+	for i := range dps {
+		dps[i].StartTime, dps[i].Time = start, now
+	}
+}
+
+// replayMetricInterval effectively disables periodic metric export so a
+// replay's metric stream depends only on its ForceFlush/Shutdown calls.
+const replayMetricInterval = 100 * 365 * 24 * time.Hour
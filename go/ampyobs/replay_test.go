@@ -0,0 +1,186 @@
+package ampyobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// replayRun records the spans of a small trace tree started through both
+// StartSpan and TracerProvider.
+func replayRun(t *testing.T, runID string, start time.Time) tracetest.SpanStubs {
+	t.Helper()
+	spans := tracetest.NewInMemoryExporter()
+	clock := NewSimClock(start)
+	o := newTestObs(t, func(c *Config) {
+		c.Replay = ReplayConfig{RunID: runID, Clock: clock}
+		c.SpanProcessors = []sdktrace.SpanProcessor{sdktrace.NewSimpleSpanProcessor(spans)}
+	})
+
+	ctx, root := o.StartSpan(context.Background(), "strategy.on_bar", trace.SpanKindInternal)
+	clock.Advance(time.Second)
+	_, child := o.TracerProvider().Tracer("strategy").Start(ctx, "risk.check")
+	clock.Advance(time.Second)
+	child.End()
+	root.End()
+	return spans.GetSpans()
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	start := time.Date(2024, 6, 3, 13, 30, 0, 0, time.UTC)
+	a := replayRun(t, "bt-1", start)
+	b := replayRun(t, "bt-1", start)
+	other := replayRun(t, "bt-2", start)
+
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("recorded %d and %d spans, want 2", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].SpanContext.TraceID() != b[i].SpanContext.TraceID() || a[i].SpanContext.SpanID() != b[i].SpanContext.SpanID() {
+			t.Errorf("span %q ids differ between runs of one run id", a[i].Name)
+		}
+		if a[i].SpanContext.TraceID() == other[i].SpanContext.TraceID() {
+			t.Errorf("span %q has the same trace id for another run id", a[i].Name)
+		}
+		if !a[i].StartTime.Equal(b[i].StartTime) || !a[i].EndTime.Equal(b[i].EndTime) {
+			t.Errorf("span %q timestamps differ between runs", a[i].Name)
+		}
+	}
+
+	// The child, started through TracerProvider, uses the clock too.
+	child, root := a[0], a[1]
+	if child.Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("child parent = %s, want %s", child.Parent.SpanID(), root.SpanContext.SpanID())
+	}
+	tests := []struct {
+		name      string
+		got, want time.Time
+	}{
+		{"root start", root.StartTime, start},
+		{"child start", child.StartTime, start.Add(time.Second)},
+		{"child end", child.EndTime, start.Add(2 * time.Second)},
+		{"root end", root.EndTime, start.Add(2 * time.Second)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestTracerProvider(t *testing.T) {
+	if tp := newTestObs(t, func(c *Config) { c.EnableTracing = false }).TracerProvider(); tp != nil {
+		t.Errorf("TracerProvider() = %v with tracing disabled, want nil", tp)
+	}
+	if _, ok := newTestObs(t).TracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Error("TracerProvider() without a clock is not the SDK provider")
+	}
+
+	// An explicit timestamp from the caller wins over the clock.
+	spans := tracetest.NewInMemoryExporter()
+	clock := NewSimClock(time.Unix(1000, 0))
+	o := newTestObs(t, func(c *Config) {
+		c.Replay.Clock = clock
+		c.SpanProcessors = []sdktrace.SpanProcessor{sdktrace.NewSimpleSpanProcessor(spans)}
+	})
+	explicit := time.Unix(42, 0)
+	_, span := o.TracerProvider().Tracer("t").Start(context.Background(), "s", trace.WithTimestamp(explicit))
+	span.End()
+	if got := spans.GetSpans(); len(got) != 1 || !got[0].StartTime.Equal(explicit) || !got[0].EndTime.Equal(clock.Now()) {
+		t.Errorf("spans = %+v", got)
+	}
+}
+
+func TestReplayLogTimestamps(t *testing.T) {
+	var got []time.Time
+	capture := &captureProcessor{}
+	clock := NewSimClock(time.Date(2024, 6, 3, 13, 30, 0, 0, time.UTC))
+	o := newTestObs(t, func(c *Config) {
+		c.Replay.Clock = clock
+		c.LogProcessors = []sdklog.Processor{capture}
+	})
+	o.L().Info("first")
+	clock.Advance(time.Minute)
+	o.L().Info("second")
+	for _, r := range capture.records {
+		got = append(got, r.Timestamp())
+	}
+	if len(got) != 2 || !got[0].Equal(clock.Now().Add(-time.Minute)) || !got[1].Equal(clock.Now()) {
+		t.Errorf("log timestamps = %v", got)
+	}
+}
+
+func TestReplaySamplingClock(t *testing.T) {
+	start := time.Date(2024, 6, 3, 13, 30, 0, 0, time.UTC)
+
+	t.Run("rate limit", func(t *testing.T) {
+		clock := NewSimClock(start)
+		spans := tracetest.NewInMemoryExporter()
+		o := newTestObs(t, func(c *Config) {
+			c.Sampler, c.RateLimit, c.RateLimitBurst = "ratelimit", 1, 1
+			c.Replay.Clock = clock
+			c.SpanProcessors = []sdktrace.SpanProcessor{sdktrace.NewSimpleSpanProcessor(spans)}
+		})
+		for _, advance := range []time.Duration{0, 0, time.Second, 0} {
+			clock.Advance(advance)
+			_, span := o.StartSpan(context.Background(), "strategy.on_bar", trace.SpanKindInternal)
+			span.End()
+		}
+		// One token at the start and one more a simulated second later.
+		if n := len(spans.GetSpans()); n != 2 {
+			t.Errorf("kept %d of 4 roots, want 2", n)
+		}
+	})
+
+	t.Run("tail window", func(t *testing.T) {
+		clock := NewSimClock(start)
+		o := newTestObs(t, func(c *Config) {
+			c.Sampler = ""
+			c.TailSampling = TailSamplingConfig{Enabled: true, Ratio: 1, Window: time.Minute}
+			c.Replay.Clock = clock
+		})
+		ctx, root := o.StartSpan(context.Background(), "strategy.on_bar", trace.SpanKindInternal)
+		defer root.End()
+		_, child := o.TracerProvider().Tracer("strategy").Start(ctx, "risk.check")
+		child.End()
+
+		if err := o.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := o.stats.spans.tail.buffered.Load(); n != 1 {
+			t.Fatalf("%d traces buffered before the window, want 1", n)
+		}
+		clock.Advance(2 * time.Minute)
+		if err := o.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if n := o.stats.spans.tail.buffered.Load(); n != 0 {
+			t.Errorf("%d traces buffered after the simulated window, want 0", n)
+		}
+	})
+
+	t.Run("log sampling window", func(t *testing.T) {
+		clock := NewSimClock(start)
+		logs := &recordCounter{}
+		o := newTestObs(t, func(c *Config) {
+			c.LogSampling = LogSamplingConfig{Enabled: true, Interval: time.Second, First: 1, SummaryInterval: time.Hour}
+			c.Replay.Clock = clock
+			c.LogProcessors = []sdklog.Processor{logs}
+		})
+		o.L().Warn("slow bar")
+		o.L().Warn("slow bar")
+		clock.Advance(2 * time.Second)
+		o.L().Warn("slow bar")
+
+		logs.mu.Lock()
+		defer logs.mu.Unlock()
+		if len(logs.msgs) != 2 {
+			t.Errorf("logged %q, want the first record of each simulated window", logs.msgs)
+		}
+	})
+}
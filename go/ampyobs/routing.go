@@ -0,0 +1,386 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Routing policies accepted by RoutingConfig.Policy.
+const (
+	RoutingFailover = "failover" // one endpoint at a time, primary first
+	RoutingFanout   = "fanout"   // every endpoint receives every batch
+)
+
+// RoutingConfig sends signals to several collectors, e.g. a primary and a DR
+// collector. Every endpoint uses the signal's protocol, TLS and headers.
+// When a signal has endpoints here, CollectorEndpoint and the per-signal
+// endpoint are ignored for it.
+//
+// Each send to an endpoint is tried once and bounded by ExportTimeout; the
+// router, not the exporter's backoff, decides where a failed batch goes next.
+//
+// With failover, batches go to the active endpoint, starting with the first.
+// After FailoverAfter consecutive failed exports the next endpoint becomes
+// active and the failing batch is retried there. While failed over, one batch
+// per ProbeInterval is tried on the primary first; the primary is active
+// again as soon as it accepts one.
+//
+// With fanout, batches are exported to all endpoints concurrently. A batch
+// counts as exported (and is not kept in the WAL) when at least one endpoint
+// accepted it.
+type RoutingConfig struct {
+	Policy          string        // "failover" | "fanout" (default: failover)
+	Endpoints       []string      // all signals, primary first; "host:port" or URL
+	TraceEndpoints  []string      // overrides Endpoints for traces; may carry a URL path like TraceEndpoint
+	MetricEndpoints []string      // overrides Endpoints for metrics
+	LogEndpoints    []string      // overrides Endpoints for logs
+	FailoverAfter   int           // consecutive failed exports before moving on (default: 3)
+	ProbeInterval   time.Duration // how often the primary is retried while failed over (default: 30s)
+}
+
+func (r RoutingConfig) clone() RoutingConfig {
+	r.Endpoints = slices.Clone(r.Endpoints)
+	r.TraceEndpoints = slices.Clone(r.TraceEndpoints)
+	r.MetricEndpoints = slices.Clone(r.MetricEndpoints)
+	r.LogEndpoints = slices.Clone(r.LogEndpoints)
+	return r
+}
+
+// route is one destination of a routed signal.
+type route struct {
+	endpoint string
+	cfg      Config // Routing cleared, endpoint set
+}
+
+// routes returns the destinations of a signal, or nil if it is not routed.
+// Entries of the signal's own list become its per-signal endpoint (so URL
+// paths are kept); entries of the shared list become CollectorEndpoint.
+func (c Config) routes(perSignal []string, signalEndpoint func(*Config) *string) []route {
+	eps, shared := perSignal, false
+	if len(eps) == 0 {
+		eps, shared = c.Routing.Endpoints, true
+	}
+	if len(eps) == 0 {
+		return nil
+	}
+	out := make([]route, len(eps))
+	for i, ep := range eps {
+		rc := c
+		rc.Routing = RoutingConfig{}
+		rc.Retry.Disabled = true // retrying a dead endpoint would hold the batch back from the next one
+		if shared {
+			rc.CollectorEndpoint, *signalEndpoint(&rc) = ep, ""
+		} else {
+			*signalEndpoint(&rc) = ep
+		}
+		out[i] = route{endpoint: ep, cfg: rc}
+	}
+	return out
+}
+
+// buildRoutes builds one exporter per route, shutting down the ones already
+// built if a later one fails.
+func buildRoutes[E interface{ Shutdown(context.Context) error }](routes []route, build func(Config) (E, error)) ([]E, error) {
+	exps := make([]E, 0, len(routes))
+	for _, rt := range routes {
+		exp, err := build(rt.cfg)
+		if err != nil {
+			for _, e := range exps {
+				_ = e.Shutdown(context.Background())
+			}
+			return nil, fmt.Errorf("route %s: %w", rt.endpoint, err)
+		}
+		exps = append(exps, exp)
+	}
+	return exps, nil
+}
+
+// ----------- Router -----------
+
+// destination tracks one endpoint of a router.
+type destination struct {
+	endpoint      string
+	batchesOK     atomic.Int64
+	batchesFailed atomic.Int64
+	consecutive   atomic.Int64 // failed exports since the last success
+	lastFailure   atomic.Pointer[exportFailure]
+}
+
+// router applies a RoutingConfig policy to the exporters of one signal.
+type router struct {
+	fanout        bool
+	dests         []*destination
+	failoverAfter int64
+	probeInterval time.Duration
+	timeout       time.Duration // per send
+
+	active    atomic.Int64 // failover: index of the endpoint receiving batches
+	failovers atomic.Int64
+
+	mu        sync.Mutex // guards nextProbe; never held while sending
+	nextProbe time.Time
+}
+
+func newRouter(cfg Config, routes []route) *router {
+	r := &router{
+		fanout:        strings.EqualFold(cfg.Routing.Policy, RoutingFanout),
+		failoverAfter: int64(valueOr(cfg.Routing.FailoverAfter, 3)),
+		probeInterval: valueOr(cfg.Routing.ProbeInterval, 30*time.Second),
+		timeout:       valueOr(cfg.ExportTimeout, defaultExportTimeout),
+	}
+	for _, rt := range routes {
+		r.dests = append(r.dests, &destination{endpoint: rt.endpoint})
+	}
+	return r
+}
+
+// export sends one batch according to the policy; send exports it to the
+// i-th endpoint.
+func (r *router) export(ctx context.Context, send func(ctx context.Context, i int) error) error {
+	if r.fanout {
+		return r.exportAll(ctx, send)
+	}
+	return r.exportFailover(ctx, send)
+}
+
+func (r *router) exportAll(ctx context.Context, send func(ctx context.Context, i int) error) error {
+	errs := make([]error, len(r.dests))
+	var wg sync.WaitGroup
+	for i := range r.dests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.try(ctx, i, 1, send)
+		}()
+	}
+	wg.Wait()
+	if slices.Contains(errs, nil) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+func (r *router) exportFailover(ctx context.Context, send func(ctx context.Context, i int) error) error {
+	left := len(r.dests) // sends this batch may still need
+	if r.probeDue() {
+		if r.try(ctx, 0, left+1, send) == nil {
+			r.active.Store(0)
+			return nil
+		}
+	}
+	var errs []error
+	for ; left > 0; left-- {
+		i := r.active.Load()
+		err := r.try(ctx, int(i), left, send)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if !r.failover(i) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// probeDue reports whether this batch should try the primary first. Only one
+// batch per ProbeInterval gets to, however many export concurrently.
+func (r *router) probeDue() bool {
+	if r.active.Load() == 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if now.Before(r.nextProbe) {
+		return false
+	}
+	r.nextProbe = now.Add(r.probeInterval)
+	return true
+}
+
+// failover makes the endpoint after i active once i has failed FailoverAfter
+// times in a row. It reports whether the batch should be tried again on the
+// now active endpoint.
+func (r *router) failover(i int64) bool {
+	if len(r.dests) < 2 || r.dests[i].consecutive.Load() < r.failoverAfter {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active.CompareAndSwap(i, (i+1)%int64(len(r.dests))) {
+		r.failovers.Add(1)
+		r.nextProbe = time.Now().Add(r.probeInterval)
+	}
+	return true // moved on here or by a concurrent export
+}
+
+// try sends the batch to the i-th endpoint and records the outcome. The send
+// gets at most the export timeout and, when the batch has a deadline, an
+// equal share of what is left of it across the left sends still possible, so
+// a dead endpoint cannot use up the time of the ones after it.
+func (r *router) try(ctx context.Context, i, left int, send func(ctx context.Context, i int) error) error {
+	timeout := r.timeout
+	if dl, ok := ctx.Deadline(); ok && left > 1 {
+		timeout = min(timeout, time.Until(dl)/time.Duration(left))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	d := r.dests[i]
+	if err := send(ctx, i); err != nil {
+		d.batchesFailed.Add(1)
+		d.consecutive.Add(1)
+		d.lastFailure.Store(&exportFailure{err: err, at: time.Now()})
+		return fmt.Errorf("%s: %w", d.endpoint, err)
+	}
+	d.batchesOK.Add(1)
+	d.consecutive.Store(0)
+	return nil
+}
+
+// isActive reports whether the i-th endpoint currently receives batches.
+func (r *router) isActive(i int) bool {
+	return r.fanout || r.active.Load() == int64(i)
+}
+
+func (r *router) health() []DestinationHealth {
+	out := make([]DestinationHealth, len(r.dests))
+	for i, d := range r.dests {
+		out[i] = DestinationHealth{
+			Endpoint:            d.endpoint,
+			Active:              r.isActive(i),
+			BatchesOK:           d.batchesOK.Load(),
+			BatchesFailed:       d.batchesFailed.Load(),
+			ConsecutiveFailures: d.consecutive.Load(),
+		}
+		if f := d.lastFailure.Load(); f != nil {
+			out[i].LastError, out[i].LastErrorTime = f.err, f.at
+		}
+	}
+	return out
+}
+
+// routerOf returns the router behind a routed exporter, or nil.
+func routerOf(exp any) *router {
+	if r, ok := exp.(interface{ routes() *router }); ok {
+		return r.routes()
+	}
+	return nil
+}
+
+func shutdownAll[E interface{ Shutdown(context.Context) error }](ctx context.Context, exps []E) error {
+	var errs []error
+	for _, e := range exps {
+		errs = append(errs, e.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ----------- Routed exporters -----------
+
+type routedSpanExporter struct {
+	r    *router
+	exps []sdktrace.SpanExporter
+}
+
+func newRoutedSpanExporter(cfg Config, routes []route) (sdktrace.SpanExporter, error) {
+	exps, err := buildRoutes(routes, newSpanExporter)
+	if err != nil {
+		return nil, err
+	}
+	return routedSpanExporter{r: newRouter(cfg, routes), exps: exps}, nil
+}
+
+func (e routedSpanExporter) routes() *router { return e.r }
+
+func (e routedSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return e.r.export(ctx, func(ctx context.Context, i int) error {
+		return e.exps[i].ExportSpans(ctx, spans)
+	})
+}
+
+func (e routedSpanExporter) Shutdown(ctx context.Context) error { return shutdownAll(ctx, e.exps) }
+
+type routedMetricExporter struct {
+	r    *router
+	exps []sdkmetric.Exporter
+}
+
+func newRoutedMetricExporter(cfg Config, routes []route) (sdkmetric.Exporter, error) {
+	exps, err := buildRoutes(routes, newMetricExporter)
+	if err != nil {
+		return nil, err
+	}
+	return routedMetricExporter{r: newRouter(cfg, routes), exps: exps}, nil
+}
+
+func (e routedMetricExporter) routes() *router { return e.r }
+
+// Temporality and Aggregation come from the primary; every endpoint is built
+// from the same Config, so they agree.
+func (e routedMetricExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return e.exps[0].Temporality(k)
+}
+
+func (e routedMetricExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return e.exps[0].Aggregation(k)
+}
+
+func (e routedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.r.export(ctx, func(ctx context.Context, i int) error {
+		return e.exps[i].Export(ctx, rm)
+	})
+}
+
+func (e routedMetricExporter) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, exp := range e.exps {
+		errs = append(errs, exp.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (e routedMetricExporter) Shutdown(ctx context.Context) error { return shutdownAll(ctx, e.exps) }
+
+type routedLogExporter struct {
+	r    *router
+	exps []sdklog.Exporter
+}
+
+func newRoutedLogExporter(cfg Config, routes []route) (sdklog.Exporter, error) {
+	exps, err := buildRoutes(routes, newLogExporter)
+	if err != nil {
+		return nil, err
+	}
+	return routedLogExporter{r: newRouter(cfg, routes), exps: exps}, nil
+}
+
+func (e routedLogExporter) routes() *router { return e.r }
+
+func (e routedLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	return e.r.export(ctx, func(ctx context.Context, i int) error {
+		return e.exps[i].Export(ctx, records)
+	})
+}
+
+func (e routedLogExporter) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, exp := range e.exps {
+		errs = append(errs, exp.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (e routedLogExporter) Shutdown(ctx context.Context) error { return shutdownAll(ctx, e.exps) }
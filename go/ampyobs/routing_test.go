@@ -0,0 +1,160 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRouter returns a failover router over a primary and a DR endpoint.
+func newTestRouter(t *testing.T, configure func(*Config)) *router {
+	t.Helper()
+	cfg := Config{Routing: RoutingConfig{Endpoints: []string{"primary:4317", "dr:4317"}}}
+	if configure != nil {
+		configure(&cfg)
+	}
+	rs := cfg.routes(nil, func(c *Config) *string { return &c.TraceEndpoint })
+	for _, rt := range rs {
+		if !rt.cfg.Retry.Disabled {
+			t.Fatalf("route %s keeps the exporter's retries", rt.endpoint)
+		}
+	}
+	return newRouter(cfg, rs)
+}
+
+func TestRouterFailover(t *testing.T) {
+	type batch struct {
+		primaryUp  bool
+		wantTo     int // endpoint that accepts the batch, -1 if it fails
+		wantActive int // active endpoint afterwards
+	}
+	tests := []struct {
+		name          string
+		failoverAfter int
+		probeInterval time.Duration
+		batches       []batch
+		wantFailovers int64
+	}{
+		{
+			name:    "healthy primary",
+			batches: []batch{{true, 0, 0}, {true, 0, 0}},
+		},
+		{
+			name:          "failures below the threshold stay on the primary",
+			failoverAfter: 3,
+			batches:       []batch{{false, -1, 0}, {false, -1, 0}, {true, 0, 0}, {false, -1, 0}, {false, -1, 0}},
+		},
+		{
+			name:          "threshold moves the failing batch to the DR endpoint",
+			failoverAfter: 2,
+			batches:       []batch{{false, -1, 0}, {false, 1, 1}, {true, 1, 1}},
+			wantFailovers: 1,
+		},
+		{
+			name:          "probe recovers the primary",
+			failoverAfter: 1,
+			probeInterval: time.Nanosecond,
+			batches:       []batch{{false, 1, 1}, {false, 1, 1}, {true, 0, 0}, {true, 0, 0}},
+			wantFailovers: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, func(c *Config) {
+				c.Routing.FailoverAfter = tt.failoverAfter
+				c.Routing.ProbeInterval = valueOr(tt.probeInterval, time.Hour)
+			})
+			for n, b := range tt.batches {
+				to := -1
+				err := r.export(context.Background(), func(_ context.Context, i int) error {
+					if i == 0 && !b.primaryUp {
+						return errCollectorDown
+					}
+					to = i
+					return nil
+				})
+				if (err != nil) != (b.wantTo < 0) || to != b.wantTo {
+					t.Errorf("batch %d went to %d (err %v), want %d", n, to, err, b.wantTo)
+				}
+				if got := int(r.active.Load()); got != b.wantActive {
+					t.Errorf("after batch %d active = %d, want %d", n, got, b.wantActive)
+				}
+			}
+			if got := r.failovers.Load(); got != tt.wantFailovers {
+				t.Errorf("failovers = %d, want %d", got, tt.wantFailovers)
+			}
+		})
+	}
+}
+
+func TestRouterDeadPrimary(t *testing.T) {
+	// A primary that never answers must not use up the batch's deadline.
+	r := newTestRouter(t, func(c *Config) { c.Routing.FailoverAfter = 1 })
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := r.export(ctx, func(ctx context.Context, i int) error {
+		if i == 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("export = %v, want the DR endpoint to accept the batch", err)
+	}
+	if took := time.Since(start); took > 350*time.Millisecond {
+		t.Errorf("export took %s, the primary held the whole deadline", took)
+	}
+	h := r.health()
+	if !errors.Is(h[0].LastError, context.DeadlineExceeded) || h[1].BatchesOK != 1 || !h[1].Active {
+		t.Errorf("health = %+v", h)
+	}
+}
+
+func TestRouterSendTimeout(t *testing.T) {
+	r := newTestRouter(t, func(c *Config) { c.ExportTimeout = 50 * time.Millisecond })
+	err := r.export(context.Background(), func(ctx context.Context, _ int) error {
+		dl, ok := ctx.Deadline()
+		if !ok || time.Until(dl) > 50*time.Millisecond {
+			t.Errorf("send deadline = %v (set %v), want within ExportTimeout", dl, ok)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouterFanout(t *testing.T) {
+	tests := []struct {
+		name    string
+		up      []bool
+		wantErr bool
+	}{
+		{"all up", []bool{true, true}, false},
+		{"one up", []bool{false, true}, false},
+		{"all down", []bool{false, false}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, func(c *Config) { c.Routing.Policy = RoutingFanout })
+			err := r.export(context.Background(), func(_ context.Context, i int) error {
+				if !tt.up[i] {
+					return errCollectorDown
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("export = %v, want error %v", err, tt.wantErr)
+			}
+			for i, h := range r.health() {
+				if !h.Active || h.BatchesOK+h.BatchesFailed != 1 {
+					t.Errorf("destination %d = %+v, want one batch", i, h)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package ampyobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/AmpyFin/ampy-observability/internal/ratelimit"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// samplerName is cfg.Sampler in lower case. Left empty, it is "rules" when
+// rules are configured, else "ratelimit" when RateLimit is set, since
+// OTEL_TRACES_SAMPLER has neither value.
+func (c Config) samplerName() string {
+	if c.Sampler == "" {
+		switch {
+		case len(c.SamplingRules.Rules) > 0 || c.SamplingRules.Fallback != nil || c.SamplingRulesFile != "":
+			return "rules"
+		case c.RateLimit > 0:
+			return "ratelimit"
+		}
+	}
+	return strings.ToLower(c.Sampler)
+}
+
+// newSampler builds the head sampler selected by cfg.Sampler, behind the
+// remote sampler when cfg.RemoteSampling is set. Every sampler is
+// parent-based: child spans follow the decision of their parent.
+func newSampler(cfg Config, stats *exportStats) (sdktrace.Sampler, error) {
+	root, err := newRootSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RemoteSampling.URL != "" {
+		stats.remote = newRemoteSampler(cfg.RemoteSampling, cfg.ServiceName, root, cfg.Replay.clock())
+		root = stats.remote
+	}
+	return sdktrace.ParentBased(root), nil
+}
+
+// newRootSampler builds the sampler applied to root spans. With
+// LenientValidation a sampler that cannot be built, such as rules from an
+// unreadable file, is logged and replaced by the default.
+func newRootSampler(cfg Config) (sdktrace.Sampler, error) {
+	s, err := configuredRootSampler(cfg)
+	if err != nil && cfg.LenientValidation {
+		newSlogLogger().Warn("ampyobs config warning: using the default sampler", slog.String("error", err.Error()))
+		return sdktrace.TraceIDRatioBased(defaultSampleRatio), nil
+	}
+	return s, err
+}
+
+func configuredRootSampler(cfg Config) (sdktrace.Sampler, error) {
+	switch cfg.samplerName() {
+	case "ratio":
+		if cfg.SampleRatio >= 0 && cfg.SampleRatio <= 1 {
+			return sdktrace.TraceIDRatioBased(cfg.SampleRatio), nil
+		}
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "ratelimit":
+		return newRateLimitSampler(cfg.RateLimit, cfg.RateLimitBurst, cfg.Replay.clock()), nil
+	case "rules":
+		rules, err := cfg.samplingRules()
+		if err != nil {
+			return nil, err
+		}
+		return newRulesSampler(rules, cfg.Replay.clock())
+	}
+	// "parent", "" and anything Validate let through
+	return sdktrace.TraceIDRatioBased(defaultSampleRatio), nil
+}
+
+const defaultSampleRatio = 0.25
+
+// ----------- Rules -----------
+
+// SamplingRule matches root spans and samples them at its own ratio or rate.
+// Empty matchers match anything. Globs follow path.Match, so "*" does not
+// cross a "/" (e.g. "ampy/*/bars/v1" matches one topic segment).
+type SamplingRule struct {
+	Name       string            `json:"name,omitempty"`       // span name glob, e.g. "oms.*"
+	Kind       string            `json:"kind,omitempty"`       // "internal" | "server" | "client" | "producer" | "consumer"
+	Attributes map[string]string `json:"attributes,omitempty"` // start attribute globs, e.g. {"topic": "ampy/*/bars/v1"}
+	Ratio      *float64          `json:"ratio,omitempty"`      // share of matching traces kept (default: 1)
+	RateLimit  float64           `json:"rate_limit,omitempty"` // max matching traces per second; replaces Ratio when > 0
+	Burst      int               `json:"burst,omitempty"`      // with RateLimit (default: one second's worth)
+}
+
+// SamplingRules configures the "rules" sampler. The first matching rule
+// decides; spans matching none use Fallback (default: ratio 0.25, like the
+// default sampler). In JSON:
+//
+//	{"rules": [
+//	   {"name": "oms.*", "ratio": 1},
+//	   {"attributes": {"broker": "alpaca"}, "ratio": 1},
+//	   {"name": "bus.publish", "attributes": {"topic": "ampy/*/bars/v1"}, "ratio": 0.01}
+//	 ],
+//	 "fallback": {"rate_limit": 100}}
+type SamplingRules struct {
+	Rules    []SamplingRule `json:"rules"`
+	Fallback *SamplingRule  `json:"fallback,omitempty"`
+}
+
+func (r SamplingRules) clone() SamplingRules {
+	r.Rules = slices.Clone(r.Rules)
+	for i := range r.Rules {
+		r.Rules[i] = r.Rules[i].clone()
+	}
+	if r.Fallback != nil {
+		fb := r.Fallback.clone()
+		r.Fallback = &fb
+	}
+	return r
+}
+
+func (r SamplingRule) clone() SamplingRule {
+	r.Attributes = maps.Clone(r.Attributes)
+	if r.Ratio != nil {
+		ratio := *r.Ratio
+		r.Ratio = &ratio
+	}
+	return r
+}
+
+// ratio returns the rule's Ratio, 1 when unset.
+func (r SamplingRule) ratio() float64 {
+	if r.Ratio == nil {
+		return 1
+	}
+	return *r.Ratio
+}
+
+// parseSamplingRules decodes the JSON form shown on SamplingRules.
+func parseSamplingRules(b []byte) (SamplingRules, error) {
+	var r SamplingRules
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return SamplingRules{}, err
+	}
+	return r, nil
+}
+
+// samplingRules returns the inline rules followed by those of
+// SamplingRulesFile. An inline Fallback wins over the file's.
+func (c Config) samplingRules() (SamplingRules, error) {
+	rules := c.SamplingRules.clone()
+	if c.SamplingRulesFile == "" {
+		return rules, nil
+	}
+	b, err := os.ReadFile(c.SamplingRulesFile)
+	if err != nil {
+		return SamplingRules{}, fmt.Errorf("sampling rules file: %w", err)
+	}
+	fr, err := parseSamplingRules(b)
+	if err != nil {
+		return SamplingRules{}, fmt.Errorf("sampling rules file %s: %w", c.SamplingRulesFile, err)
+	}
+	if err := fr.validate(); err != nil {
+		return SamplingRules{}, fmt.Errorf("sampling rules file %s: %w", c.SamplingRulesFile, err)
+	}
+	rules.Rules = append(rules.Rules, fr.Rules...)
+	if rules.Fallback == nil {
+		rules.Fallback = fr.Fallback
+	}
+	return rules, nil
+}
+
+// validate reports the first invalid rule.
+func (r SamplingRules) validate() error {
+	check := func(where string, rule SamplingRule) error {
+		if r := rule.ratio(); math.IsNaN(r) || r < 0 || r > 1 {
+			return fmt.Errorf("%s: ratio %v: %w", where, r, ErrInvalidSampleRatio)
+		}
+		if math.IsNaN(rule.RateLimit) || rule.RateLimit < 0 || rule.Burst < 0 {
+			return fmt.Errorf("%s: rate_limit %v, burst %d: %w", where, rule.RateLimit, rule.Burst, ErrInvalidSampleRule)
+		}
+		if _, ok := parseSpanKind(rule.Kind); !ok {
+			return fmt.Errorf("%s: kind %q: %w", where, rule.Kind, ErrInvalidSampleRule)
+		}
+		for _, g := range append([]string{rule.Name}, slices.Collect(maps.Values(rule.Attributes))...) {
+			if _, err := path.Match(g, ""); err != nil {
+				return fmt.Errorf("%s: glob %q: %w", where, g, ErrInvalidSampleRule)
+			}
+		}
+		return nil
+	}
+	for i, rule := range r.Rules {
+		if err := check(fmt.Sprintf("rule %d", i), rule); err != nil {
+			return err
+		}
+	}
+	if r.Fallback != nil {
+		return check("fallback", *r.Fallback)
+	}
+	return nil
+}
+
+func parseSpanKind(s string) (trace.SpanKind, bool) {
+	switch strings.ToLower(s) {
+	case "":
+		return trace.SpanKindUnspecified, true
+	case "internal":
+		return trace.SpanKindInternal, true
+	case "server":
+		return trace.SpanKindServer, true
+	case "client":
+		return trace.SpanKindClient, true
+	case "producer":
+		return trace.SpanKindProducer, true
+	case "consumer":
+		return trace.SpanKindConsumer, true
+	default:
+		return trace.SpanKindUnspecified, false
+	}
+}
+
+// compiledRule is a SamplingRule ready for matching.
+type compiledRule struct {
+	name    string
+	kind    trace.SpanKind
+	attrs   map[string]string
+	sampler sdktrace.Sampler
+}
+
+func compileRule(r SamplingRule, clock Clock) compiledRule {
+	kind, _ := parseSpanKind(r.Kind)
+	return compiledRule{name: r.Name, kind: kind, attrs: r.Attributes, sampler: ruleSampler(r, clock)}
+}
+
+func ruleSampler(r SamplingRule, clock Clock) sdktrace.Sampler {
+	if r.RateLimit > 0 {
+		return newRateLimitSampler(r.RateLimit, r.Burst, clock)
+	}
+	return sdktrace.TraceIDRatioBased(r.ratio())
+}
+
+func (r compiledRule) matches(p sdktrace.SamplingParameters) bool {
+	if r.name != "" && !globMatch(r.name, p.Name) {
+		return false
+	}
+	if r.kind != trace.SpanKindUnspecified && r.kind != p.Kind {
+		return false
+	}
+	for key, glob := range r.attrs {
+		found := false
+		for _, kv := range p.Attributes {
+			if string(kv.Key) == key {
+				found = globMatch(glob, kv.Value.Emit())
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func globMatch(glob, s string) bool {
+	ok, _ := path.Match(glob, s)
+	return ok
+}
+
+// rulesSampler samples each root span with the first matching rule.
+type rulesSampler struct {
+	rules    []compiledRule
+	fallback sdktrace.Sampler
+}
+
+// newRulesSampler compiles r; rate limited rules refill by clock.
+func newRulesSampler(r SamplingRules, clock Clock) (*rulesSampler, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	s := &rulesSampler{fallback: sdktrace.TraceIDRatioBased(defaultSampleRatio)}
+	for _, rule := range r.Rules {
+		s.rules = append(s.rules, compileRule(rule, clock))
+	}
+	if r.Fallback != nil {
+		s.fallback = ruleSampler(*r.Fallback, clock)
+	}
+	return s, nil
+}
+
+func (s *rulesSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, r := range s.rules {
+		if r.matches(p) {
+			return r.sampler.ShouldSample(p)
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s *rulesSampler) Description() string {
+	return fmt.Sprintf("RulesSampler{rules:%d,fallback:%s}", len(s.rules), s.fallback.Description())
+}
+
+// ----------- Rate limit -----------
+
+// NewRateLimitSampler returns a sampler that keeps at most perSecond traces
+// per second, allowing bursts of up to burst traces (default: one second's
+// worth, at least 1). Wrap it in sdktrace.ParentBased, or use it as the
+// root or fallback of another sampler, so child spans follow their parent.
+// A perSecond that is NaN or not positive, or a negative burst, returns an
+// error wrapping ErrInvalidRateLimit.
+func NewRateLimitSampler(perSecond float64, burst int) (sdktrace.Sampler, error) {
+	if math.IsNaN(perSecond) || perSecond <= 0 || burst < 0 {
+		return nil, fmt.Errorf("rate limit %v, burst %d: %w", perSecond, burst, ErrInvalidRateLimit)
+	}
+	return newRateLimitSampler(perSecond, burst, wallClock{}), nil
+}
+
+// rateLimitSampler admits traces through a token bucket refilled at
+// perSecond up to burst, as time passes on clock.
+type rateLimitSampler struct {
+	limit *ratelimit.Limiter
+	clock Clock
+}
+
+func newRateLimitSampler(perSecond float64, burst int, clock Clock) *rateLimitSampler {
+	return &rateLimitSampler{limit: ratelimit.New(perSecond, burst, clock.Now()), clock: clock}
+}
+
+func (s *rateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	decision := sdktrace.Drop
+	if s.limit.Allow(s.clock.Now()) {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{
+		Decision:   decision,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s *rateLimitSampler) Description() string {
+	return fmt.Sprintf("RateLimitSampler{%g/s,burst:%g}", s.limit.Rate(), s.limit.Burst())
+}
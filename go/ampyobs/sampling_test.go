@@ -0,0 +1,291 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// rootParams returns the sampling parameters of a root span.
+func rootParams(name string, kind trace.SpanKind, attrs ...attribute.KeyValue) sdktrace.SamplingParameters {
+	return sdktrace.SamplingParameters{
+		TraceID:    trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		Name:       name,
+		Kind:       kind,
+		Attributes: attrs,
+	}
+}
+
+func TestRulesSampler(t *testing.T) {
+	rules := SamplingRules{
+		Rules: []SamplingRule{
+			{Name: "health.*", Ratio: ptr(0.0)},
+			{Name: "oms.*"}, // no ratio: keep all
+			{Kind: "consumer", Attributes: map[string]string{"topic": "ampy/*/bars/v1"}, Ratio: ptr(0.0)},
+			{Attributes: map[string]string{"broker": "alpaca"}, Ratio: ptr(1.0)},
+		},
+		Fallback: &SamplingRule{Ratio: ptr(0.0)},
+	}
+	s, err := newRulesSampler(rules, wallClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		params sdktrace.SamplingParameters
+		want   sdktrace.SamplingDecision
+	}{
+		{"explicit ratio 0 drops", rootParams("health.check", trace.SpanKindServer), sdktrace.Drop},
+		{"unset ratio keeps", rootParams("oms.submit", trace.SpanKindInternal), sdktrace.RecordAndSample},
+		{"kind and topic glob", rootParams("bus.consume", trace.SpanKindConsumer, attribute.String("topic", "ampy/prod/bars/v1")), sdktrace.Drop},
+		{"glob stays within a segment", rootParams("bus.consume", trace.SpanKindConsumer, attribute.String("topic", "ampy/a/b/bars/v1"), attribute.String("broker", "alpaca")), sdktrace.RecordAndSample},
+		{"other kind", rootParams("bus.consume", trace.SpanKindProducer, attribute.String("topic", "ampy/prod/bars/v1"), attribute.String("broker", "alpaca")), sdktrace.RecordAndSample},
+		{"missing attribute", rootParams("broker.call", trace.SpanKindClient), sdktrace.Drop},
+		{"fallback", rootParams("md.tick", trace.SpanKindInternal), sdktrace.Drop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.ShouldSample(tt.params).Decision; got != tt.want {
+				t.Errorf("decision = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesSamplerDefaultFallback(t *testing.T) {
+	s, err := newRulesSampler(SamplingRules{}, wallClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.fallback.Description(), sdktrace.TraceIDRatioBased(defaultSampleRatio).Description(); got != want {
+		t.Errorf("fallback = %s, want %s", got, want)
+	}
+	s, err = newRulesSampler(SamplingRules{Fallback: &SamplingRule{RateLimit: 5}}, wallClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.fallback.(*rateLimitSampler); !ok {
+		t.Errorf("fallback = %s, want the rate limiter", s.fallback.Description())
+	}
+}
+
+func TestSamplingRulesValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule SamplingRule
+		want error
+	}{
+		{"no ratio or rate", SamplingRule{Name: "oms.*"}, nil},
+		{"ratio 0", SamplingRule{Ratio: ptr(0.0)}, nil},
+		{"rate limit", SamplingRule{RateLimit: 10, Burst: 20}, nil},
+		{"ratio above 1", SamplingRule{Ratio: ptr(1.5)}, ErrInvalidSampleRatio},
+		{"ratio NaN", SamplingRule{Ratio: ptr(math.NaN())}, ErrInvalidSampleRatio},
+		{"negative rate", SamplingRule{RateLimit: -1}, ErrInvalidSampleRule},
+		{"rate NaN", SamplingRule{RateLimit: math.NaN()}, ErrInvalidSampleRule},
+		{"negative burst", SamplingRule{RateLimit: 1, Burst: -1}, ErrInvalidSampleRule},
+		{"unknown kind", SamplingRule{Kind: "queue"}, ErrInvalidSampleRule},
+		{"bad name glob", SamplingRule{Name: "oms.["}, ErrInvalidSampleRule},
+		{"bad attribute glob", SamplingRule{Attributes: map[string]string{"topic": "["}}, ErrInvalidSampleRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, rules := range []SamplingRules{{Rules: []SamplingRule{tt.rule}}, {Fallback: &tt.rule}} {
+				if err := rules.validate(); !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+					t.Errorf("validate() = %v, want %v", err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSamplingRules(t *testing.T) {
+	got, err := parseSamplingRules([]byte(`{"rules": [{"name": "oms.*"}, {"name": "health.*", "ratio": 0}], "fallback": {"rate_limit": 100}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SamplingRules{
+		Rules:    []SamplingRule{{Name: "oms.*"}, {Name: "health.*", Ratio: ptr(0.0)}},
+		Fallback: &SamplingRule{RateLimit: 100},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSamplingRules = %+v, want %+v", got, want)
+	}
+	if _, err := parseSamplingRules([]byte(`{"rules": [{"ratio": 1, "rate": 5}]}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestConfigSamplingRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(file, []byte(`{"rules": [{"name": "md.*", "ratio": 0.1}], "fallback": {"ratio": 0.5}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	inline := SamplingRules{Rules: []SamplingRule{{Name: "oms.*"}}}
+	tests := []struct {
+		name   string
+		config Config
+		want   SamplingRules
+	}{
+		{
+			name:   "file rules after inline ones",
+			config: Config{SamplingRules: inline, SamplingRulesFile: file},
+			want:   SamplingRules{Rules: []SamplingRule{{Name: "oms.*"}, {Name: "md.*", Ratio: ptr(0.1)}}, Fallback: &SamplingRule{Ratio: ptr(0.5)}},
+		},
+		{
+			name:   "inline fallback wins",
+			config: Config{SamplingRules: SamplingRules{Fallback: &SamplingRule{RateLimit: 1}}, SamplingRulesFile: file},
+			want:   SamplingRules{Rules: []SamplingRule{{Name: "md.*", Ratio: ptr(0.1)}}, Fallback: &SamplingRule{RateLimit: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.samplingRules()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("samplingRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The returned rules do not alias the Config's.
+	cfg := Config{SamplingRules: SamplingRules{Rules: []SamplingRule{{Ratio: ptr(0.5)}}}}
+	got, _ := cfg.samplingRules()
+	*got.Rules[0].Ratio = 1
+	if *cfg.SamplingRules.Rules[0].Ratio != 0.5 {
+		t.Error("samplingRules() shares the Config's ratio")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"rules": [{"ratio": 2}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Config{SamplingRulesFile: bad}).samplingRules(); !errors.Is(err, ErrInvalidSampleRatio) {
+		t.Errorf("samplingRules() with an invalid file = %v", err)
+	}
+}
+
+func TestRateLimitSampler(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond float64
+		burst     int
+		wantBurst int           // roots admitted at once
+		after     time.Duration // then, after this long
+		wantAfter int           // roots admitted
+	}{
+		{name: "burst defaults to one second", perSecond: 5, wantBurst: 5, after: time.Second, wantAfter: 5},
+		{name: "explicit burst", perSecond: 5, burst: 20, wantBurst: 20, after: 200 * time.Millisecond, wantAfter: 1},
+		{name: "refill is capped at burst", perSecond: 10, burst: 2, wantBurst: 2, after: time.Minute, wantAfter: 2},
+		{name: "below one per second", perSecond: 0.5, wantBurst: 1, after: time.Second, wantAfter: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewSimClock(time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC))
+			s := newRateLimitSampler(tt.perSecond, tt.burst, clock)
+			admit := func() int {
+				n := 0
+				for range 100 {
+					if s.ShouldSample(rootParams("md.tick", trace.SpanKindInternal)).Decision == sdktrace.RecordAndSample {
+						n++
+					}
+				}
+				return n
+			}
+			if got := admit(); got != tt.wantBurst {
+				t.Errorf("admitted %d at once, want %d", got, tt.wantBurst)
+			}
+			clock.Advance(tt.after)
+			if got := admit(); got != tt.wantAfter {
+				t.Errorf("admitted %d after %s, want %d", got, tt.after, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestNewRateLimitSampler(t *testing.T) {
+	tests := []struct {
+		perSecond float64
+		burst     int
+		wantErr   bool
+	}{
+		{10, 0, false},
+		{0.5, 2, false},
+		{0, 0, true},
+		{-1, 0, true},
+		{math.NaN(), 0, true},
+		{10, -1, true},
+	}
+	for _, tt := range tests {
+		s, err := NewRateLimitSampler(tt.perSecond, tt.burst)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidRateLimit) || s != nil {
+				t.Errorf("NewRateLimitSampler(%g, %d) = %v, %v, want ErrInvalidRateLimit", tt.perSecond, tt.burst, s, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewRateLimitSampler(%g, %d) = %v", tt.perSecond, tt.burst, err)
+		}
+	}
+}
+
+func TestRateLimitSamplerSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"rate limit alone", Config{RateLimit: 10}, "ratelimit"},
+		{"explicit sampler wins", Config{Sampler: "always_on", RateLimit: 10}, "always_on"},
+		{"rules win", Config{RateLimit: 10, SamplingRules: SamplingRules{Rules: []SamplingRule{{}}}}, "rules"},
+		{"neither", Config{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.samplerName(); got != tt.want {
+				t.Errorf("samplerName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Children follow their parent instead of consuming the budget.
+	s, err := newSampler(Config{RateLimit: 1, RateLimitBurst: 1}, &exportStats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := rootParams("md.tick", trace.SpanKindInternal)
+	if got := s.ShouldSample(root).Decision; got != sdktrace.RecordAndSample {
+		t.Fatalf("first root = %v, want sampled", got)
+	}
+	parent := trace.NewSpanContext(trace.SpanContextConfig{TraceID: root.TraceID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled})
+	child := root
+	child.ParentContext = trace.ContextWithSpanContext(context.Background(), parent)
+	for range 3 {
+		if got := s.ShouldSample(child).Decision; got != sdktrace.RecordAndSample {
+			t.Errorf("child of a sampled parent = %v, want sampled", got)
+		}
+	}
+	if got := s.ShouldSample(root).Decision; got != sdktrace.Drop {
+		t.Errorf("second root = %v, want dropped by the limit", got)
+	}
+
+	for _, cfg := range []Config{{Sampler: "ratelimit"}, {Sampler: "ratelimit", RateLimit: math.NaN()}, {RateLimit: 1, RateLimitBurst: -1}} {
+		cfg.ServiceName, cfg.Environment = "svc", "dev"
+		if err := cfg.Validate(); !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("Validate(%g, %d) = %v, want ErrInvalidRateLimit", cfg.RateLimit, cfg.RateLimitBurst, err)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// defaultMaxQueueSize bounds the span and log batch queues. Items beyond it
+// are dropped and counted in ampy.obs.*.dropped_total.
+const defaultMaxQueueSize = 2048
+
+// ----------- Health -----------
+
+// SignalHealth is the state of one signal's export pipeline. Counts are
+// cumulative since New.
+type SignalHealth struct {
+	Enabled       bool
+	Started       int64 // spans started (traces only)
+	Ended         int64 // spans ended or log records emitted
+	Exported      int64 // items accepted by the exporter (data points for metrics)
+	Failed        int64 // items the exporter gave up on
+	Dropped       int64 // items refused because the batch queue was full
+	QueueSize     int64 // items waiting in the batch queue or being exported
+	BatchesOK     int64
+	BatchesFailed int64
+	LastExport    time.Time // last successful export; zero if none yet
+	LastError     error     // most recent export error, even if later exports succeeded
+	LastErrorTime time.Time
+	WAL           WALStats // zero unless Config.WAL is enabled
+
+	// Destinations lists the endpoints of a routed signal (see RoutingConfig)
+	// in configured order; Failovers counts switches between them.
+	Destinations []DestinationHealth
+	Failovers    int64
+}
+
+// DestinationHealth is the state of one endpoint of a routed signal.
+type DestinationHealth struct {
+	Endpoint            string
+	Active              bool // currently receiving batches
+	BatchesOK           int64
+	BatchesFailed       int64
+	ConsecutiveFailures int64
+	LastError           error
+	LastErrorTime       time.Time
+}
+
+// Failing reports whether the most recent export attempt failed.
+func (s SignalHealth) Failing() bool {
+	return s.LastError != nil && s.LastErrorTime.After(s.LastExport)
+}
+
+// Health is a point-in-time view of the instance's telemetry pipeline,
+// e.g. for readiness checks. The same numbers are exported as ampy.obs.*
+// metrics when metrics are enabled.
+type Health struct {
+	Logs    SignalHealth
+	Spans   SignalHealth
+	Metrics SignalHealth
+}
+
+// Err returns nil when no enabled signal is failing to export, or an error
+// naming each failing signal and its last error.
+func (h Health) Err() error {
+	var errs []error
+	for _, s := range []struct {
+		name string
+		h    SignalHealth
+	}{
+		{"logs", h.Logs},
+		{"traces", h.Spans},
+		{"metrics", h.Metrics},
+	} {
+		if s.h.Enabled && s.h.Failing() {
+			errs = append(errs, fmt.Errorf("%s: export failing since %s: %w",
+				s.name, s.h.LastErrorTime.Format(time.RFC3339), s.h.LastError))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthSnapshot returns the current state of the instance's export pipelines.
+func (o *Obs) HealthSnapshot() Health {
+	return Health{
+		Logs:    o.stats.logs.health(o.lp != nil),
+		Spans:   o.stats.spans.health(o.tp != nil),
+		Metrics: o.stats.metrics.health(o.mp != nil && o.cfg.EnableMetrics),
+	}
+}
+
+// HealthSnapshot returns the Default instance's pipeline state.
+func HealthSnapshot() Health {
+	return Default().HealthSnapshot()
+}
+
+func (s *exportStats) health(enabled bool) SignalHealth {
+	h := SignalHealth{
+		Enabled:       enabled,
+		Started:       s.started.Load(),
+		Ended:         s.ended.Load(),
+		Exported:      s.exported.Load(),
+		Failed:        s.failed.Load(),
+		Dropped:       s.dropped.Load(),
+		QueueSize:     s.pending(),
+		BatchesOK:     s.batchesOK.Load(),
+		BatchesFailed: s.batchesFailed.Load(),
+	}
+	if ns := s.lastOK.Load(); ns != 0 {
+		h.LastExport = time.Unix(0, ns)
+	}
+	if f := s.lastFailure.Load(); f != nil {
+		h.LastError, h.LastErrorTime = f.err, f.at
+	}
+	if s.wal != nil {
+		h.WAL = s.wal.stats()
+	}
+	if s.router != nil {
+		h.Destinations = s.router.health()
+		h.Failovers = s.router.failovers.Load()
+	}
+	return h
+}
+
+// ----------- ampy.obs.* metrics -----------
+
+// latencyRecorder records export latency for one signal.
+type latencyRecorder struct {
+	hist  metric.Float64Histogram
+	attrs metric.MeasurementOption
+}
+
+func (l *latencyRecorder) record(took time.Duration) {
+	l.hist.Record(context.Background(), float64(took)/float64(time.Millisecond), l.attrs)
+}
+
+// registerSelfMetrics exposes o's pipeline counters as ampy.obs.* metrics on
+// meter. Counters and gauges are observed at collection time; export latency
+// is recorded by the counting exporters, or by the WAL when enabled.
+func (o *Obs) registerSelfMetrics(meter metric.Meter) error {
+	common := []attribute.KeyValue{
+		attribute.String("service", o.cfg.ServiceName),
+		attribute.String("env", o.cfg.Environment),
+	}
+	with := func(kvs ...attribute.KeyValue) metric.MeasurementOption {
+		return metric.WithAttributes(append(kvs, common...)...)
+	}
+
+	latency, err := meter.Float64Histogram(
+		"ampy.obs.export.latency_ms",
+		metric.WithDescription("Telemetry export latency per batch in milliseconds"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return err
+	}
+	spansStarted, err := meter.Int64ObservableCounter(
+		"ampy.obs.spans.started_total",
+		metric.WithDescription("Spans started"),
+	)
+	if err != nil {
+		return err
+	}
+	spansEnded, err := meter.Int64ObservableCounter(
+		"ampy.obs.spans.ended_total",
+		metric.WithDescription("Spans ended"),
+	)
+	if err != nil {
+		return err
+	}
+	spansDropped, err := meter.Int64ObservableCounter(
+		"ampy.obs.spans.dropped_total",
+		metric.WithDescription("Sampled spans dropped because the batch queue was full"),
+	)
+	if err != nil {
+		return err
+	}
+	logsDropped, err := meter.Int64ObservableCounter(
+		"ampy.obs.logs.dropped_total",
+		metric.WithDescription("Log records dropped because the batch queue was full"),
+	)
+	if err != nil {
+		return err
+	}
+	logsSuppressed, err := meter.Int64ObservableCounter(
+		"ampy.obs.logs.suppressed_total",
+		metric.WithDescription("Log records dropped by log sampling, by level and reason"),
+	)
+	if err != nil {
+		return err
+	}
+	batches, err := meter.Int64ObservableCounter(
+		"ampy.obs.export.batches_total",
+		metric.WithDescription("Telemetry export batches by signal and outcome"),
+	)
+	if err != nil {
+		return err
+	}
+	queueSize, err := meter.Int64ObservableGauge(
+		"ampy.obs.queue.size",
+		metric.WithDescription("Items waiting in the batch queue or being exported"),
+	)
+	if err != nil {
+		return err
+	}
+	walBatches, err := meter.Int64ObservableGauge(
+		"ampy.obs.wal.batches",
+		metric.WithDescription("Batches waiting in the write-ahead log"),
+	)
+	if err != nil {
+		return err
+	}
+
+	destActive, err := meter.Int64ObservableGauge(
+		"ampy.obs.exporter.active",
+		metric.WithDescription("1 while a routed endpoint receives batches, else 0"),
+	)
+	if err != nil {
+		return err
+	}
+	destBatches, err := meter.Int64ObservableCounter(
+		"ampy.obs.exporter.batches_total",
+		metric.WithDescription("Export batches per routed endpoint and outcome"),
+	)
+	if err != nil {
+		return err
+	}
+	destFailures, err := meter.Int64ObservableGauge(
+		"ampy.obs.exporter.consecutive_failures",
+		metric.WithDescription("Failed exports to a routed endpoint since its last success"),
+	)
+	if err != nil {
+		return err
+	}
+	failovers, err := meter.Int64ObservableCounter(
+		"ampy.obs.exporter.failovers_total",
+		metric.WithDescription("Switches to the next routed endpoint"),
+	)
+	if err != nil {
+		return err
+	}
+
+	tailDecisions, err := meter.Int64ObservableCounter(
+		"ampy.obs.tail.decisions_total",
+		metric.WithDescription("Tail sampling decisions by decision and reason"),
+	)
+	if err != nil {
+		return err
+	}
+	tailBuffered, err := meter.Int64ObservableGauge(
+		"ampy.obs.tail.buffered_traces",
+		metric.WithDescription("Traces waiting for a tail sampling decision"),
+	)
+	if err != nil {
+		return err
+	}
+	tailOverflow, err := meter.Int64ObservableCounter(
+		"ampy.obs.tail.spans_overflow_total",
+		metric.WithDescription("Spans discarded because their trace hit the per-trace buffer limit"),
+	)
+	if err != nil {
+		return err
+	}
+
+	samplingStrategy, err := meter.Int64ObservableGauge(
+		"ampy.obs.sampling.strategy",
+		metric.WithDescription("1 for the sampling strategy in effect, by strategy and source"),
+	)
+	if err != nil {
+		return err
+	}
+	samplingPolls, err := meter.Int64ObservableCounter(
+		"ampy.obs.sampling.polls_total",
+		metric.WithDescription("Remote sampling strategy fetches by outcome"),
+	)
+	if err != nil {
+		return err
+	}
+
+	signals := []struct {
+		name  string
+		stats *exportStats
+		queue bool
+	}{
+		{"logs", &o.stats.logs, true},
+		{"traces", &o.stats.spans, true},
+		{"metrics", &o.stats.metrics, false},
+	}
+	for _, sig := range signals {
+		sig.stats.latency.Store(&latencyRecorder{hist: latency, attrs: with(attribute.String("signal", sig.name))})
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		obs.ObserveInt64(spansStarted, o.stats.spans.started.Load(), with())
+		obs.ObserveInt64(spansEnded, o.stats.spans.ended.Load(), with())
+		obs.ObserveInt64(spansDropped, o.stats.spans.dropped.Load(), with())
+		obs.ObserveInt64(logsDropped, o.stats.logs.dropped.Load(), with())
+		if s := o.logSampler; s != nil {
+			for k, n := range s.totalsSnapshot() {
+				obs.ObserveInt64(logsSuppressed, n, with(attribute.String("level", k.level.String()), attribute.String("reason", k.reason)))
+			}
+		}
+		if r := o.stats.spans.remote; r != nil {
+			st := r.active.Load()
+			source := "local"
+			if st.remote {
+				source = "remote"
+			}
+			obs.ObserveInt64(samplingStrategy, 1, with(attribute.String("strategy", st.kind), attribute.String("source", source)))
+			obs.ObserveInt64(samplingPolls, r.pollsOK.Load(), with(attribute.String("outcome", OutcomeOK)))
+			obs.ObserveInt64(samplingPolls, r.pollsFailed.Load(), with(attribute.String("outcome", "failed")))
+		}
+		if t := o.stats.spans.tail; t != nil {
+			for _, r := range tailReasons {
+				obs.ObserveInt64(tailDecisions, t.kept[r].Load(), with(attribute.String("decision", "keep"), attribute.String("reason", r)))
+			}
+			obs.ObserveInt64(tailDecisions, t.dropped.Load(), with(attribute.String("decision", "drop"), attribute.String("reason", tailReasonRatio)))
+			obs.ObserveInt64(tailBuffered, t.buffered.Load(), with())
+			obs.ObserveInt64(tailOverflow, t.overflow.Load(), with())
+		}
+		for _, sig := range signals {
+			signal := attribute.String("signal", sig.name)
+			obs.ObserveInt64(batches, sig.stats.batchesOK.Load(), with(signal, attribute.String("outcome", OutcomeOK)))
+			obs.ObserveInt64(batches, sig.stats.batchesFailed.Load(), with(signal, attribute.String("outcome", "failed")))
+			if sig.queue {
+				obs.ObserveInt64(queueSize, sig.stats.pending(), with(signal))
+			}
+			if sig.stats.wal != nil {
+				obs.ObserveInt64(walBatches, int64(sig.stats.wal.stats().Batches), with(signal))
+			}
+			if r := sig.stats.router; r != nil {
+				obs.ObserveInt64(failovers, r.failovers.Load(), with(signal))
+				for i, d := range r.dests {
+					endpoint := attribute.String("endpoint", d.endpoint)
+					active := int64(0)
+					if r.isActive(i) {
+						active = 1
+					}
+					obs.ObserveInt64(destActive, active, with(signal, endpoint))
+					obs.ObserveInt64(destFailures, d.consecutive.Load(), with(signal, endpoint))
+					obs.ObserveInt64(destBatches, d.batchesOK.Load(), with(signal, endpoint, attribute.String("outcome", OutcomeOK)))
+					obs.ObserveInt64(destBatches, d.batchesFailed.Load(), with(signal, endpoint, attribute.String("outcome", "failed")))
+				}
+			}
+		}
+		return nil
+	}, spansStarted, spansEnded, spansDropped, logsDropped, logsSuppressed, batches, queueSize, walBatches,
+		destActive, destBatches, destFailures, failovers, tailDecisions, tailBuffered, tailOverflow,
+		samplingStrategy, samplingPolls)
+	return err
+}
@@ -0,0 +1,141 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHealthErr(t *testing.T) {
+	boom := errors.New("unavailable")
+	t0 := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		spans   SignalHealth
+		wantErr string // substring; empty for nil
+	}{
+		{name: "no exports yet", spans: SignalHealth{Enabled: true}},
+		{name: "recovered", spans: SignalHealth{Enabled: true, LastError: boom, LastErrorTime: t0, LastExport: t0.Add(time.Second)}},
+		{name: "failing", spans: SignalHealth{Enabled: true, LastError: boom, LastErrorTime: t0.Add(time.Second), LastExport: t0}, wantErr: "traces: export failing since"},
+		{name: "never exported", spans: SignalHealth{Enabled: true, LastError: boom, LastErrorTime: t0}, wantErr: "unavailable"},
+		{name: "disabled", spans: SignalHealth{LastError: boom, LastErrorTime: t0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Health{Spans: tt.spans}.Err()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, boom) {
+				t.Errorf("Err() = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// int64Point returns the value of the int64 sum or gauge point of name
+// carrying attrs, or the count of a histogram point.
+func int64Point(rm metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) (int64, bool) {
+	has := func(set attribute.Set) bool {
+		for _, kv := range attrs {
+			if v, ok := set.Value(kv.Key); !ok || v != kv.Value {
+				return false
+			}
+		}
+		return true
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range d.DataPoints {
+					if has(dp.Attributes) {
+						return dp.Value, true
+					}
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range d.DataPoints {
+					if has(dp.Attributes) {
+						return dp.Value, true
+					}
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range d.DataPoints {
+					if has(dp.Attributes) {
+						return int64(dp.Count), true
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestSelfTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	o := newTestObs(t, func(c *Config) { c.MetricReaders = []sdkmetric.Reader{reader} })
+
+	ctx, span := o.StartSpan(context.Background(), "oms.submit", trace.SpanKindInternal)
+	o.L().InfoContext(ctx, "submitted")
+	span.End()
+	if err := o.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h := o.HealthSnapshot()
+	if err := h.Err(); err != nil {
+		t.Errorf("Err() = %v", err)
+	}
+	for name, s := range map[string]SignalHealth{"spans": h.Spans, "logs": h.Logs} {
+		if !s.Enabled || s.Ended != 1 || s.Exported != 1 || s.QueueSize != 0 || s.BatchesOK == 0 || s.LastExport.IsZero() {
+			t.Errorf("%s health = %+v", name, s)
+		}
+	}
+	if h.Spans.Started != 1 {
+		t.Errorf("spans started = %d, want 1", h.Spans.Started)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	traces := attribute.String("signal", "traces")
+	tests := []struct {
+		name  string
+		attrs []attribute.KeyValue
+		want  int64 // minimum
+	}{
+		{"ampy.obs.spans.started_total", []attribute.KeyValue{attribute.String("service", "svc"), attribute.String("env", "dev")}, 1},
+		{"ampy.obs.spans.ended_total", nil, 1},
+		{"ampy.obs.spans.dropped_total", nil, 0},
+		{"ampy.obs.export.batches_total", []attribute.KeyValue{traces, attribute.String("outcome", OutcomeOK)}, 1},
+		{"ampy.obs.export.batches_total", []attribute.KeyValue{traces, attribute.String("outcome", "failed")}, 0},
+		{"ampy.obs.export.latency_ms", []attribute.KeyValue{traces}, 1},
+		{"ampy.obs.queue.size", []attribute.KeyValue{attribute.String("signal", "logs")}, 0},
+	}
+	for _, tt := range tests {
+		got, ok := int64Point(rm, tt.name, tt.attrs...)
+		if !ok || got < tt.want || (tt.want == 0 && got != 0) {
+			t.Errorf("%s%v = %d (found %v), want %d", tt.name, tt.attrs, got, ok, tt.want)
+		}
+	}
+	// Optional pipelines report nothing when they are off.
+	for _, name := range []string{"ampy.obs.wal.batches", "ampy.obs.exporter.active", "ampy.obs.tail.buffered_traces", "ampy.obs.sampling.strategy"} {
+		if _, ok := int64Point(rm, name); ok {
+			t.Errorf("%s reported without its pipeline", name)
+		}
+	}
+}
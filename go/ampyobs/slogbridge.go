@@ -0,0 +1,215 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelHandler is an slog.Handler that emits records through an OTel Logs
+// API logger. Groups are flattened into dotted attribute keys so they stay
+// queryable in Loki. Trace and span ids come from the record's context.
+type otelHandler struct {
+	logger otellog.Logger
+	level  slog.Leveler
+	attrs  []otellog.KeyValue
+	prefix string // open groups joined with "."
+	clock  Clock  // replay mode: observed timestamps
+}
+
+func newOTelHandler(logger otellog.Logger, level slog.Leveler) *otelHandler {
+	return &otelHandler{logger: logger, level: level}
+}
+
+func (h *otelHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *otelHandler) Handle(ctx context.Context, r slog.Record) error {
+	var rec otellog.Record
+	rec.SetTimestamp(r.Time)
+	observed := time.Now()
+	if h.clock != nil {
+		observed = h.clock.Now()
+	}
+	rec.SetObservedTimestamp(observed)
+	rec.SetBody(otellog.StringValue(r.Message))
+	rec.SetSeverity(slogSeverity(r.Level))
+	rec.SetSeverityText(r.Level.String())
+
+	// trace_id/span_id attrs added by C(ctx) duplicate the record's own ids.
+	hasSpan := trace.SpanContextFromContext(ctx).IsValid()
+	for _, kv := range h.attrs {
+		if hasSpan && (kv.Key == "trace_id" || kv.Key == "span_id") {
+			continue
+		}
+		rec.AddAttributes(kv)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.AddAttributes(slogAttrToOTel(h.prefix, a)...)
+		return true
+	})
+
+	h.logger.Emit(ctx, rec)
+	return nil
+}
+
+func (h *otelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = append([]otellog.KeyValue{}, h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, slogAttrToOTel(h.prefix, a)...)
+	}
+	return &h2
+}
+
+func (h *otelHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// slogSeverity maps slog levels onto OTel severity numbers (INFO = 9).
+func slogSeverity(l slog.Level) otellog.Severity {
+	s := int(l) + int(otellog.SeverityInfo)
+	switch {
+	case s < int(otellog.SeverityTrace1):
+		s = int(otellog.SeverityTrace1)
+	case s > int(otellog.SeverityFatal4):
+		s = int(otellog.SeverityFatal4)
+	}
+	return otellog.Severity(s)
+}
+
+func slogAttrToOTel(prefix string, a slog.Attr) []otellog.KeyValue {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		var out []otellog.KeyValue
+		for _, ga := range v.Group() {
+			out = append(out, slogAttrToOTel(p, ga)...)
+		}
+		return out
+	}
+	if a.Key == "" {
+		return nil
+	}
+	return []otellog.KeyValue{{Key: prefix + a.Key, Value: slogValueToOTel(v)}}
+}
+
+func slogValueToOTel(v slog.Value) otellog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return otellog.StringValue(v.String())
+	case slog.KindInt64:
+		return otellog.Int64Value(v.Int64())
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			return otellog.Int64Value(int64(u))
+		}
+		return otellog.StringValue(v.String())
+	case slog.KindFloat64:
+		return otellog.Float64Value(v.Float64())
+	case slog.KindBool:
+		return otellog.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return otellog.Int64Value(int64(v.Duration()))
+	case slog.KindTime:
+		return otellog.StringValue(v.Time().UTC().Format(time.RFC3339Nano))
+	default:
+		switch x := v.Any().(type) {
+		case error:
+			return otellog.StringValue(x.Error())
+		case []byte:
+			return otellog.BytesValue(x)
+		case fmt.Stringer:
+			return otellog.StringValue(x.String())
+		default:
+			return otellog.StringValue(fmt.Sprint(x))
+		}
+	}
+}
+
+// teeHandler fans records out to several handlers (e.g. stdout and OTLP).
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// contextHandler binds the context captured by C(ctx) to records logged
+// without one, so bridged records still carry trace/span ids and debugged
+// flows log at debug level.
+type contextHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h contextHandler) bind(ctx context.Context) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return h.ctx
+	}
+	return ctx
+}
+
+func (h contextHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.Handler.Enabled(h.bind(ctx), l)
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.Handler.Handle(h.bind(ctx), r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
@@ -0,0 +1,273 @@
+package ampyobs
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TailSamplingConfig keeps whole traces based on how they turned out rather
+// than a coin flip at the root. Spans are buffered per trace until the local
+// root span ends (or Window passes), then the trace is kept if any span has
+// an error status, any span has an "outcome" attribute of OutcomeReject or
+// OutcomeDLQ, or the root took at least LatencyThreshold; the rest are kept
+// with probability Ratio. Traces of debugged flows (see AddDebugTarget) are
+// always kept.
+//
+// With tail sampling every trace is recorded; Validate rejects a head sampler
+// (a Sampler other than parent or always_on, a rate limit, rules or
+// RemoteSampling) alongside it. A not-sampled decision from an upstream
+// service is still honoured.
+type TailSamplingConfig struct {
+	Enabled          bool
+	Ratio            float64       // share of the remaining traces kept (0 keeps only the ones matched above)
+	LatencyThreshold time.Duration // root duration that always keeps a trace (default: 250ms)
+	Window           time.Duration // max wait for the root span (default: 10s)
+	MaxTraces        int           // traces buffered at once; the oldest is decided early beyond it (default: 10000)
+	MaxSpansPerTrace int           // spans buffered per trace; later ones except the root are discarded (default: 1000)
+}
+
+// Tail sampling decision reasons, reported as the reason label of
+// ampy.obs.tail.decisions_total.
+const (
+	tailReasonError   = "error"
+	tailReasonOutcome = "outcome"
+	tailReasonLatency = "latency"
+	tailReasonRatio   = "ratio"
+	tailReasonDebug   = "debug"
+)
+
+var tailReasons = []string{tailReasonError, tailReasonOutcome, tailReasonLatency, tailReasonRatio, tailReasonDebug}
+
+// tailTrace is the buffered state of one undecided trace.
+type tailTrace struct {
+	spans  []sdktrace.ReadOnlySpan
+	first  time.Time
+	reason string // first keep rule matched so far; empty if none
+}
+
+// tailSampler is a span processor that makes the keep/drop decision per
+// trace and forwards kept spans to next.
+type tailSampler struct {
+	next      sdktrace.SpanProcessor
+	ratio     float64
+	threshold uint64 // trace id cut-off derived from ratio
+	latency   time.Duration
+	window    time.Duration
+	maxTraces int
+	maxSpans  int
+	clock     Clock // times the window
+
+	mu           sync.Mutex
+	traces       map[trace.TraceID]*tailTrace
+	order        []trace.TraceID // buffered traces, oldest first (may hold decided ids)
+	decided      map[trace.TraceID]bool
+	decidedOrder []trace.TraceID
+
+	kept     map[string]*atomic.Int64 // by reason
+	dropped  atomic.Int64
+	overflow atomic.Int64 // spans discarded by MaxSpansPerTrace
+	buffered atomic.Int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// newTailSampler forwards kept spans to next, timing the window by clock.
+func newTailSampler(cfg TailSamplingConfig, next sdktrace.SpanProcessor, clock Clock) *tailSampler {
+	p := &tailSampler{
+		next:      next,
+		clock:     clock,
+		ratio:     cfg.Ratio,
+		threshold: uint64(cfg.Ratio * (1 << 63)),
+		latency:   valueOr(cfg.LatencyThreshold, 250*time.Millisecond),
+		window:    valueOr(cfg.Window, 10*time.Second),
+		maxTraces: valueOr(cfg.MaxTraces, 10000),
+		maxSpans:  valueOr(cfg.MaxSpansPerTrace, 1000),
+		traces:    make(map[trace.TraceID]*tailTrace),
+		decided:   make(map[trace.TraceID]bool),
+		kept:      make(map[string]*atomic.Int64, len(tailReasons)),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, r := range tailReasons {
+		p.kept[r] = new(atomic.Int64)
+	}
+	go p.sweep()
+	return p
+}
+
+func (p *tailSampler) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(ctx, s)
+}
+
+func (p *tailSampler) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		p.next.OnEnd(s) // counted as ended, not exported
+		return
+	}
+	tid := s.SpanContext().TraceID()
+
+	p.mu.Lock()
+	if keep, ok := p.decided[tid]; ok {
+		// Late span of a decided trace, e.g. an async child.
+		p.mu.Unlock()
+		if keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+	var flush []sdktrace.ReadOnlySpan
+	t := p.traces[tid]
+	if t == nil {
+		if len(p.traces) >= p.maxTraces {
+			flush = append(flush, p.decideOldest()...)
+		}
+		t = &tailTrace{first: p.clock.Now()}
+		p.traces[tid] = t
+		p.order = append(p.order, tid)
+		p.buffered.Add(1)
+	}
+	parent := s.Parent()
+	root := !parent.IsValid() || parent.IsRemote()
+	if len(t.spans) < p.maxSpans || root {
+		t.spans = append(t.spans, s)
+	} else {
+		p.overflow.Add(1)
+	}
+	if t.reason == "" {
+		t.reason = spanKeepReason(s)
+	}
+	if root {
+		if t.reason == "" && s.EndTime().Sub(s.StartTime()) >= p.latency {
+			t.reason = tailReasonLatency
+		}
+		flush = append(flush, p.decide(tid, t)...)
+	}
+	p.mu.Unlock()
+
+	for _, k := range flush {
+		p.next.OnEnd(k)
+	}
+}
+
+// spanKeepReason returns the keep rule s matches on its own, if any.
+func spanKeepReason(s sdktrace.ReadOnlySpan) string {
+	if s.Status().Code == codes.Error {
+		return tailReasonError
+	}
+	reason := ""
+	for _, kv := range s.Attributes() {
+		switch {
+		case kv.Key == "outcome":
+			if v := kv.Value.AsString(); v == OutcomeReject || v == OutcomeDLQ {
+				return tailReasonOutcome
+			}
+		case kv == debugAttr:
+			reason = tailReasonDebug
+		}
+	}
+	return reason
+}
+
+// decide removes t from the buffer, records the decision and returns the
+// spans to forward. Callers hold p.mu.
+func (p *tailSampler) decide(tid trace.TraceID, t *tailTrace) []sdktrace.ReadOnlySpan {
+	delete(p.traces, tid)
+	p.buffered.Add(-1)
+
+	reason := t.reason
+	if reason == "" && p.ratio > 0 && binary.BigEndian.Uint64(tid[8:16])>>1 < p.threshold {
+		reason = tailReasonRatio
+	}
+	keep := reason != ""
+
+	p.decided[tid] = keep
+	p.decidedOrder = append(p.decidedOrder, tid)
+	if len(p.decidedOrder) > p.maxTraces {
+		delete(p.decided, p.decidedOrder[0])
+		p.decidedOrder = p.decidedOrder[1:]
+	}
+
+	if !keep {
+		p.dropped.Add(1)
+		return nil
+	}
+	p.kept[reason].Add(1)
+	return t.spans
+}
+
+// decideOldest decides the oldest buffered trace. Callers hold p.mu.
+func (p *tailSampler) decideOldest() []sdktrace.ReadOnlySpan {
+	for len(p.order) > 0 {
+		tid := p.order[0]
+		p.order = p.order[1:]
+		if t, ok := p.traces[tid]; ok {
+			return p.decide(tid, t)
+		}
+	}
+	return nil
+}
+
+// decideExpired decides traces buffered for longer than the window, or all
+// of them when all is set.
+func (p *tailSampler) decideExpired(all bool) {
+	cutoff := p.clock.Now().Add(-p.window)
+	var flush []sdktrace.ReadOnlySpan
+
+	p.mu.Lock()
+	for len(p.order) > 0 {
+		tid := p.order[0]
+		t, ok := p.traces[tid]
+		if ok && !all && t.first.After(cutoff) {
+			break
+		}
+		p.order = p.order[1:]
+		if ok {
+			flush = append(flush, p.decide(tid, t)...)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, s := range flush {
+		p.next.OnEnd(s)
+	}
+}
+
+func (p *tailSampler) sweep() {
+	defer close(p.done)
+	tick := time.NewTicker(max(p.window/4, 100*time.Millisecond))
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			p.decideExpired(false)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// ForceFlush decides traces past their window and flushes the spans kept so
+// far. Traces still waiting for their root stay buffered: deciding them now
+// would judge them on part of their spans.
+func (p *tailSampler) ForceFlush(ctx context.Context) error {
+	p.decideExpired(false)
+	return p.next.ForceFlush(ctx)
+}
+
+// Shutdown decides every buffered trace, complete or not, before shutting
+// down next.
+func (p *tailSampler) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+	p.decideExpired(true)
+	return p.next.Shutdown(ctx)
+}
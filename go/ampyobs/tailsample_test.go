@@ -0,0 +1,197 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTailProvider returns a tracer provider whose spans go through a tail
+// sampler into the returned recorder.
+func newTailProvider(t *testing.T, cfg TailSamplingConfig) (*sdktrace.TracerProvider, *tailSampler, *tracetest.SpanRecorder) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tail := newTailSampler(cfg, rec, wallClock{})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(tail))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp, tail, rec
+}
+
+func TestTailSamplerDecisions(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		ratio      float64
+		root       time.Duration // root duration
+		child      func(trace.Span)
+		wantReason string // empty if the trace is dropped
+	}{
+		{name: "fast and clean", root: time.Millisecond},
+		{name: "error in a child", root: time.Millisecond, child: func(s trace.Span) { s.SetStatus(codes.Error, "rejected by venue") }, wantReason: tailReasonError},
+		{name: "reject outcome", root: time.Millisecond, child: func(s trace.Span) { s.SetAttributes(attribute.String("outcome", OutcomeReject)) }, wantReason: tailReasonOutcome},
+		{name: "other outcome", root: time.Millisecond, child: func(s trace.Span) { s.SetAttributes(attribute.String("outcome", "ok")) }},
+		{name: "slow root", root: time.Second, wantReason: tailReasonLatency},
+		{name: "debugged flow", root: time.Millisecond, child: func(s trace.Span) { s.SetAttributes(debugAttr) }, wantReason: tailReasonDebug},
+		{name: "ratio keeps the rest", ratio: 1, root: time.Millisecond, wantReason: tailReasonRatio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, tail, rec := newTailProvider(t, TailSamplingConfig{Ratio: tt.ratio})
+			tracer := tp.Tracer("test")
+			ctx, root := tracer.Start(context.Background(), "order.submit", trace.WithTimestamp(start))
+			_, child := tracer.Start(ctx, "risk.check")
+			if tt.child != nil {
+				tt.child(child)
+			}
+			child.End()
+			if n := len(rec.Ended()); n != 0 {
+				t.Fatalf("%d spans exported before the root ended", n)
+			}
+			root.End(trace.WithTimestamp(start.Add(tt.root)))
+
+			wantSpans, wantDropped := 2, int64(0)
+			if tt.wantReason == "" {
+				wantSpans, wantDropped = 0, 1
+			}
+			if n := len(rec.Ended()); n != wantSpans {
+				t.Errorf("exported %d spans, want %d", n, wantSpans)
+			}
+			if got := tail.dropped.Load(); got != wantDropped {
+				t.Errorf("dropped %d traces, want %d", got, wantDropped)
+			}
+			for reason, n := range tail.kept {
+				want := int64(0)
+				if reason == tt.wantReason {
+					want = 1
+				}
+				if n.Load() != want {
+					t.Errorf("kept[%s] = %d, want %d", reason, n.Load(), want)
+				}
+			}
+			if got := tail.buffered.Load(); got != 0 {
+				t.Errorf("%d traces still buffered", got)
+			}
+		})
+	}
+}
+
+func TestTailSamplerLateSpan(t *testing.T) {
+	tp, _, rec := newTailProvider(t, TailSamplingConfig{})
+	tracer := tp.Tracer("test")
+	ctx, root := tracer.Start(context.Background(), "order.submit")
+	root.SetStatus(codes.Error, "rejected")
+	_, async := tracer.Start(ctx, "audit.write")
+	root.End()
+	async.End() // ends after the trace was kept
+
+	if n := len(rec.Ended()); n != 2 {
+		t.Errorf("exported %d spans, want the late child too", n)
+	}
+}
+
+func TestTailSamplerFlushAndShutdown(t *testing.T) {
+	tp, tail, rec := newTailProvider(t, TailSamplingConfig{})
+	tracer := tp.Tracer("test")
+	ctx, root := tracer.Start(context.Background(), "order.submit")
+	_, child := tracer.Start(ctx, "risk.check")
+	child.SetStatus(codes.Error, "limit")
+	child.End()
+
+	// The root has not ended: ForceFlush must not judge the trace yet.
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n, b := len(rec.Ended()), tail.buffered.Load(); n != 0 || b != 1 {
+		t.Errorf("after ForceFlush: %d spans exported, %d traces buffered; want 0 and 1", n, b)
+	}
+
+	if err := tail.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Ended()); n != 1 {
+		t.Errorf("after Shutdown: %d spans exported, want the buffered child", n)
+	}
+	if got := tail.kept[tailReasonError].Load(); got != 1 {
+		t.Errorf("kept[error] = %d, want 1", got)
+	}
+	root.End()
+}
+
+func TestTailSamplerLimits(t *testing.T) {
+	t.Run("max traces", func(t *testing.T) {
+		tp, tail, _ := newTailProvider(t, TailSamplingConfig{MaxTraces: 1})
+		tracer := tp.Tracer("test")
+		var roots []trace.Span
+		for range 2 {
+			ctx, root := tracer.Start(context.Background(), "order.submit")
+			_, child := tracer.Start(ctx, "risk.check")
+			child.End()
+			roots = append(roots, root)
+		}
+		if b, d := tail.buffered.Load(), tail.dropped.Load(); b != 1 || d != 1 {
+			t.Errorf("buffered %d and dropped %d traces, want the oldest decided early", b, d)
+		}
+		for _, root := range roots {
+			root.End()
+		}
+	})
+	t.Run("max spans per trace", func(t *testing.T) {
+		tp, tail, rec := newTailProvider(t, TailSamplingConfig{MaxSpansPerTrace: 1, Ratio: 1})
+		tracer := tp.Tracer("test")
+		ctx, root := tracer.Start(context.Background(), "order.submit")
+		for range 3 {
+			_, child := tracer.Start(ctx, "fill")
+			child.End()
+		}
+		root.End()
+		if got := tail.overflow.Load(); got != 2 {
+			t.Errorf("overflow = %d, want 2", got)
+		}
+		if n := len(rec.Ended()); n != 2 {
+			t.Errorf("exported %d spans, want one child and the root", n)
+		}
+	})
+}
+
+func TestTailSamplingReplacesHeadSampler(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  func(*Config)
+		wantErr bool
+	}{
+		{name: "always_on", config: func(c *Config) {}},
+		{name: "parent", config: func(c *Config) { c.Sampler = "parent" }},
+		{name: "ratio", config: func(c *Config) { c.Sampler = "ratio" }, wantErr: true},
+		{name: "rate limit", config: func(c *Config) { c.Sampler, c.RateLimit = "", 10 }, wantErr: true},
+		{name: "rules", config: func(c *Config) {
+			c.Sampler, c.SamplingRules.Rules = "", []SamplingRule{{Name: "orders"}}
+		}, wantErr: true},
+		{name: "remote", config: func(c *Config) { c.RemoteSampling.URL = "http://127.0.0.1:1/sampling" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ServiceName: "svc", Environment: "dev", Sampler: "always_on", TailSampling: TailSamplingConfig{Enabled: true}}
+			tt.config(&cfg)
+			if err := cfg.Validate(); errors.Is(err, ErrTailSampler) != tt.wantErr {
+				t.Errorf("Validate() = %v, want ErrTailSampler %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Let through leniently, the head sampler is not built at all.
+	o := newTestObs(t, func(c *Config) {
+		c.TailSampling.Enabled = true
+		c.RemoteSampling.URL = "http://127.0.0.1:1/sampling"
+		c.LenientValidation = true
+	})
+	if o.stats.spans.remote != nil {
+		t.Error("remote sampler polled alongside tail sampling")
+	}
+}
@@ -0,0 +1,42 @@
+package ampyobs
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/tlsconfig"
+)
+
+// TLSConfig configures TLS toward the collector. It applies to every gRPC and
+// HTTP exporter (traces, metrics, logs). Certificates loaded from files are
+// re-read when the files change, so rotated certs are picked up without a restart.
+type TLSConfig struct {
+	CAFile string // PEM bundle used to verify the collector (default: system roots)
+	CAPEM  []byte // inline PEM, combined with CAFile
+
+	CertFile string // client certificate for mTLS
+	KeyFile  string // client key for mTLS
+	CertPEM  []byte // inline client certificate, used when CertFile is empty
+	KeyPEM   []byte // inline client key, used when KeyFile is empty
+
+	ServerName         string        // overrides the name verified against the collector cert
+	MinVersion         string        // "1.2" | "1.3" (default: "1.2")
+	InsecureSkipVerify bool          // dev only: do not verify the collector certificate
+	ReloadInterval     time.Duration // how often files are checked for changes (default: 30s)
+}
+
+// shared converts t for the tlsconfig package, which both SDKs build on.
+func (t TLSConfig) shared() tlsconfig.Config { return tlsconfig.Config(t) }
+
+// enabled reports whether any TLS setting was provided.
+func (t TLSConfig) enabled() bool { return t.shared().Enabled() }
+
+func (t TLSConfig) hasClientCert() bool { return t.shared().HasClientCert() }
+
+func (t TLSConfig) hasClientKey() bool { return t.shared().HasClientKey() }
+
+func parseTLSVersion(v string) (uint16, bool) { return tlsconfig.ParseVersion(v) }
+
+// newTLSClientConfig builds a *tls.Config for exporters whose client cert and
+// CA pool are reloaded when their files change.
+func newTLSClientConfig(t TLSConfig) (*tls.Config, error) { return tlsconfig.New(t.shared()) }
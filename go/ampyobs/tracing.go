@@ -17,43 +17,63 @@ type BusAttrs struct {
 	RunID        string
 }
 
+// Tracer returns the instance's "ampyobs" tracer. Without a tracer provider
+// of its own it falls back to the OTel global.
+func (o *Obs) Tracer() trace.Tracer {
+	if o.tp != nil {
+		return o.TracerProvider().Tracer("ampyobs")
+	}
+	return otel.Tracer("ampyobs")
+}
+
 // StartSpan creates a span with a conventional name and kind.
-func StartSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
-	tr := otel.Tracer("ampyobs")
+func (o *Obs) StartSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
 	opts := []trace.SpanStartOption{
 		trace.WithSpanKind(kind),
 		trace.WithAttributes(attrs...),
 	}
-	return tr.Start(ctx, name, opts...)
+	return o.Tracer().Start(ctx, name, opts...)
 }
 
 // StartBusPublishSpan creates a `bus.publish` span with standardized attributes.
-func StartBusPublishSpan(ctx context.Context, a BusAttrs) (context.Context, trace.Span) {
-	return StartSpan(ctx, "bus.publish", trace.SpanKindProducer,
-		attribute.String("topic", a.Topic),
-		attribute.String("schema_fqdn", a.SchemaFQDN),
-		attribute.String("message_id", a.MessageID),
-		attribute.String("partition_key", a.PartitionKey),
-		attribute.String("run_id", a.RunID),
-	)
+func (o *Obs) StartBusPublishSpan(ctx context.Context, a BusAttrs) (context.Context, trace.Span) {
+	return o.StartSpan(ctx, "bus.publish", trace.SpanKindProducer, a.attributes()...)
 }
 
 // StartBusConsumeSpan extracts W3C context from headers and starts `bus.consume`
 // as a child of the upstream span. It also adds a span link to the upstream context.
-func StartBusConsumeSpan(parent context.Context, headers map[string]string, a BusAttrs) (context.Context, trace.Span) {
-	remoteCtx := ExtractTrace(parent, headers) // from propagation.go
+func (o *Obs) StartBusConsumeSpan(parent context.Context, headers map[string]string, a BusAttrs) (context.Context, trace.Span) {
+	remoteCtx := o.ExtractTrace(parent, headers) // from propagation.go
 	link := trace.LinkFromContext(remoteCtx)
 
-	tr := otel.Tracer("ampyobs")
-	return tr.Start(remoteCtx, "bus.consume",
+	return o.Tracer().Start(remoteCtx, "bus.consume",
 		trace.WithSpanKind(trace.SpanKindConsumer),
-		trace.WithAttributes(
-			attribute.String("topic", a.Topic),
-			attribute.String("schema_fqdn", a.SchemaFQDN),
-			attribute.String("message_id", a.MessageID),
-			attribute.String("partition_key", a.PartitionKey),
-			attribute.String("run_id", a.RunID),
-		),
+		trace.WithAttributes(a.attributes()...),
 		trace.WithLinks(link),
 	)
 }
+
+func (a BusAttrs) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("topic", a.Topic),
+		attribute.String("schema_fqdn", a.SchemaFQDN),
+		attribute.String("message_id", a.MessageID),
+		attribute.String("partition_key", a.PartitionKey),
+		attribute.String("run_id", a.RunID),
+	}
+}
+
+// StartSpan creates a span on the Default instance.
+func StartSpan(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Default().StartSpan(ctx, name, kind, attrs...)
+}
+
+// StartBusPublishSpan creates a `bus.publish` span on the Default instance.
+func StartBusPublishSpan(ctx context.Context, a BusAttrs) (context.Context, trace.Span) {
+	return Default().StartBusPublishSpan(ctx, a)
+}
+
+// StartBusConsumeSpan starts a `bus.consume` span on the Default instance.
+func StartBusConsumeSpan(parent context.Context, headers map[string]string, a BusAttrs) (context.Context, trace.Span) {
+	return Default().StartBusConsumeSpan(parent, headers, a)
+}
@@ -0,0 +1,229 @@
+package ampyobs
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sentinel errors reported by Config.Validate. Match them with errors.Is.
+var (
+	ErrMissingServiceName = errors.New("service name is required")
+	ErrInvalidEnvironment = errors.New("environment must be one of dev, paper, prod")
+	ErrUnknownSampler     = errors.New("unknown sampler")
+	ErrInvalidSampleRatio = errors.New("sample ratio must be within [0, 1]")
+	ErrInvalidTLS         = errors.New("invalid TLS configuration")
+	ErrUnknownProtocol    = errors.New("unknown protocol (use grpc, http/protobuf, http/json, stdout, file or none)")
+	ErrUnknownFormat      = errors.New("unknown export format (use pretty or otlp-json)")
+	ErrUnknownCompression = errors.New("unknown compression (use gzip or none)")
+	ErrInvalidDuration    = errors.New("duration must not be negative")
+	ErrInvalidRetry       = errors.New("invalid retry policy")
+	ErrInvalidHeader      = errors.New("header names must not be empty")
+	ErrUnknownProfile     = errors.New("unknown pipeline profile (use oms, bulk or job)")
+	ErrInvalidPipeline    = errors.New("invalid pipeline settings")
+	ErrInvalidRouting     = errors.New("invalid routing")
+	ErrInvalidSampleRule  = errors.New("invalid sampling rule")
+	ErrTailSampler        = errors.New("tail sampling replaces the head sampler; leave Sampler unset, parent or always_on")
+	ErrInvalidRateLimit   = errors.New("rate limit must be positive and burst not negative")
+	ErrInvalidLogLevel    = errors.New("unknown log level (use debug, info, warn or error)")
+	ErrInvalidLogSampling = errors.New("log sampling counts and rate limit must not be negative")
+	ErrInvalidEnvVar      = errors.New("malformed environment variable")
+)
+
+// ConfigError describes a single invalid Config field. It unwraps to one of
+// the Err* sentinels above.
+type ConfigError struct {
+	Field string
+	Value any
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("ampyobs config: %s=%v: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// Validate checks c and returns every problem found, joined with errors.Join.
+// Each joined error is a *ConfigError. A nil result means c is valid.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(field string, value any, err error) {
+		errs = append(errs, &ConfigError{Field: field, Value: value, Err: err})
+	}
+
+	if strings.TrimSpace(c.ServiceName) == "" {
+		add("ServiceName", c.ServiceName, ErrMissingServiceName)
+	}
+
+	switch c.Environment {
+	case "dev", "paper", "prod":
+	default:
+		add("Environment", c.Environment, ErrInvalidEnvironment)
+	}
+
+	for _, p := range []struct{ field, value string }{
+		{"Protocol", c.Protocol},
+		{"TraceProtocol", c.TraceProtocol},
+		{"MetricProtocol", c.MetricProtocol},
+		{"LogProtocol", c.LogProtocol},
+	} {
+		if _, ok := normalizeProtocol(p.value); !ok {
+			add(p.field, p.value, ErrUnknownProtocol)
+		}
+	}
+
+	switch strings.ToLower(c.ExportFormat) {
+	case "", FormatPretty, FormatOTLPJSON:
+	default:
+		add("ExportFormat", c.ExportFormat, ErrUnknownFormat)
+	}
+
+	if c.TLS.hasClientCert() != c.TLS.hasClientKey() {
+		add("TLS", "client cert/key", fmt.Errorf("%w: client certificate and key must be set together", ErrInvalidTLS))
+	}
+	if _, ok := parseTLSVersion(c.TLS.MinVersion); !ok {
+		add("TLS.MinVersion", c.TLS.MinVersion, fmt.Errorf("%w: use 1.2 or 1.3", ErrInvalidTLS))
+	}
+	if c.TLS.InsecureSkipVerify && c.Environment == "prod" {
+		add("TLS.InsecureSkipVerify", true, fmt.Errorf("%w: not allowed in prod", ErrInvalidTLS))
+	}
+
+	switch strings.ToLower(c.Compression) {
+	case "", CompressionNone, CompressionGzip:
+	default:
+		add("Compression", c.Compression, ErrUnknownCompression)
+	}
+	for _, d := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"ExportTimeout", c.ExportTimeout},
+		{"Retry.InitialInterval", c.Retry.InitialInterval},
+		{"Retry.MaxInterval", c.Retry.MaxInterval},
+		{"Retry.MaxElapsedTime", c.Retry.MaxElapsedTime},
+		{"Pipeline.BatchTimeout", c.Pipeline.BatchTimeout},
+		{"Pipeline.BatchExportTimeout", c.Pipeline.BatchExportTimeout},
+		{"Pipeline.MetricInterval", c.Pipeline.MetricInterval},
+		{"Pipeline.MetricTimeout", c.Pipeline.MetricTimeout},
+		{"Routing.ProbeInterval", c.Routing.ProbeInterval},
+		{"TailSampling.LatencyThreshold", c.TailSampling.LatencyThreshold},
+		{"TailSampling.Window", c.TailSampling.Window},
+		{"RemoteSampling.PollInterval", c.RemoteSampling.PollInterval},
+		{"RemoteSampling.Timeout", c.RemoteSampling.Timeout},
+		{"LogSampling.Interval", c.LogSampling.Interval},
+		{"LogSampling.SummaryInterval", c.LogSampling.SummaryInterval},
+	} {
+		if d.value < 0 {
+			add(d.field, d.value, ErrInvalidDuration)
+		}
+	}
+	if r := c.Retry.withDefaults(); r.InitialInterval > r.MaxInterval {
+		add("Retry", c.Retry, fmt.Errorf("%w: initial interval exceeds max interval", ErrInvalidRetry))
+	}
+	if _, ok := pipelineProfiles[strings.ToLower(c.Pipeline.Profile)]; !ok && c.Pipeline.Profile != "" {
+		add("Pipeline.Profile", c.Pipeline.Profile, ErrUnknownProfile)
+	}
+	for _, n := range []struct {
+		field string
+		value int
+	}{
+		{"Pipeline.MaxQueueSize", c.Pipeline.MaxQueueSize},
+		{"Pipeline.MaxExportBatchSize", c.Pipeline.MaxExportBatchSize},
+		{"Pipeline.LogExportBufferSize", c.Pipeline.LogExportBufferSize},
+	} {
+		if n.value < 0 {
+			add(n.field, n.value, fmt.Errorf("%w: must not be negative", ErrInvalidPipeline))
+		}
+	}
+	if q, b := c.Pipeline.MaxQueueSize, c.Pipeline.MaxExportBatchSize; q > 0 && b > q {
+		add("Pipeline.MaxExportBatchSize", b, fmt.Errorf("%w: batch size exceeds queue size %d", ErrInvalidPipeline, q))
+	}
+	if r := c.TailSampling.Ratio; math.IsNaN(r) || r < 0 || r > 1 {
+		add("TailSampling.Ratio", r, ErrInvalidSampleRatio)
+	}
+	if c.TailSampling.MaxTraces < 0 || c.TailSampling.MaxSpansPerTrace < 0 {
+		add("TailSampling", c.TailSampling, fmt.Errorf("%w: buffer limits must not be negative", ErrInvalidPipeline))
+	}
+	if c.TailSampling.Enabled {
+		switch name := c.samplerName(); {
+		case name != "" && name != "parent" && name != "always_on":
+			add("Sampler", name, ErrTailSampler)
+		case c.RemoteSampling.URL != "":
+			add("RemoteSampling.URL", c.RemoteSampling.URL, ErrTailSampler)
+		}
+	}
+	if ls := c.LogSampling; ls.First < 0 || ls.Thereafter < 0 || math.IsNaN(ls.RateLimit) || ls.RateLimit < 0 {
+		add("LogSampling", ls, ErrInvalidLogSampling)
+	}
+	switch strings.ToLower(c.Routing.Policy) {
+	case "", RoutingFailover, RoutingFanout:
+	default:
+		add("Routing.Policy", c.Routing.Policy, fmt.Errorf("%w: policy must be failover or fanout", ErrInvalidRouting))
+	}
+	if c.Routing.FailoverAfter < 0 {
+		add("Routing.FailoverAfter", c.Routing.FailoverAfter, fmt.Errorf("%w: must not be negative", ErrInvalidRouting))
+	}
+	for _, l := range []struct {
+		field string
+		eps   []string
+	}{
+		{"Routing.Endpoints", c.Routing.Endpoints},
+		{"Routing.TraceEndpoints", c.Routing.TraceEndpoints},
+		{"Routing.MetricEndpoints", c.Routing.MetricEndpoints},
+		{"Routing.LogEndpoints", c.Routing.LogEndpoints},
+	} {
+		if slices.ContainsFunc(l.eps, func(ep string) bool { return strings.TrimSpace(ep) == "" }) {
+			add(l.field, l.eps, fmt.Errorf("%w: empty endpoint", ErrInvalidRouting))
+		}
+	}
+	for k := range c.Headers {
+		if strings.TrimSpace(k) == "" {
+			add("Headers", k, ErrInvalidHeader)
+			break
+		}
+	}
+	if _, err := parseLevel(c.LogLevel); c.LogLevel != "" && err != nil {
+		add("LogLevel", c.LogLevel, ErrInvalidLogLevel)
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.LogLevels)) {
+		if _, err := parseLevel(c.LogLevels[name]); err != nil {
+			add("LogLevels["+name+"]", c.LogLevels[name], ErrInvalidLogLevel)
+		}
+	}
+
+	switch c.samplerName() {
+	case "", "parent", "always_on", "always_off":
+	case "ratelimit":
+		if math.IsNaN(c.RateLimit) || c.RateLimit <= 0 || c.RateLimitBurst < 0 {
+			add("RateLimit", c.RateLimit, ErrInvalidRateLimit)
+		}
+	case "rules":
+		if err := c.SamplingRules.validate(); err != nil {
+			add("SamplingRules", c.SamplingRules.Rules, err)
+		}
+	case "ratio":
+		if math.IsNaN(c.SampleRatio) || c.SampleRatio < 0 || c.SampleRatio > 1 {
+			add("SampleRatio", c.SampleRatio, ErrInvalidSampleRatio)
+		}
+	default:
+		add("Sampler", c.Sampler, ErrUnknownSampler)
+	}
+
+	return errors.Join(errs...)
+}
+
+// unjoin splits an errors.Join result into its errors.
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
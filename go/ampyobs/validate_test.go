@@ -0,0 +1,134 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    func(*Config)
+		field  string // empty if the config is valid
+		wantIs error
+	}{
+		{name: "valid", cfg: func(c *Config) {}},
+		{name: "missing service", cfg: func(c *Config) { c.ServiceName = " " }, field: "ServiceName", wantIs: ErrMissingServiceName},
+		{name: "environment", cfg: func(c *Config) { c.Environment = "staging" }, field: "Environment", wantIs: ErrInvalidEnvironment},
+		{name: "protocol alias", cfg: func(c *Config) { c.MetricProtocol = "HTTP" }},
+		{name: "protocol", cfg: func(c *Config) { c.LogProtocol = "kafka" }, field: "LogProtocol", wantIs: ErrUnknownProtocol},
+		{name: "format", cfg: func(c *Config) { c.ExportFormat = "yaml" }, field: "ExportFormat", wantIs: ErrUnknownFormat},
+		{name: "cert without key", cfg: func(c *Config) { c.TLS.CertFile = "client.pem" }, field: "TLS", wantIs: ErrInvalidTLS},
+		{name: "TLS version", cfg: func(c *Config) { c.TLS.MinVersion = "1.1" }, field: "TLS.MinVersion", wantIs: ErrInvalidTLS},
+		{name: "insecure in prod", cfg: func(c *Config) { c.Environment, c.TLS.InsecureSkipVerify = "prod", true }, field: "TLS.InsecureSkipVerify", wantIs: ErrInvalidTLS},
+		{name: "compression", cfg: func(c *Config) { c.Compression = "zstd" }, field: "Compression", wantIs: ErrUnknownCompression},
+		{name: "negative duration", cfg: func(c *Config) { c.ExportTimeout = -time.Second }, field: "ExportTimeout", wantIs: ErrInvalidDuration},
+		{name: "retry intervals", cfg: func(c *Config) { c.Retry.InitialInterval = time.Minute }, field: "Retry", wantIs: ErrInvalidRetry},
+		{name: "profile", cfg: func(c *Config) { c.Pipeline.Profile = "hft" }, field: "Pipeline.Profile", wantIs: ErrUnknownProfile},
+		{name: "batch above queue", cfg: func(c *Config) { c.Pipeline.MaxQueueSize, c.Pipeline.MaxExportBatchSize = 10, 20 }, field: "Pipeline.MaxExportBatchSize", wantIs: ErrInvalidPipeline},
+		{name: "tail ratio", cfg: func(c *Config) { c.TailSampling.Ratio = 2 }, field: "TailSampling.Ratio", wantIs: ErrInvalidSampleRatio},
+		{name: "log sampling", cfg: func(c *Config) { c.LogSampling.First = -1 }, field: "LogSampling", wantIs: ErrInvalidLogSampling},
+		{name: "routing policy", cfg: func(c *Config) { c.Routing.Policy = "random" }, field: "Routing.Policy", wantIs: ErrInvalidRouting},
+		{name: "empty endpoint", cfg: func(c *Config) { c.Routing.LogEndpoints = []string{"a:4317", ""} }, field: "Routing.LogEndpoints", wantIs: ErrInvalidRouting},
+		{name: "header name", cfg: func(c *Config) { c.Headers = map[string]string{"": "x"} }, field: "Headers", wantIs: ErrInvalidHeader},
+		{name: "log level", cfg: func(c *Config) { c.LogLevels = map[string]string{"oms": "loud"} }, field: "LogLevels[oms]", wantIs: ErrInvalidLogLevel},
+		{name: "sampler", cfg: func(c *Config) { c.Sampler = "coin" }, field: "Sampler", wantIs: ErrUnknownSampler},
+		{name: "sample ratio", cfg: func(c *Config) { c.Sampler, c.SampleRatio = "ratio", math.NaN() }, field: "SampleRatio", wantIs: ErrInvalidSampleRatio},
+		{name: "sampling rule", cfg: func(c *Config) { c.SamplingRules.Rules = []SamplingRule{{Kind: "queue"}} }, field: "SamplingRules", wantIs: ErrInvalidSampleRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ServiceName: "svc", Environment: "dev"}
+			tt.cfg(&cfg)
+			err := cfg.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var ce *ConfigError
+			if !errors.As(err, &ce) || ce.Field != tt.field || !errors.Is(err, tt.wantIs) {
+				t.Errorf("Validate() = %v, want a %s error wrapping %v", err, tt.field, tt.wantIs)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Config{Environment: "qa", Protocol: "smoke", Compression: "lz4", LogLevel: "loud"}
+	var fields []string
+	for _, err := range cfg.Validate().(interface{ Unwrap() []error }).Unwrap() {
+		var ce *ConfigError
+		if !errors.As(err, &ce) {
+			t.Fatalf("%v is not a *ConfigError", err)
+		}
+		fields = append(fields, ce.Field)
+	}
+	want := []string{"ServiceName", "Environment", "Protocol", "Compression", "LogLevel"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := New(Config{ServiceName: "svc", Environment: "qa"}); !errors.Is(err, ErrInvalidEnvironment) {
+		t.Errorf("New() = %v, want ErrInvalidEnvironment", err)
+	}
+	// Lenient validation logs the problem and builds the instance anyway.
+	o, err := New(Config{ServiceName: "svc", Environment: "qa", LenientValidation: true})
+	if err != nil {
+		t.Fatalf("lenient New() = %v", err)
+	}
+	_ = o.Shutdown(context.Background())
+}
+
+func TestLenientSamplingRules(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	tests := []struct {
+		name   string
+		cfg    func(*Config)
+		wantIs error
+	}{
+		{"invalid rule", func(c *Config) { c.SamplingRules.Rules = []SamplingRule{{Kind: "queue"}} }, ErrInvalidSampleRule},
+		{"unreadable rules file", func(c *Config) { c.SamplingRulesFile = missing }, os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ServiceName: "svc", Environment: "dev", Protocol: ProtocolNone, EnableTracing: true}
+			tt.cfg(&cfg)
+			if _, err := New(cfg); !errors.Is(err, tt.wantIs) {
+				t.Fatalf("New() = %v, want %v", err, tt.wantIs)
+			}
+
+			// Lenient mode warns and samples like the default sampler.
+			cfg.LenientValidation = true
+			out := captureStdout(t, func() {
+				s, err := newRootSampler(cfg)
+				if err != nil {
+					t.Fatalf("newRootSampler() = %v", err)
+				}
+				if want := sdktrace.TraceIDRatioBased(defaultSampleRatio).Description(); s.Description() != want {
+					t.Errorf("sampler = %s, want %s", s.Description(), want)
+				}
+				o, err := New(cfg)
+				if err != nil {
+					t.Fatalf("lenient New() = %v", err)
+				}
+				_ = o.Shutdown(context.Background())
+			})
+			if !strings.Contains(out, "using the default sampler") {
+				t.Errorf("no fallback warning in %s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	"go.opentelemetry.io/otel"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultWALMaxBytes       = 256 << 20
+	defaultWALReplayInterval = 5 * time.Second
+	walExt                   = ".wal"
+)
+
+// WALConfig enables a write-ahead log in front of every exporter. Batches
+// that fail to export are written to disk as OTLP protobuf and replayed in
+// order once the collector accepts data again, including after a restart.
+// A directory must not be shared by two running processes.
+type WALConfig struct {
+	Dir            string        // enables the WAL; one subdirectory per signal
+	MaxBytes       int64         // per-signal disk budget; oldest batches are dropped beyond it (default: 256 MiB)
+	ReplayInterval time.Duration // how often persisted batches are retried (default: 5s)
+}
+
+func (w WALConfig) enabled() bool { return w.Dir != "" }
+
+// WALStats describes one signal's on-disk queue.
+type WALStats struct {
+	Batches  int   // batches waiting on disk
+	Bytes    int64 // size of those batches
+	Dropped  int64 // batches discarded to stay under MaxBytes, or unreadable
+	Replayed int64 // persisted batches exported after a failure
+}
+
+// WALReport holds WALStats per signal. It is zero when the WAL is disabled.
+type WALReport struct {
+	Logs, Spans, Metrics WALStats
+}
+
+// WALStats returns the current depth and counters of the instance's WAL.
+func (o *Obs) WALStats() WALReport {
+	return WALReport{
+		Logs:    o.stats.logs.wal.stats(),
+		Spans:   o.stats.spans.wal.stats(),
+		Metrics: o.stats.metrics.wal.stats(),
+	}
+}
+
+// walQueue is a FIFO of encoded batches, one file per batch, named by a
+// zero-padded sequence number so directory order is replay order.
+type walQueue struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	seq     uint64
+	entries []walEntry // oldest first
+	bytes   int64
+
+	dropped  atomic.Int64
+	replayed atomic.Int64
+}
+
+type walEntry struct {
+	name string
+	size int64
+	n    int // items in the batch; 0 for batches left by a previous process
+}
+
+func openWALQueue(dir string, maxBytes int64) (*walQueue, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultWALMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("wal: %w", err)
+	}
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("wal: %w", err)
+	}
+	q := &walQueue{dir: dir, maxBytes: maxBytes}
+	for _, de := range des {
+		name := de.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(filepath.Join(dir, name)) // torn write from a crash
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walExt), 10, 64)
+		if err != nil || !strings.HasSuffix(name, walExt) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		q.entries = append(q.entries, walEntry{name: name, size: info.Size()})
+		q.bytes += info.Size()
+		q.seq = max(q.seq, seq)
+	}
+	sort.Slice(q.entries, func(i, j int) bool { return q.entries[i].name < q.entries[j].name })
+	return q, nil
+}
+
+// push appends b, a batch of n items, evicting the oldest batches if the
+// queue would exceed maxBytes. It returns the number of items evicted.
+func (q *walQueue) push(b []byte, n int) (evicted int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	name := fmt.Sprintf("%020d%s", q.seq, walExt)
+	path := filepath.Join(q.dir, name)
+	if err := os.WriteFile(path+".tmp", b, 0o600); err != nil {
+		return 0, fmt.Errorf("wal: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return 0, fmt.Errorf("wal: %w", err)
+	}
+	q.entries = append(q.entries, walEntry{name: name, size: int64(len(b)), n: n})
+	q.bytes += int64(len(b))
+	for q.bytes > q.maxBytes && len(q.entries) > 0 {
+		evicted += q.entries[0].n
+		q.removeLocked(q.entries[0])
+		q.dropped.Add(1)
+	}
+	return evicted, nil
+}
+
+// peek returns the oldest batch. ok is false when the queue is empty.
+func (q *walQueue) peek() (e walEntry, b []byte, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return walEntry{}, nil, false, nil
+	}
+	e = q.entries[0]
+	b, err = os.ReadFile(filepath.Join(q.dir, e.name))
+	return e, b, true, err
+}
+
+// remove deletes e if it is still queued (it may have been evicted meanwhile).
+func (q *walQueue) remove(e walEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeLocked(e)
+}
+
+func (q *walQueue) removeLocked(e walEntry) {
+	for i, cur := range q.entries {
+		if cur.name == e.name {
+			_ = os.Remove(filepath.Join(q.dir, e.name))
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			q.bytes -= e.size
+			return
+		}
+	}
+}
+
+func (q *walQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *walQueue) stats() WALStats {
+	if q == nil {
+		return WALStats{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return WALStats{
+		Batches:  len(q.entries),
+		Bytes:    q.bytes,
+		Dropped:  q.dropped.Load(),
+		Replayed: q.replayed.Load(),
+	}
+}
+
+// walCore implements the persist-and-replay logic shared by the signal
+// wrappers; T is the batch type handed to the wrapped exporter.
+//
+// It records export outcomes in stats at the WAL boundary: a persisted batch
+// is pending rather than failed, and counts as exported once replayed. Items
+// only fail when they cannot be persisted or are evicted from disk.
+type walCore[T any] struct {
+	q      *walQueue
+	stats  *exportStats
+	count  func(T) int // items in a batch, as counted by stats
+	encode func(T) ([]byte, error)
+	decode func([]byte) (T, error)
+	export func(context.Context, T) error
+
+	sendMu sync.Mutex // keeps direct exports and replays in order
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newWALCore[T any](cfg WALConfig, signal string, stats *exportStats, count func(T) int, encode func(T) ([]byte, error), decode func([]byte) (T, error), export func(context.Context, T) error) (*walCore[T], error) {
+	q, err := openWALQueue(filepath.Join(cfg.Dir, signal), cfg.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.ReplayInterval
+	if interval <= 0 {
+		interval = defaultWALReplayInterval
+	}
+	w := &walCore[T]{
+		q:      q,
+		stats:  stats,
+		count:  count,
+		encode: encode,
+		decode: decode,
+		export: export,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.replayLoop(interval)
+	return w, nil
+}
+
+// exportBatch sends batch directly while the queue is empty; otherwise, or
+// if the send fails, it is appended to the WAL to keep export order.
+func (w *walCore[T]) exportBatch(ctx context.Context, batch T) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	n := w.count(batch)
+	var sendErr error
+	if w.q.len() == 0 {
+		start := time.Now()
+		sendErr = w.export(ctx, batch)
+		w.stats.recordAttempt(sendErr, time.Since(start))
+		if sendErr == nil {
+			w.stats.exported.Add(int64(n))
+			return nil
+		}
+	}
+	b, err := w.encode(batch)
+	var evicted int
+	if err == nil {
+		evicted, err = w.q.push(b, n)
+	}
+	if err != nil {
+		w.stats.failed.Add(int64(n))
+		return errors.Join(sendErr, err)
+	}
+	w.stats.persisted.Add(int64(n - evicted))
+	w.stats.failed.Add(int64(evicted))
+	if sendErr != nil {
+		otel.Handle(fmt.Errorf("wal: export failed, batch persisted for replay: %w", sendErr))
+	}
+	return nil
+}
+
+// drain replays persisted batches oldest first until the queue is empty or
+// an export fails.
+func (w *walCore[T]) drain(ctx context.Context) error {
+	for {
+		if err := w.replayOne(ctx); err != nil || w.q.len() == 0 {
+			return err
+		}
+	}
+}
+
+func (w *walCore[T]) replayOne(ctx context.Context) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	e, b, ok, err := w.q.peek()
+	if !ok {
+		return nil
+	}
+	var batch T
+	if err == nil {
+		batch, err = w.decode(b)
+	}
+	if err != nil {
+		// Unreadable entries would block the queue forever.
+		w.unqueue(e, &w.stats.failed)
+		w.q.dropped.Add(1)
+		otel.Handle(fmt.Errorf("wal: dropping unreadable batch %s: %w", e.name, err))
+		return nil
+	}
+	start := time.Now()
+	err = w.export(ctx, batch)
+	w.stats.recordAttempt(err, time.Since(start))
+	if err != nil {
+		return err
+	}
+	w.unqueue(e, &w.stats.exported)
+	w.q.replayed.Add(1)
+	return nil
+}
+
+// unqueue removes e from the WAL and moves its items from persisted to to.
+// Batches left by a previous process were never counted here and carry no
+// items.
+func (w *walCore[T]) unqueue(e walEntry, to *atomic.Int64) {
+	w.q.remove(e)
+	w.stats.persisted.Add(-int64(e.n))
+	to.Add(int64(e.n))
+}
+
+func (w *walCore[T]) replayLoop(interval time.Duration) {
+	defer close(w.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+			_ = w.drain(ctx)
+		}
+	}
+}
+
+// close stops the replay loop and makes a last drain attempt; whatever is
+// left stays on disk for the next process.
+func (w *walCore[T]) close(ctx context.Context) error {
+	select {
+	case <-w.stop:
+		return nil
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	if err := w.drain(ctx); err != nil {
+		return fmt.Errorf("wal: %d batches left on disk: %w", w.q.len(), err)
+	}
+	return nil
+}
+
+// walSpanExporter persists span batches the wrapped exporter fails to send.
+type walSpanExporter struct {
+	sdktrace.SpanExporter
+	core *walCore[[]sdktrace.ReadOnlySpan]
+}
+
+func newWALSpanExporter(exp sdktrace.SpanExporter, cfg WALConfig, stats *exportStats) (*walSpanExporter, error) {
+	core, err := newWALCore(cfg, "traces", stats,
+		func(spans []sdktrace.ReadOnlySpan) int { return len(spans) },
+		func(spans []sdktrace.ReadOnlySpan) ([]byte, error) {
+			return proto.Marshal(&collectortracepb.ExportTraceServiceRequest{ResourceSpans: otlpconv.SpansToProto(spans)})
+		},
+		func(b []byte) ([]sdktrace.ReadOnlySpan, error) {
+			var req collectortracepb.ExportTraceServiceRequest
+			if err := proto.Unmarshal(b, &req); err != nil {
+				return nil, err
+			}
+			return otlpconv.SpansFromProto(req.GetResourceSpans()), nil
+		},
+		exp.ExportSpans,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &walSpanExporter{SpanExporter: exp, core: core}, nil
+}
+
+func (e *walSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return e.core.exportBatch(ctx, spans)
+}
+
+func (e *walSpanExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.core.close(ctx), e.SpanExporter.Shutdown(ctx))
+}
+
+// walMetricExporter persists metric exports the wrapped exporter fails to send.
+type walMetricExporter struct {
+	sdkmetric.Exporter
+	core *walCore[*metricdata.ResourceMetrics]
+}
+
+func newWALMetricExporter(exp sdkmetric.Exporter, cfg WALConfig, stats *exportStats) (*walMetricExporter, error) {
+	core, err := newWALCore(cfg, "metrics", stats, dataPoints,
+		func(rm *metricdata.ResourceMetrics) ([]byte, error) {
+			return proto.Marshal(&collectormetricspb.ExportMetricsServiceRequest{
+				ResourceMetrics: []*metricspb.ResourceMetrics{otlpconv.MetricsToProto(rm)},
+			})
+		},
+		func(b []byte) (*metricdata.ResourceMetrics, error) {
+			var req collectormetricspb.ExportMetricsServiceRequest
+			if err := proto.Unmarshal(b, &req); err != nil {
+				return nil, err
+			}
+			if len(req.GetResourceMetrics()) != 1 {
+				return nil, fmt.Errorf("expected 1 resource, got %d", len(req.GetResourceMetrics()))
+			}
+			return otlpconv.MetricsFromProto(req.GetResourceMetrics()[0]), nil
+		},
+		exp.Export,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &walMetricExporter{Exporter: exp, core: core}, nil
+}
+
+func (e *walMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.core.exportBatch(ctx, rm)
+}
+
+func (e *walMetricExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.core.drain(ctx), e.Exporter.ForceFlush(ctx))
+}
+
+func (e *walMetricExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.core.close(ctx), e.Exporter.Shutdown(ctx))
+}
+
+// walLogExporter persists log batches the wrapped exporter fails to send.
+type walLogExporter struct {
+	sdklog.Exporter
+	core *walCore[[]sdklog.Record]
+}
+
+func newWALLogExporter(exp sdklog.Exporter, cfg WALConfig, stats *exportStats) (*walLogExporter, error) {
+	core, err := newWALCore(cfg, "logs", stats,
+		func(records []sdklog.Record) int { return len(records) },
+		func(records []sdklog.Record) ([]byte, error) {
+			return proto.Marshal(&collectorlogspb.ExportLogsServiceRequest{ResourceLogs: logsToProto(records)})
+		},
+		func(b []byte) ([]sdklog.Record, error) {
+			var req collectorlogspb.ExportLogsServiceRequest
+			if err := proto.Unmarshal(b, &req); err != nil {
+				return nil, err
+			}
+			return logsFromProto(req.GetResourceLogs()), nil
+		},
+		exp.Export,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &walLogExporter{Exporter: exp, core: core}, nil
+}
+
+func (e *walLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return e.core.exportBatch(ctx, records)
+}
+
+func (e *walLogExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.core.drain(ctx), e.Exporter.ForceFlush(ctx))
+}
+
+func (e *walLogExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.core.close(ctx), e.Exporter.Shutdown(ctx))
+}
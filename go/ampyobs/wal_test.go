@@ -0,0 +1,246 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var errCollectorDown = errors.New("collector down")
+
+// flakyExporter records the batches it accepts and fails while down is set.
+type flakyExporter[T any] struct {
+	mu   sync.Mutex
+	down bool
+	got  []T
+}
+
+func (e *flakyExporter[T]) setDown(down bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.down = down
+}
+
+func (e *flakyExporter[T]) export(_ context.Context, batch T) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.down {
+		return errCollectorDown
+	}
+	e.got = append(e.got, batch)
+	return nil
+}
+
+func (e *flakyExporter[T]) exported() []T {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]T(nil), e.got...)
+}
+
+// newStringWAL returns a WAL of string batches counting one item per byte.
+// Batches starting with "bad" cannot be decoded.
+func newStringWAL(t *testing.T, cfg WALConfig, stats *exportStats, exp *flakyExporter[string]) *walCore[string] {
+	t.Helper()
+	cfg.ReplayInterval = time.Hour // replays are driven by the test
+	w, err := newWALCore(cfg, "strings", stats,
+		func(s string) int { return len(s) },
+		func(s string) ([]byte, error) { return []byte(s), nil },
+		func(b []byte) (string, error) {
+			if strings.HasPrefix(string(b), "bad") {
+				return "", errors.New("corrupt batch")
+			}
+			return string(b), nil
+		},
+		exp.export,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestWALExportAndReplay(t *testing.T) {
+	type step struct {
+		batch string
+		down  bool
+	}
+	tests := []struct {
+		name     string
+		maxBytes int64
+		steps    []step
+		drainUp  bool // bring the collector back and drain at the end
+		want     []string
+		report   SignalReport
+		wal      WALStats
+	}{
+		{
+			name:   "collector up",
+			steps:  []step{{batch: "aa"}, {batch: "b"}},
+			want:   []string{"aa", "b"},
+			report: SignalReport{Exported: 3},
+		},
+		{
+			name:   "outage is persisted, not failed",
+			steps:  []step{{batch: "aa", down: true}, {batch: "bbb", down: true}},
+			report: SignalReport{Persisted: 5},
+			wal:    WALStats{Batches: 2, Bytes: 5},
+		},
+		{
+			name:    "replay exports in order",
+			steps:   []step{{batch: "aa", down: true}, {batch: "bbb", down: true}},
+			drainUp: true,
+			want:    []string{"aa", "bbb"},
+			report:  SignalReport{Exported: 5},
+			wal:     WALStats{Replayed: 2},
+		},
+		{
+			name:    "queued batches keep their place after recovery",
+			steps:   []step{{batch: "a", down: true}, {batch: "b"}},
+			drainUp: true,
+			want:    []string{"a", "b"},
+			report:  SignalReport{Exported: 2},
+			wal:     WALStats{Replayed: 2},
+		},
+		{
+			name:     "eviction beyond MaxBytes fails the oldest batch",
+			maxBytes: 4,
+			steps:    []step{{batch: "aaa", down: true}, {batch: "bb", down: true}},
+			report:   SignalReport{Failed: 3, Persisted: 2},
+			wal:      WALStats{Batches: 1, Bytes: 2, Dropped: 1},
+		},
+		{
+			name:    "unreadable batch is dropped",
+			steps:   []step{{batch: "bad", down: true}, {batch: "ok", down: true}},
+			drainUp: true,
+			want:    []string{"ok"},
+			report:  SignalReport{Exported: 2, Failed: 3},
+			wal:     WALStats{Dropped: 1, Replayed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats exportStats
+			exp := &flakyExporter[string]{}
+			w := newStringWAL(t, WALConfig{Dir: t.TempDir(), MaxBytes: tt.maxBytes}, &stats, exp)
+			for _, s := range tt.steps {
+				stats.queued.Add(int64(len(s.batch)))
+				exp.setDown(s.down)
+				if err := w.exportBatch(context.Background(), s.batch); err != nil {
+					t.Fatalf("exportBatch(%q): %v", s.batch, err)
+				}
+			}
+			if tt.drainUp {
+				exp.setDown(false)
+				if err := w.drain(context.Background()); err != nil {
+					t.Fatalf("drain: %v", err)
+				}
+			}
+			if got := exp.exported(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exported %q, want %q", got, tt.want)
+			}
+			if got := stats.report(); got != tt.report {
+				t.Errorf("report() = %+v, want %+v", got, tt.report)
+			}
+			if got := w.q.stats(); got != tt.wal {
+				t.Errorf("WAL stats = %+v, want %+v", got, tt.wal)
+			}
+			// close reports batches it could not drain.
+			if err := w.close(context.Background()); (err != nil) != (tt.wal.Batches > 0) {
+				t.Errorf("close() = %v with %d batches left", err, tt.wal.Batches)
+			}
+		})
+	}
+}
+
+func TestWALSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	exp := &flakyExporter[string]{down: true}
+
+	var before exportStats
+	w := newStringWAL(t, WALConfig{Dir: dir}, &before, exp)
+	for _, b := range []string{"one", "two"} {
+		if err := w.exportBatch(context.Background(), b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(context.Background()); !errors.Is(err, errCollectorDown) {
+		t.Fatalf("close() = %v, want the export error", err)
+	}
+	if got := before.report().Persisted; got != 6 {
+		t.Errorf("persisted %d items, want 6", got)
+	}
+
+	// A crash mid-write leaves a .tmp file; unrelated files are ignored.
+	sub := filepath.Join(dir, "strings")
+	for _, name := range []string{"00000000000000000099.wal.tmp", "README"} {
+		if err := os.WriteFile(filepath.Join(sub, name), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var after exportStats
+	w = newStringWAL(t, WALConfig{Dir: dir}, &after, exp)
+	if got := w.q.stats(); got.Batches != 2 || got.Bytes != 6 {
+		t.Errorf("reopened WAL = %+v, want 2 batches of 6 bytes", got)
+	}
+	if _, err := os.Stat(filepath.Join(sub, "00000000000000000099.wal.tmp")); !os.IsNotExist(err) {
+		t.Errorf("torn write left behind: %v", err)
+	}
+	// New batches queue behind the old ones.
+	if err := w.exportBatch(context.Background(), "three"); err != nil {
+		t.Fatal(err)
+	}
+	exp.setDown(false)
+	if err := w.close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got, want := exp.exported(), []string{"one", "two", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %q, want %q", got, want)
+	}
+	// Only this process's items count; the previous run's were reported there.
+	if got, want := after.report(), (SignalReport{Exported: 5}); got != want {
+		t.Errorf("report() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWALSpanRoundTrip(t *testing.T) {
+	exp := &flakyExporter[[]sdktrace.ReadOnlySpan]{down: true}
+	var stats exportStats
+	w, err := newWALSpanExporter(spanExporterFunc(exp.export), WALConfig{Dir: t.TempDir(), ReplayInterval: time.Hour}, &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := tracetest.SpanStubs{{Name: "submit"}, {Name: "fill"}}.Snapshots()
+	if err := w.ExportSpans(context.Background(), spans); err != nil {
+		t.Fatal(err)
+	}
+	exp.setDown(false)
+	if err := w.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := exp.exported()
+	if len(got) != 1 || len(got[0]) != 2 || got[0][0].Name() != "submit" || got[0][1].Name() != "fill" {
+		t.Fatalf("replayed %v", got)
+	}
+	if got, want := stats.report(), (SignalReport{Exported: 2}); got != want {
+		t.Errorf("report() = %+v, want %+v", got, want)
+	}
+}
+
+// spanExporterFunc adapts a function to sdktrace.SpanExporter.
+type spanExporterFunc func(context.Context, []sdktrace.ReadOnlySpan) error
+
+func (f spanExporterFunc) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return f(ctx, spans)
+}
+
+func (f spanExporterFunc) Shutdown(context.Context) error { return nil }
@@ -23,7 +23,7 @@ func main() {
 		ServiceVersion:    "0.1.0",
 		Environment:       "dev",
 		CollectorEndpoint: "localhost:4318", // Use HTTP port
-		TraceProtocol:     "http",           // Use HTTP exporter
+		Protocol:          "http/protobuf",  // Use HTTP exporters for traces and metrics
 		EnableLogs:        true,
 		EnableMetrics:     true,
 		EnableTracing:     true,
@@ -53,16 +53,15 @@ func main() {
 	}
 
 	ctx, span := ampyobs.StartBusConsumeSpan(ctx, headers, attrs)
-	defer span.End()
 
 	ampyobs.C(ctx).Info("consumed signal",
 		slog.String("event", "signals.consume"),
 		slog.String("action", "forward_to_oms"),
 	)
 
-	time.Sleep(1 * time.Second)
+	span.End()
 
-	// Properly shutdown to flush traces
+	// Shutdown flushes logs, traces and metrics before returning
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := ampyobs.Shutdown(shutdownCtx); err != nil {
@@ -23,7 +23,7 @@ func main() {
 		ServiceVersion:    "0.1.0",
 		Environment:       "dev",
 		CollectorEndpoint: "localhost:4318", // Use HTTP port
-		TraceProtocol:     "http",           // Use HTTP exporter
+		Protocol:          "http/protobuf",  // Use HTTP exporters for traces and metrics
 		EnableLogs:        true,
 		EnableMetrics:     true,
 		EnableTracing:     true,
@@ -46,7 +46,6 @@ func main() {
 	}
 
 	ctx, span := ampyobs.StartBusPublishSpan(ctx, attrs)
-	defer span.End()
 
 	ampyobs.C(ctx).Info("publishing signal",
 		slog.String("event", "signals.emit"),
@@ -60,9 +59,9 @@ func main() {
 	_ = os.WriteFile("bus_headers.json", data, 0o644)
 	fmt.Println("Wrote bus_headers.json with headers:", headers)
 
-	time.Sleep(1 * time.Second)
+	span.End()
 
-	// Properly shutdown to flush traces
+	// Shutdown flushes logs, traces and metrics before returning
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := ampyobs.Shutdown(shutdownCtx); err != nil {
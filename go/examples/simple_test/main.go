@@ -22,9 +22,13 @@ func main() {
 	})
 
 	ctx := context.Background()
-	ctx, span := ampyobs.StartSpan(ctx, "test-operation", 1) // 1 = SPAN_KIND_INTERNAL
-	defer span.End()
+	_, span := ampyobs.StartSpan(ctx, "test-operation", 1) // 1 = SPAN_KIND_INTERNAL
+	span.End()
 
-	fmt.Println("Created span, waiting for export...")
-	time.Sleep(1 * time.Second)
+	fmt.Println("Created span, flushing...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ampyobs.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("Shutdown error: %v\n", err)
+	}
 }
@@ -0,0 +1,135 @@
+// Package debugtarget holds the debug targets of both Go SDKs (go/ampyobs
+// and sdk/go/ampyobs): flows forced to full sampling and debug logging,
+// matched by key and value, and the baggage member that marks such flows
+// across services.
+package debugtarget
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Baggage is the baggage member marking a flow for debugging.
+const Baggage = "ampy.debug"
+
+// Attr marks spans sampled because their flow is being debugged, so tail
+// sampling keeps them too.
+var Attr = attribute.Bool(Baggage, true)
+
+// WithBaggage marks the flow of ctx for debugging via baggage.
+func WithBaggage(ctx context.Context) context.Context {
+	m, err := baggage.NewMemberRaw(Baggage, "1")
+	if err != nil {
+		return ctx
+	}
+	b, err := baggage.FromContext(ctx).SetMember(m)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, b)
+}
+
+// HasBaggage reports whether ctx carries the Baggage member.
+func HasBaggage(ctx context.Context) bool {
+	switch strings.ToLower(baggage.FromContext(ctx).Member(Baggage).Value()) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// Target is a debugged flow; the SDKs convert it to their DebugTarget.
+type Target struct {
+	Key     string
+	Value   string
+	Expires time.Time // zero: until removed
+}
+
+type key struct{ key, value string }
+
+// Set is the set of debug targets of an SDK instance with their expiry.
+// Expired targets are dropped lazily. The zero value is an empty set.
+type Set struct {
+	n       atomic.Int64 // len(targets), so flows are matched lock-free when empty
+	mu      sync.RWMutex
+	targets map[key]time.Time
+}
+
+// Add adds a target for ttl (0 keeps it until Remove). Adding an existing
+// target resets its expiry.
+func (s *Set) Add(k, value string, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.targets == nil {
+		s.targets = make(map[key]time.Time)
+	}
+	s.dropExpired()
+	s.targets[key{k, value}] = expires
+	s.n.Store(int64(len(s.targets)))
+}
+
+// Remove removes a target.
+func (s *Set) Remove(k, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.targets, key{k, value})
+	s.n.Store(int64(len(s.targets)))
+}
+
+// dropExpired removes expired targets. Callers hold s.mu.
+func (s *Set) dropExpired() {
+	now := time.Now()
+	for k, exp := range s.targets {
+		if !exp.IsZero() && now.After(exp) {
+			delete(s.targets, k)
+		}
+	}
+}
+
+// List returns the targets that have not expired, by key and value.
+func (s *Set) List() []Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpired()
+	s.n.Store(int64(len(s.targets)))
+	out := make([]Target, 0, len(s.targets))
+	for k, exp := range s.targets {
+		out = append(out, Target{Key: k.key, Value: k.value, Expires: exp})
+	}
+	slices.SortFunc(out, func(a, b Target) int {
+		return strings.Compare(a.Key+"="+a.Value, b.Key+"="+b.Value)
+	})
+	return out
+}
+
+// Empty reports whether the set has no targets, without locking.
+func (s *Set) Empty() bool {
+	return s.n.Load() == 0
+}
+
+// MatchAny reports whether any of attrs is a target that has not expired.
+func (s *Set) MatchAny(attrs []attribute.KeyValue) bool {
+	if len(attrs) == 0 || s.Empty() {
+		return false
+	}
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, kv := range attrs {
+		if exp, ok := s.targets[key{string(kv.Key), kv.Value.Emit()}]; ok && (exp.IsZero() || !now.After(exp)) {
+			return true
+		}
+	}
+	return false
+}
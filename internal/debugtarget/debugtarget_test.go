@@ -0,0 +1,64 @@
+package debugtarget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestSet(t *testing.T) {
+	var s Set
+	if s.MatchAny([]attribute.KeyValue{attribute.String("run_id", "bt-1")}) || len(s.List()) != 0 {
+		t.Fatal("zero Set is not empty")
+	}
+	s.Add("run_id", "bt-1", 0)
+	s.Add("client_order_id", "co-9", time.Hour)
+	s.Add("symbol", "AAPL", time.Hour)
+	s.Remove("symbol", "AAPL")
+
+	// An expired target is neither listed nor matched.
+	s.Add("mic", "XNAS", time.Hour)
+	s.mu.Lock()
+	s.targets[key{"mic", "XNAS"}] = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+	if s.MatchAny([]attribute.KeyValue{attribute.String("mic", "XNAS")}) {
+		t.Error("expired target matched")
+	}
+	if !s.MatchAny([]attribute.KeyValue{attribute.String("symbol", "MSFT"), attribute.String("client_order_id", "co-9")}) {
+		t.Error("target not matched")
+	}
+
+	got := s.List()
+	if len(got) != 2 || got[0].Key != "client_order_id" || got[0].Expires.IsZero() || got[1].Key != "run_id" || !got[1].Expires.IsZero() {
+		t.Errorf("List() = %+v", got)
+	}
+	if s.n.Load() != 2 {
+		t.Errorf("n = %d after dropping the expired target, want 2", s.n.Load())
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	withMember := func(v string) context.Context {
+		m, _ := baggage.NewMemberRaw(Baggage, v)
+		b, _ := baggage.New(m)
+		return baggage.ContextWithBaggage(context.Background(), b)
+	}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"plain", context.Background(), false},
+		{"WithBaggage", WithBaggage(context.Background()), true},
+		{"true", withMember("TRUE"), true},
+		{"0", withMember("0"), false},
+	}
+	for _, tt := range tests {
+		if got := HasBaggage(tt.ctx); got != tt.want {
+			t.Errorf("%s: HasBaggage = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package loglevels keeps the per-logger log levels of both Go SDKs
+// (go/ampyobs and sdk/go/ampyobs): the dotted name hierarchy, temporary
+// levels that revert after a TTL, and the HTTP handler that reads and
+// changes them. Levels are ints; each SDK converts its own level type
+// (slog.Level, zapcore.Level) and supplies how levels parse and print.
+package loglevels
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is the level of one logger as reported by List.
+type Entry struct {
+	Logger   string     // "" for the root logger
+	Level    int        // the SDK level as an int
+	RevertAt *time.Time // set while a temporary level applies
+}
+
+// entry is the level of one logger. base is the level a temporary one
+// reverts to; nil means the entry goes away and the name follows its parent.
+type entry struct {
+	level    atomic.Int64
+	base     *int
+	timer    *time.Timer
+	revertAt time.Time
+}
+
+// Levels holds the root and named levels of an SDK instance.
+type Levels struct {
+	configured map[string]int
+
+	mu    sync.RWMutex
+	root  *entry
+	named map[string]*entry
+}
+
+// New returns levels starting at configured, keyed by logger name with the
+// root under "" (default: 0).
+func New(configured map[string]int) *Levels {
+	l := &Levels{configured: configured, named: make(map[string]*entry)}
+	l.root = newEntry(configured[""])
+	for name, level := range configured {
+		if name != "" {
+			l.named[name] = newEntry(level)
+		}
+	}
+	return l
+}
+
+func newEntry(level int) *entry {
+	e := &entry{base: &level}
+	e.level.Store(int64(level))
+	return e
+}
+
+// Level returns the level in effect for name: its own, else that of its
+// closest dotted parent ("md" for "md.ingest"), else the root's.
+func (l *Levels) Level(name string) int {
+	if name == "" {
+		return int(l.root.level.Load())
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name != "" {
+		if e, ok := l.named[name]; ok {
+			return int(e.level.Load())
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return int(l.root.level.Load())
+}
+
+// Set sets the level of name ("" for the root). With a positive ttl the
+// previous level comes back once it elapses.
+func (l *Levels) Set(name string, level int, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.root
+	if name != "" {
+		if e = l.named[name]; e == nil {
+			e = &entry{}
+			l.named[name] = e
+		}
+	}
+	e.stopTimer()
+	e.level.Store(int64(level))
+	if ttl <= 0 {
+		e.base = &level
+		return
+	}
+	e.revertAt = time.Now().Add(ttl)
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if e.timer != timer {
+			return // superseded
+		}
+		e.timer, e.revertAt = nil, time.Time{}
+		l.restore(name, e)
+	})
+	e.timer = timer
+}
+
+// restore puts e back to its base level. Callers hold l.mu.
+func (l *Levels) restore(name string, e *entry) {
+	if e.base == nil {
+		delete(l.named, name)
+		return
+	}
+	e.level.Store(int64(*e.base))
+}
+
+// Reset restores the configured level of name; a name without one follows
+// its parent again.
+func (l *Levels) Reset(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := l.root
+	if name != "" {
+		if e = l.named[name]; e == nil {
+			return
+		}
+	}
+	e.stopTimer()
+	if level, ok := l.configured[name]; ok || name == "" {
+		e.base = &level
+	} else {
+		e.base = nil
+	}
+	l.restore(name, e)
+}
+
+func (e *entry) stopTimer() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer, e.revertAt = nil, time.Time{}
+	}
+}
+
+// List returns the root level followed by every named level, by name.
+func (l *Levels) List() []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := []Entry{l.root.info("")}
+	for _, name := range slices.Sorted(maps.Keys(l.named)) {
+		out = append(out, l.named[name].info(name))
+	}
+	return out
+}
+
+func (e *entry) info(name string) Entry {
+	ll := Entry{Logger: name, Level: int(e.level.Load())}
+	if !e.revertAt.IsZero() {
+		at := e.revertAt
+		ll.RevertAt = &at
+	}
+	return ll
+}
+
+// Handler reads and changes the levels over HTTP:
+//
+//	GET                                       lists the levels as JSON
+//	PUT|POST ?logger=oms&level=debug&ttl=15m  sets a level (logger and ttl optional)
+//	DELETE   ?logger=oms                      restores the configured level
+//
+// Parameters may also be sent as a form body. Every call responds with the
+// levels after the change, each printed with format.
+func (l *Levels) Handler(parse func(string) (int, error), format func(int) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.FormValue("logger")
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut, http.MethodPost:
+			level, err := parse(r.FormValue("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var ttl time.Duration
+			if v := r.FormValue("ttl"); v != "" {
+				d, err := time.ParseDuration(v)
+				if err != nil || d < 0 {
+					http.Error(w, "invalid ttl "+v, http.StatusBadRequest)
+					return
+				}
+				ttl = d
+			}
+			l.Set(name, level, ttl)
+		case http.MethodDelete:
+			l.Reset(name)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		type jsonEntry struct {
+			Logger   string     `json:"logger"`
+			Level    string     `json:"level"`
+			RevertAt *time.Time `json:"revert_at,omitempty"`
+		}
+		entries := l.List()
+		out := make([]jsonEntry, len(entries))
+		for i, e := range entries {
+			out[i] = jsonEntry{Logger: e.Logger, Level: format(e.Level), RevertAt: e.RevertAt}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+}
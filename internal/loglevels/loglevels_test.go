@@ -0,0 +1,103 @@
+package loglevels
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLevels(t *testing.T) {
+	l := New(map[string]int{"": 4, "md": 8})
+	l.Set("md.ingest", -4, 0)
+
+	tests := map[string]int{"": 4, "md": 8, "md.bars": 8, "md.ingest": -4, "md.ingest.kafka": -4, "mdx": 4}
+	for name, want := range tests {
+		if got := l.Level(name); got != want {
+			t.Errorf("Level(%q) = %d, want %d", name, got, want)
+		}
+	}
+
+	l.Set("md", 0, 0)
+	l.Reset("md")
+	l.Reset("md.ingest")
+	l.Set("", -4, 0)
+	l.Reset("")
+	want := []Entry{{Logger: "", Level: 4}, {Logger: "md", Level: 8}}
+	if got := l.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after reset List() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLevelsTTL(t *testing.T) {
+	l := New(map[string]int{"md": 4})
+	wait := func(name string, want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for l.Level(name) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Level(%q) = %d, want %d", name, l.Level(name), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// A name without a configured level goes away; a configured one and the
+	// root go back to their previous level.
+	l.Set("md.ingest", -4, 20*time.Millisecond)
+	l.Set("md", -4, 20*time.Millisecond)
+	l.Set("", 8, 20*time.Millisecond)
+	if got := l.List(); len(got) != 3 || got[2].RevertAt == nil {
+		t.Errorf("List() = %+v, want md.ingest with RevertAt", got)
+	}
+	wait("md.ingest", 4)
+	wait("", 0)
+	if got := l.List(); len(got) != 2 || got[0].RevertAt != nil || got[1].RevertAt != nil {
+		t.Errorf("List() after revert = %+v", got)
+	}
+
+	// A later permanent level cancels the pending revert.
+	l.Set("oms", -4, 20*time.Millisecond)
+	l.Set("oms", 4, 0)
+	time.Sleep(60 * time.Millisecond)
+	if got := l.Level("oms"); got != 4 {
+		t.Errorf("oms = %d after a superseded ttl, want 4", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := New(map[string]int{"": 1}).Handler(strconv.Atoi, func(l int) string { return "L" + strconv.Itoa(l) })
+	tests := []struct {
+		method, query string
+		wantCode      int
+		want          []map[string]any
+	}{
+		{http.MethodGet, "", http.StatusOK, []map[string]any{{"logger": "", "level": "L1"}}},
+		{http.MethodPut, "logger=oms&level=2", http.StatusOK, []map[string]any{{"logger": "", "level": "L1"}, {"logger": "oms", "level": "L2"}}},
+		{http.MethodDelete, "logger=oms", http.StatusOK, []map[string]any{{"logger": "", "level": "L1"}}},
+		{http.MethodPut, "level=loud", http.StatusBadRequest, nil},
+		{http.MethodPut, "level=3&ttl=-1s", http.StatusBadRequest, nil},
+		{http.MethodPatch, "", http.StatusMethodNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/loglevels?"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s ?%s: status = %d, want %d", tt.method, tt.query, rec.Code, tt.wantCode)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		var got []map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s ?%s: %v", tt.method, tt.query, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s ?%s: body = %v, want %v", tt.method, tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package logsample is the level-agnostic core of log sampling in both Go
+// SDKs (go/ampyobs and sdk/go/ampyobs): per-message counting windows, the
+// first-N and every-M rules, the rate limit across messages and the
+// periodic summaries of what was suppressed. Levels are ints; each SDK
+// adapts its own level type and writes the summaries to its logger.
+package logsample
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/ratelimit"
+)
+
+// Config mirrors the SDKs' LogSamplingConfig; zero values take the
+// defaults noted.
+type Config struct {
+	Interval        time.Duration    // counting window per message (default: 1s)
+	First           int              // records per message and window always logged (default: 100)
+	Thereafter      int              // then every Thereafter-th is logged (0: none)
+	RateLimit       float64          // records per second across all messages, after sampling (0: unlimited)
+	SummaryInterval time.Duration    // how often report is called, in wall time (default: 1m)
+	Now             func() time.Time // clock of the windows and the rate limit (default: time.Now)
+}
+
+// Suppression reasons, reported with every suppressed record.
+const (
+	ReasonSampling  = "sampling"
+	ReasonRateLimit = "ratelimit"
+)
+
+// MaxKeys bounds the messages tracked per window and per summary; further
+// messages share one entry with an empty message.
+const MaxKeys = 10000
+
+// Summary is the count of one message suppressed since the last summary.
+type Summary struct {
+	Level      int
+	Message    string // "" for messages beyond MaxKeys
+	Suppressed int64
+}
+
+// Text is the summary's log message, e.g.
+//
+//	suppressed 12,345 occurrences of "broker disconnected"
+func (s Summary) Text() string {
+	what := strconv.Quote(s.Message)
+	if s.Message == "" {
+		what = "other messages"
+	}
+	return fmt.Sprintf("suppressed %s occurrences of %s", GroupDigits(s.Suppressed), what)
+}
+
+type key struct {
+	level int
+	msg   string
+}
+
+// Sampler decides which records of a flood are logged. Suppressed records
+// are passed to onSuppress as they happen and to report, one Summary per
+// message, every SummaryInterval.
+type Sampler struct {
+	interval   time.Duration
+	first      int
+	thereafter int
+	now        func() time.Time
+	limit      *ratelimit.Limiter // nil when RateLimit is unset
+	report     func(Summary)
+	onSuppress func(level int, reason string)
+
+	mu         sync.Mutex
+	windowEnd  time.Time
+	counts     map[key]int
+	suppressed map[key]int64 // since the last summary
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// New starts a sampler; Shutdown stops it. onSuppress may be nil and is
+// called with the sampler's lock held, so it must not log through it.
+func New(cfg Config, report func(Summary), onSuppress func(level int, reason string)) *Sampler {
+	s := &Sampler{
+		interval:   cfg.Interval,
+		first:      cfg.First,
+		thereafter: cfg.Thereafter,
+		now:        cfg.Now,
+		report:     report,
+		onSuppress: onSuppress,
+		counts:     make(map[key]int),
+		suppressed: make(map[key]int64),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if s.interval <= 0 {
+		s.interval = time.Second
+	}
+	if s.first <= 0 {
+		s.first = 100
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+	if cfg.RateLimit > 0 {
+		s.limit = ratelimit.New(cfg.RateLimit, 0, s.now())
+	}
+	every := cfg.SummaryInterval
+	if every <= 0 {
+		every = time.Minute
+	}
+	go s.run(every)
+	return s
+}
+
+// Allow reports whether a record is logged, counting it otherwise.
+func (s *Sampler) Allow(level int, msg string) bool {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.windowEnd) {
+		clear(s.counts)
+		s.windowEnd = now.Add(s.interval)
+	}
+	k := key{level, msg}
+	if _, ok := s.counts[k]; !ok && len(s.counts) >= MaxKeys {
+		k.msg = ""
+	}
+	n := s.counts[k] + 1
+	s.counts[k] = n
+
+	reason := ""
+	switch {
+	case n > s.first && (s.thereafter <= 0 || (n-s.first)%s.thereafter != 0):
+		reason = ReasonSampling
+	case s.limit != nil && !s.limit.Allow(now):
+		reason = ReasonRateLimit
+	default:
+		return true
+	}
+	if _, ok := s.suppressed[k]; !ok && len(s.suppressed) >= MaxKeys {
+		k.msg = ""
+	}
+	s.suppressed[k]++
+	if s.onSuppress != nil {
+		s.onSuppress(level, reason)
+	}
+	return false
+}
+
+func (s *Sampler) run(every time.Duration) {
+	defer close(s.done)
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.Summarize()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Summarize reports every message suppressed since the last call, the most
+// severe level first, then by message.
+func (s *Sampler) Summarize() {
+	s.mu.Lock()
+	suppressed := s.suppressed
+	s.suppressed = make(map[key]int64)
+	s.mu.Unlock()
+
+	keys := make([]key, 0, len(suppressed))
+	for k := range suppressed {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		return cmp.Or(cmp.Compare(b.level, a.level), cmp.Compare(a.msg, b.msg))
+	})
+	for _, k := range keys {
+		s.report(Summary{Level: k.level, Message: k.msg, Suppressed: suppressed[k]})
+	}
+}
+
+// Shutdown stops the summary loop and reports what is still pending.
+func (s *Sampler) Shutdown() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	s.Summarize()
+}
+
+// GroupDigits formats n with thousands separators, e.g. 12,345.
+func GroupDigits(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
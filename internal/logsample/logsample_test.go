@@ -0,0 +1,91 @@
+package logsample
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// collect returns a sampler whose summaries land in the returned slice.
+func collect(cfg Config) (*Sampler, *[]Summary) {
+	var (
+		mu  sync.Mutex
+		got []Summary
+	)
+	s := New(cfg, func(sum Summary) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, sum)
+	}, nil)
+	return s, &got
+}
+
+func TestSampler(t *testing.T) {
+	type suppressed struct {
+		level  int
+		reason string
+	}
+	var reasons []suppressed
+	var summaries []Summary
+	s := New(Config{First: 2, RateLimit: 0.01}, func(sum Summary) { summaries = append(summaries, sum) },
+		func(level int, reason string) { reasons = append(reasons, suppressed{level, reason}) })
+
+	var logged []bool
+	for _, l := range []int{8, 8, 8, 0} {
+		logged = append(logged, s.Allow(l, "broker disconnected"))
+	}
+	// The first passes; the second is within First but the single rate
+	// limit token is spent; the third is beyond First.
+	if want := []bool{true, false, false, false}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("logged %v, want %v", logged, want)
+	}
+	wantReasons := []suppressed{{8, ReasonRateLimit}, {8, ReasonSampling}, {0, ReasonRateLimit}}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Errorf("suppressed %v, want %v", reasons, wantReasons)
+	}
+
+	s.Shutdown()
+	// The most severe level first.
+	want := []Summary{{Level: 8, Message: "broker disconnected", Suppressed: 2}, {Level: 0, Message: "broker disconnected", Suppressed: 1}}
+	if !reflect.DeepEqual(summaries, want) {
+		t.Errorf("summaries = %+v, want %+v", summaries, want)
+	}
+}
+
+func TestSamplerOverflow(t *testing.T) {
+	s, got := collect(Config{First: 1})
+	for i := range MaxKeys + 2 {
+		msg := "order " + strconv.Itoa(i)
+		s.Allow(0, msg)
+		s.Allow(0, msg)
+	}
+	s.Shutdown()
+	// The first MaxKeys messages are tracked alone; the last two share one
+	// window entry, so its second record onwards is suppressed. The shared
+	// entry sorts first.
+	if len(*got) != MaxKeys+1 || (*got)[0] != (Summary{Level: 0, Message: "", Suppressed: 3}) {
+		t.Errorf("%d summaries, first %+v", len(*got), (*got)[0])
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	tests := map[Summary]string{
+		{Message: "broker disconnected", Suppressed: 12345}: `suppressed 12,345 occurrences of "broker disconnected"`,
+		{Message: "", Suppressed: 3}:                        "suppressed 3 occurrences of other messages",
+	}
+	for sum, want := range tests {
+		if got := sum.Text(); got != want {
+			t.Errorf("%+v.Text() = %q, want %q", sum, got, want)
+		}
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	tests := map[int64]string{0: "0", 999: "999", 1000: "1,000", 12345: "12,345", 1234567: "1,234,567"}
+	for n, want := range tests {
+		if got := GroupDigits(n); got != want {
+			t.Errorf("GroupDigits(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
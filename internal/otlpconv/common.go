@@ -0,0 +1,177 @@
+// Package otlpconv converts SDK spans and metrics to OTLP protobuf messages
+// and back. Both Go flavors use it to persist batches in their WAL and to
+// replay them through the regular exporters; go/ampyobs also encodes it as
+// OTLP/JSON.
+package otlpconv
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// UnixNano converts t to OTLP nanoseconds; the zero time is 0.
+func UnixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// ResourceToProto converts res; a nil resource is empty.
+func ResourceToProto(res *resource.Resource) *resourcepb.Resource {
+	if res == nil {
+		return &resourcepb.Resource{}
+	}
+	return &resourcepb.Resource{Attributes: AttrsToProto(res.Attributes())}
+}
+
+// ScopeToProto converts an instrumentation scope.
+func ScopeToProto(s instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:       s.Name,
+		Version:    s.Version,
+		Attributes: AttrsToProto(s.Attributes.ToSlice()),
+	}
+}
+
+// AttrsToProto converts attributes, returning nil for none.
+func AttrsToProto(kvs []attribute.KeyValue) []*commonpb.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make([]*commonpb.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: attrValueToProto(kv.Value)})
+	}
+	return out
+}
+
+func attrValueToProto(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case attribute.BOOLSLICE:
+		return arrayValue(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return arrayValue(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return arrayValue(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return arrayValue(v.AsStringSlice(), attribute.StringValue)
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+	}
+}
+
+func arrayValue[T any](vals []T, conv func(T) attribute.Value) *commonpb.AnyValue {
+	arr := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(vals))}
+	for _, v := range vals {
+		arr.Values = append(arr.Values, attrValueToProto(conv(v)))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+}
+
+// FromUnixNano is the inverse of UnixNano.
+func FromUnixNano(n uint64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(n))
+}
+
+// ResourceFromProto rebuilds a resource with the given schema URL.
+func ResourceFromProto(r *resourcepb.Resource, schemaURL string) *resource.Resource {
+	attrs := AttrsFromProto(r.GetAttributes())
+	if schemaURL == "" {
+		return resource.NewSchemaless(attrs...)
+	}
+	return resource.NewWithAttributes(schemaURL, attrs...)
+}
+
+// ScopeFromProto rebuilds an instrumentation scope.
+func ScopeFromProto(s *commonpb.InstrumentationScope, schemaURL string) instrumentation.Scope {
+	return instrumentation.Scope{
+		Name:       s.GetName(),
+		Version:    s.GetVersion(),
+		SchemaURL:  schemaURL,
+		Attributes: attribute.NewSet(AttrsFromProto(s.GetAttributes())...),
+	}
+}
+
+// AttrsFromProto converts OTLP attributes back, returning nil for none.
+func AttrsFromProto(kvs []*commonpb.KeyValue) []attribute.KeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		out = append(out, attribute.KeyValue{Key: attribute.Key(kv.GetKey()), Value: attrValueFromProto(kv.GetValue())})
+	}
+	return out
+}
+
+func attrValueFromProto(v *commonpb.AnyValue) attribute.Value {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.BoolValue(x.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64Value(x.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64Value(x.DoubleValue)
+	case *commonpb.AnyValue_StringValue:
+		return attribute.StringValue(x.StringValue)
+	case *commonpb.AnyValue_ArrayValue:
+		vals := x.ArrayValue.GetValues()
+		if len(vals) == 0 {
+			return attribute.StringSliceValue(nil)
+		}
+		// Attribute slices are homogeneous; the first element decides the type.
+		switch vals[0].GetValue().(type) {
+		case *commonpb.AnyValue_BoolValue:
+			return attribute.BoolSliceValue(sliceFromProto(vals, (*commonpb.AnyValue).GetBoolValue))
+		case *commonpb.AnyValue_IntValue:
+			return attribute.Int64SliceValue(sliceFromProto(vals, (*commonpb.AnyValue).GetIntValue))
+		case *commonpb.AnyValue_DoubleValue:
+			return attribute.Float64SliceValue(sliceFromProto(vals, (*commonpb.AnyValue).GetDoubleValue))
+		default:
+			return attribute.StringSliceValue(sliceFromProto(vals, (*commonpb.AnyValue).GetStringValue))
+		}
+	default:
+		return attribute.StringValue("")
+	}
+}
+
+func sliceFromProto[T any](vals []*commonpb.AnyValue, get func(*commonpb.AnyValue) T) []T {
+	out := make([]T, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, get(v))
+	}
+	return out
+}
+
+// SpanContextFromProto rebuilds a span context from OTLP ids, flags and
+// W3C trace state. An unparsable trace state is dropped.
+func SpanContextFromProto(traceID, spanID []byte, flags uint32, state string) trace.SpanContext {
+	var cfg trace.SpanContextConfig
+	copy(cfg.TraceID[:], traceID)
+	copy(cfg.SpanID[:], spanID)
+	cfg.TraceFlags = trace.TraceFlags(flags & 0xff)
+	if state != "" {
+		if ts, err := trace.ParseTraceState(state); err == nil {
+			cfg.TraceState = ts
+		}
+	}
+	return trace.NewSpanContext(cfg)
+}
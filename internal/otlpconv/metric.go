@@ -0,0 +1,378 @@
+package otlpconv
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// MetricsToProto converts one export of metrics. Unknown aggregations are
+// skipped.
+func MetricsToProto(rm *metricdata.ResourceMetrics) *metricspb.ResourceMetrics {
+	out := &metricspb.ResourceMetrics{Resource: ResourceToProto(rm.Resource)}
+	if rm.Resource != nil {
+		out.SchemaUrl = rm.Resource.SchemaURL()
+	}
+	for _, sm := range rm.ScopeMetrics {
+		psm := &metricspb.ScopeMetrics{Scope: ScopeToProto(sm.Scope), SchemaUrl: sm.Scope.SchemaURL}
+		for _, m := range sm.Metrics {
+			if pm := metricToProto(m); pm != nil {
+				psm.Metrics = append(psm.Metrics, pm)
+			}
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, psm)
+	}
+	return out
+}
+
+func metricToProto(m metricdata.Metrics) *metricspb.Metric {
+	pm := &metricspb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch d := m.Data.(type) {
+	case metricdata.Gauge[int64]:
+		pm.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	case metricdata.Gauge[float64]:
+		pm.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: numberPoints(d.DataPoints)}}
+	case metricdata.Sum[int64]:
+		pm.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             numberPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+			IsMonotonic:            d.IsMonotonic,
+		}}
+	case metricdata.Sum[float64]:
+		pm.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             numberPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+			IsMonotonic:            d.IsMonotonic,
+		}}
+	case metricdata.Histogram[int64]:
+		pm.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             histogramPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+		}}
+	case metricdata.Histogram[float64]:
+		pm.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints:             histogramPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[int64]:
+		pm.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+			DataPoints:             expHistogramPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+		}}
+	case metricdata.ExponentialHistogram[float64]:
+		pm.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+			DataPoints:             expHistogramPoints(d.DataPoints),
+			AggregationTemporality: temporalityToProto(d.Temporality),
+		}}
+	case metricdata.Summary:
+		pm.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: summaryPoints(d.DataPoints)}}
+	default:
+		return nil
+	}
+	return pm
+}
+
+func temporalityToProto(t metricdata.Temporality) metricspb.AggregationTemporality {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	case metricdata.CumulativeTemporality:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	default:
+		return metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED
+	}
+}
+
+func numberPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []*metricspb.NumberDataPoint {
+	out := make([]*metricspb.NumberDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		p := &metricspb.NumberDataPoint{
+			Attributes:        AttrsToProto(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: UnixNano(dp.StartTime),
+			TimeUnixNano:      UnixNano(dp.Time),
+			Exemplars:         exemplars(dp.Exemplars),
+		}
+		switch v := any(dp.Value).(type) {
+		case int64:
+			p.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			p.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func histogramPoints[N int64 | float64](dps []metricdata.HistogramDataPoint[N]) []*metricspb.HistogramDataPoint {
+	out := make([]*metricspb.HistogramDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		sum := float64(dp.Sum)
+		out = append(out, &metricspb.HistogramDataPoint{
+			Attributes:        AttrsToProto(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: UnixNano(dp.StartTime),
+			TimeUnixNano:      UnixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &sum,
+			BucketCounts:      dp.BucketCounts,
+			ExplicitBounds:    dp.Bounds,
+			Exemplars:         exemplars(dp.Exemplars),
+			Min:               extrema(dp.Min),
+			Max:               extrema(dp.Max),
+		})
+	}
+	return out
+}
+
+func expHistogramPoints[N int64 | float64](dps []metricdata.ExponentialHistogramDataPoint[N]) []*metricspb.ExponentialHistogramDataPoint {
+	out := make([]*metricspb.ExponentialHistogramDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		sum := float64(dp.Sum)
+		out = append(out, &metricspb.ExponentialHistogramDataPoint{
+			Attributes:        AttrsToProto(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: UnixNano(dp.StartTime),
+			TimeUnixNano:      UnixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               &sum,
+			Scale:             dp.Scale,
+			ZeroCount:         dp.ZeroCount,
+			ZeroThreshold:     dp.ZeroThreshold,
+			Positive:          &metricspb.ExponentialHistogramDataPoint_Buckets{Offset: dp.PositiveBucket.Offset, BucketCounts: dp.PositiveBucket.Counts},
+			Negative:          &metricspb.ExponentialHistogramDataPoint_Buckets{Offset: dp.NegativeBucket.Offset, BucketCounts: dp.NegativeBucket.Counts},
+			Exemplars:         exemplars(dp.Exemplars),
+			Min:               extrema(dp.Min),
+			Max:               extrema(dp.Max),
+		})
+	}
+	return out
+}
+
+func summaryPoints(dps []metricdata.SummaryDataPoint) []*metricspb.SummaryDataPoint {
+	out := make([]*metricspb.SummaryDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		p := &metricspb.SummaryDataPoint{
+			Attributes:        AttrsToProto(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: UnixNano(dp.StartTime),
+			TimeUnixNano:      UnixNano(dp.Time),
+			Count:             dp.Count,
+			Sum:               dp.Sum,
+		}
+		for _, q := range dp.QuantileValues {
+			p.QuantileValues = append(p.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q.Quantile, Value: q.Value})
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func extrema[N int64 | float64](e metricdata.Extrema[N]) *float64 {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	f := float64(v)
+	return &f
+}
+
+func exemplars[N int64 | float64](exs []metricdata.Exemplar[N]) []*metricspb.Exemplar {
+	if len(exs) == 0 {
+		return nil
+	}
+	out := make([]*metricspb.Exemplar, 0, len(exs))
+	for _, ex := range exs {
+		p := &metricspb.Exemplar{
+			FilteredAttributes: AttrsToProto(ex.FilteredAttributes),
+			TimeUnixNano:       UnixNano(ex.Time),
+			SpanId:             ex.SpanID,
+			TraceId:            ex.TraceID,
+		}
+		switch v := any(ex.Value).(type) {
+		case int64:
+			p.Value = &metricspb.Exemplar_AsInt{AsInt: v}
+		case float64:
+			p.Value = &metricspb.Exemplar_AsDouble{AsDouble: v}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// MetricsFromProto is the inverse of MetricsToProto.
+func MetricsFromProto(rm *metricspb.ResourceMetrics) *metricdata.ResourceMetrics {
+	out := &metricdata.ResourceMetrics{Resource: ResourceFromProto(rm.GetResource(), rm.GetSchemaUrl())}
+	for _, sm := range rm.GetScopeMetrics() {
+		osm := metricdata.ScopeMetrics{Scope: ScopeFromProto(sm.GetScope(), sm.GetSchemaUrl())}
+		for _, m := range sm.GetMetrics() {
+			if om, ok := metricFromProto(m); ok {
+				osm.Metrics = append(osm.Metrics, om)
+			}
+		}
+		out.ScopeMetrics = append(out.ScopeMetrics, osm)
+	}
+	return out
+}
+
+func metricFromProto(m *metricspb.Metric) (metricdata.Metrics, bool) {
+	om := metricdata.Metrics{Name: m.GetName(), Description: m.GetDescription(), Unit: m.GetUnit()}
+	switch d := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		dps := d.Gauge.GetDataPoints()
+		if isIntPoints(dps) {
+			om.Data = metricdata.Gauge[int64]{DataPoints: numberPointsFromProto[int64](dps)}
+		} else {
+			om.Data = metricdata.Gauge[float64]{DataPoints: numberPointsFromProto[float64](dps)}
+		}
+	case *metricspb.Metric_Sum:
+		dps := d.Sum.GetDataPoints()
+		temporality := temporalityFromProto(d.Sum.GetAggregationTemporality())
+		if isIntPoints(dps) {
+			om.Data = metricdata.Sum[int64]{DataPoints: numberPointsFromProto[int64](dps), Temporality: temporality, IsMonotonic: d.Sum.GetIsMonotonic()}
+		} else {
+			om.Data = metricdata.Sum[float64]{DataPoints: numberPointsFromProto[float64](dps), Temporality: temporality, IsMonotonic: d.Sum.GetIsMonotonic()}
+		}
+	case *metricspb.Metric_Histogram:
+		om.Data = metricdata.Histogram[float64]{
+			DataPoints:  histogramPointsFromProto(d.Histogram.GetDataPoints()),
+			Temporality: temporalityFromProto(d.Histogram.GetAggregationTemporality()),
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		om.Data = metricdata.ExponentialHistogram[float64]{
+			DataPoints:  expHistogramPointsFromProto(d.ExponentialHistogram.GetDataPoints()),
+			Temporality: temporalityFromProto(d.ExponentialHistogram.GetAggregationTemporality()),
+		}
+	case *metricspb.Metric_Summary:
+		om.Data = metricdata.Summary{DataPoints: summaryPointsFromProto(d.Summary.GetDataPoints())}
+	default:
+		return om, false
+	}
+	return om, true
+}
+
+func isIntPoints(dps []*metricspb.NumberDataPoint) bool {
+	if len(dps) == 0 {
+		return false
+	}
+	_, ok := dps[0].GetValue().(*metricspb.NumberDataPoint_AsInt)
+	return ok
+}
+
+func temporalityFromProto(t metricspb.AggregationTemporality) metricdata.Temporality {
+	switch t {
+	case metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA:
+		return metricdata.DeltaTemporality
+	case metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+		return metricdata.CumulativeTemporality
+	default:
+		return metricdata.Temporality(0)
+	}
+}
+
+func numberPointsFromProto[N int64 | float64](dps []*metricspb.NumberDataPoint) []metricdata.DataPoint[N] {
+	out := make([]metricdata.DataPoint[N], 0, len(dps))
+	for _, dp := range dps {
+		var v N
+		switch x := dp.GetValue().(type) {
+		case *metricspb.NumberDataPoint_AsInt:
+			v = N(x.AsInt)
+		case *metricspb.NumberDataPoint_AsDouble:
+			v = N(x.AsDouble)
+		}
+		out = append(out, metricdata.DataPoint[N]{
+			Attributes: attribute.NewSet(AttrsFromProto(dp.GetAttributes())...),
+			StartTime:  FromUnixNano(dp.GetStartTimeUnixNano()),
+			Time:       FromUnixNano(dp.GetTimeUnixNano()),
+			Value:      v,
+			Exemplars:  exemplarsFromProto[N](dp.GetExemplars()),
+		})
+	}
+	return out
+}
+
+func histogramPointsFromProto(dps []*metricspb.HistogramDataPoint) []metricdata.HistogramDataPoint[float64] {
+	out := make([]metricdata.HistogramDataPoint[float64], 0, len(dps))
+	for _, dp := range dps {
+		out = append(out, metricdata.HistogramDataPoint[float64]{
+			Attributes:   attribute.NewSet(AttrsFromProto(dp.GetAttributes())...),
+			StartTime:    FromUnixNano(dp.GetStartTimeUnixNano()),
+			Time:         FromUnixNano(dp.GetTimeUnixNano()),
+			Count:        dp.GetCount(),
+			Sum:          dp.GetSum(),
+			Bounds:       dp.GetExplicitBounds(),
+			BucketCounts: dp.GetBucketCounts(),
+			Min:          extremaFromProto(dp.Min),
+			Max:          extremaFromProto(dp.Max),
+			Exemplars:    exemplarsFromProto[float64](dp.GetExemplars()),
+		})
+	}
+	return out
+}
+
+func expHistogramPointsFromProto(dps []*metricspb.ExponentialHistogramDataPoint) []metricdata.ExponentialHistogramDataPoint[float64] {
+	out := make([]metricdata.ExponentialHistogramDataPoint[float64], 0, len(dps))
+	for _, dp := range dps {
+		out = append(out, metricdata.ExponentialHistogramDataPoint[float64]{
+			Attributes:     attribute.NewSet(AttrsFromProto(dp.GetAttributes())...),
+			StartTime:      FromUnixNano(dp.GetStartTimeUnixNano()),
+			Time:           FromUnixNano(dp.GetTimeUnixNano()),
+			Count:          dp.GetCount(),
+			Sum:            dp.GetSum(),
+			Scale:          dp.GetScale(),
+			ZeroCount:      dp.GetZeroCount(),
+			ZeroThreshold:  dp.GetZeroThreshold(),
+			PositiveBucket: metricdata.ExponentialBucket{Offset: dp.GetPositive().GetOffset(), Counts: dp.GetPositive().GetBucketCounts()},
+			NegativeBucket: metricdata.ExponentialBucket{Offset: dp.GetNegative().GetOffset(), Counts: dp.GetNegative().GetBucketCounts()},
+			Min:            extremaFromProto(dp.Min),
+			Max:            extremaFromProto(dp.Max),
+			Exemplars:      exemplarsFromProto[float64](dp.GetExemplars()),
+		})
+	}
+	return out
+}
+
+func summaryPointsFromProto(dps []*metricspb.SummaryDataPoint) []metricdata.SummaryDataPoint {
+	out := make([]metricdata.SummaryDataPoint, 0, len(dps))
+	for _, dp := range dps {
+		p := metricdata.SummaryDataPoint{
+			Attributes: attribute.NewSet(AttrsFromProto(dp.GetAttributes())...),
+			StartTime:  FromUnixNano(dp.GetStartTimeUnixNano()),
+			Time:       FromUnixNano(dp.GetTimeUnixNano()),
+			Count:      dp.GetCount(),
+			Sum:        dp.GetSum(),
+		}
+		for _, q := range dp.GetQuantileValues() {
+			p.QuantileValues = append(p.QuantileValues, metricdata.QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func extremaFromProto(v *float64) metricdata.Extrema[float64] {
+	if v == nil {
+		return metricdata.Extrema[float64]{}
+	}
+	return metricdata.NewExtrema(*v)
+}
+
+func exemplarsFromProto[N int64 | float64](exs []*metricspb.Exemplar) []metricdata.Exemplar[N] {
+	if len(exs) == 0 {
+		return nil
+	}
+	out := make([]metricdata.Exemplar[N], 0, len(exs))
+	for _, ex := range exs {
+		var v N
+		switch x := ex.GetValue().(type) {
+		case *metricspb.Exemplar_AsInt:
+			v = N(x.AsInt)
+		case *metricspb.Exemplar_AsDouble:
+			v = N(x.AsDouble)
+		}
+		out = append(out, metricdata.Exemplar[N]{
+			FilteredAttributes: AttrsFromProto(ex.GetFilteredAttributes()),
+			Time:               FromUnixNano(ex.GetTimeUnixNano()),
+			Value:              v,
+			SpanID:             ex.GetSpanId(),
+			TraceID:            ex.GetTraceId(),
+		})
+	}
+	return out
+}
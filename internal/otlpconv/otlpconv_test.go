@@ -0,0 +1,171 @@
+package otlpconv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpansRoundTrip(t *testing.T) {
+	start := time.Unix(1700000000, 123)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	parent := sc.WithSpanID(trace.SpanID{7})
+	res := resource.NewWithAttributes("https://opentelemetry.io/schemas/1.26.0", attribute.String("service.name", "oms"))
+	scope := instrumentation.Scope{Name: "ampy", Version: "1.0", Attributes: attribute.NewSet(attribute.Bool("lib", true))}
+
+	tests := []struct {
+		name string
+		stub tracetest.SpanStub
+	}{
+		{
+			name: "root span",
+			stub: tracetest.SpanStub{Name: "submit", SpanContext: sc, SpanKind: trace.SpanKindServer, StartTime: start, EndTime: start.Add(time.Second), Resource: res, InstrumentationScope: scope},
+		},
+		{
+			name: "child with everything",
+			stub: tracetest.SpanStub{
+				Name:        "fill",
+				SpanContext: sc,
+				Parent:      parent,
+				SpanKind:    trace.SpanKindClient,
+				StartTime:   start,
+				EndTime:     start.Add(time.Millisecond),
+				Attributes: []attribute.KeyValue{
+					attribute.String("symbol", "AAPL"),
+					attribute.Int64Slice("lots", []int64{1, 2}),
+					attribute.Float64("px", 101.5),
+				},
+				Events:               []sdktrace.Event{{Name: "ack", Time: start, Attributes: []attribute.KeyValue{attribute.Int("n", 1)}}},
+				Links:                []sdktrace.Link{{SpanContext: parent, Attributes: []attribute.KeyValue{attribute.String("why", "retry")}}},
+				Status:               sdktrace.Status{Code: codes.Error, Description: "rejected"},
+				DroppedAttributes:    2,
+				DroppedEvents:        3,
+				DroppedLinks:         4,
+				Resource:             res,
+				InstrumentationScope: scope,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SpansFromProto(SpansToProto([]sdktrace.ReadOnlySpan{tt.stub.Snapshot()}))
+			if len(got) != 1 {
+				t.Fatalf("decoded %d spans, want 1", len(got))
+			}
+			gotStub := tracetest.SpanStubFromReadOnlySpan(got[0])
+			gotStub.Resource, gotStub.InstrumentationLibrary = nil, instrumentation.Library{}
+			want := tt.stub
+			want.Resource, want.InstrumentationLibrary = nil, instrumentation.Library{}
+			if !reflect.DeepEqual(gotStub, want) {
+				t.Errorf("round trip:\n got %+v\nwant %+v", gotStub, want)
+			}
+			if !got[0].Resource().Equal(res) || got[0].Resource().SchemaURL() != res.SchemaURL() {
+				t.Errorf("resource = %v, want %v", got[0].Resource(), res)
+			}
+		})
+	}
+}
+
+// TestDecodedSpanMethods calls every ReadOnlySpan method on a decoded span,
+// so none of them is left to a nil implementation.
+func TestDecodedSpanMethods(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+	stub := tracetest.SpanStub{Name: "submit", SpanContext: sc, Resource: resource.Empty()}
+	span := SpansFromProto(SpansToProto([]sdktrace.ReadOnlySpan{stub.Snapshot()}))[0]
+
+	v := reflect.ValueOf(span)
+	it := reflect.TypeOf((*sdktrace.ReadOnlySpan)(nil)).Elem()
+	for i := 0; i < it.NumMethod(); i++ {
+		m := it.Method(i)
+		if !m.IsExported() {
+			continue
+		}
+		t.Run(m.Name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s() panicked: %v", m.Name, r)
+				}
+			}()
+			v.MethodByName(m.Name).Call(nil)
+		})
+	}
+}
+
+func TestMetricsRoundTrip(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start.Add(time.Minute)
+	attrs := attribute.NewSet(attribute.String("venue", "XNAS"))
+	min, max := metricdata.NewExtrema(0.5), metricdata.NewExtrema(9.0)
+	ex := metricdata.Exemplar[float64]{
+		FilteredAttributes: []attribute.KeyValue{attribute.String("order", "o-1")},
+		Time:               now,
+		Value:              3,
+		SpanID:             []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceID:            []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+
+	tests := []struct {
+		name string
+		data metricdata.Aggregation
+	}{
+		{"int sum", metricdata.Sum[int64]{
+			DataPoints:  []metricdata.DataPoint[int64]{{Attributes: attrs, StartTime: start, Time: now, Value: 42}},
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+		}},
+		{"float gauge", metricdata.Gauge[float64]{
+			DataPoints: []metricdata.DataPoint[float64]{{Attributes: attrs, Time: now, Value: 1.5}},
+		}},
+		{"histogram", metricdata.Histogram[float64]{
+			DataPoints: []metricdata.HistogramDataPoint[float64]{{
+				Attributes: attrs, StartTime: start, Time: now,
+				Count: 3, Sum: 12.5, Bounds: []float64{1, 5}, BucketCounts: []uint64{1, 1, 1},
+				Min: min, Max: max, Exemplars: []metricdata.Exemplar[float64]{ex},
+			}},
+			Temporality: metricdata.DeltaTemporality,
+		}},
+		{"exponential histogram", metricdata.ExponentialHistogram[float64]{
+			DataPoints: []metricdata.ExponentialHistogramDataPoint[float64]{{
+				Attributes: attrs, StartTime: start, Time: now,
+				Count: 4, Sum: 10, Scale: 2, ZeroCount: 1, ZeroThreshold: 0.001,
+				PositiveBucket: metricdata.ExponentialBucket{Offset: 1, Counts: []uint64{1, 2}},
+				NegativeBucket: metricdata.ExponentialBucket{Counts: []uint64{}},
+				Min:            min, Max: max,
+			}},
+			Temporality: metricdata.CumulativeTemporality,
+		}},
+		{"summary", metricdata.Summary{
+			DataPoints: []metricdata.SummaryDataPoint{{
+				Attributes: attrs, StartTime: start, Time: now, Count: 2, Sum: 3,
+				QuantileValues: []metricdata.QuantileValue{{Quantile: 0.5, Value: 1}, {Quantile: 1, Value: 2}},
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := &metricdata.ResourceMetrics{
+				Resource: resource.NewSchemaless(attribute.String("service.name", "oms")),
+				ScopeMetrics: []metricdata.ScopeMetrics{{
+					Scope:   instrumentation.Scope{Name: "ampy", Version: "1.0", Attributes: attribute.NewSet(attribute.Bool("lib", true))},
+					Metrics: []metricdata.Metrics{{Name: "m", Description: "d", Unit: "1", Data: tt.data}},
+				}},
+			}
+			got := MetricsFromProto(MetricsToProto(want))
+			metricdatatest.AssertEqual(t, *want, *got)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package otlpconv
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SpansToProto groups spans by resource and instrumentation scope.
+func SpansToProto(spans []sdktrace.ReadOnlySpan) []*tracepb.ResourceSpans {
+	var out []*tracepb.ResourceSpans
+	byRes := map[attribute.Distinct]*tracepb.ResourceSpans{}
+	byScope := map[attribute.Distinct]map[instrumentation.Scope]*tracepb.ScopeSpans{}
+
+	for _, s := range spans {
+		res := s.Resource()
+		key := res.Equivalent()
+		rs, ok := byRes[key]
+		if !ok {
+			rs = &tracepb.ResourceSpans{Resource: ResourceToProto(res), SchemaUrl: res.SchemaURL()}
+			byRes[key] = rs
+			byScope[key] = map[instrumentation.Scope]*tracepb.ScopeSpans{}
+			out = append(out, rs)
+		}
+		scope := s.InstrumentationScope()
+		ss, ok := byScope[key][scope]
+		if !ok {
+			ss = &tracepb.ScopeSpans{Scope: ScopeToProto(scope), SchemaUrl: scope.SchemaURL}
+			byScope[key][scope] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, spanToProto(s))
+	}
+	return out
+}
+
+func spanToProto(s sdktrace.ReadOnlySpan) *tracepb.Span {
+	sc := s.SpanContext()
+	tid, sid := sc.TraceID(), sc.SpanID()
+	ps := &tracepb.Span{
+		TraceId:                tid[:],
+		SpanId:                 sid[:],
+		TraceState:             sc.TraceState().String(),
+		Flags:                  uint32(sc.TraceFlags()),
+		Name:                   s.Name(),
+		Kind:                   tracepb.Span_SpanKind(s.SpanKind()),
+		StartTimeUnixNano:      UnixNano(s.StartTime()),
+		EndTimeUnixNano:        UnixNano(s.EndTime()),
+		Attributes:             AttrsToProto(s.Attributes()),
+		DroppedAttributesCount: uint32(s.DroppedAttributes()),
+		DroppedEventsCount:     uint32(s.DroppedEvents()),
+		DroppedLinksCount:      uint32(s.DroppedLinks()),
+		Status:                 statusToProto(s.Status()),
+	}
+	if p := s.Parent(); p.IsValid() {
+		psid := p.SpanID()
+		ps.ParentSpanId = psid[:]
+	}
+	for _, e := range s.Events() {
+		ps.Events = append(ps.Events, &tracepb.Span_Event{
+			TimeUnixNano:           UnixNano(e.Time),
+			Name:                   e.Name,
+			Attributes:             AttrsToProto(e.Attributes),
+			DroppedAttributesCount: uint32(e.DroppedAttributeCount),
+		})
+	}
+	for _, l := range s.Links() {
+		ltid, lsid := l.SpanContext.TraceID(), l.SpanContext.SpanID()
+		ps.Links = append(ps.Links, &tracepb.Span_Link{
+			TraceId:                ltid[:],
+			SpanId:                 lsid[:],
+			TraceState:             l.SpanContext.TraceState().String(),
+			Attributes:             AttrsToProto(l.Attributes),
+			DroppedAttributesCount: uint32(l.DroppedAttributeCount),
+			Flags:                  uint32(l.SpanContext.TraceFlags()),
+		})
+	}
+	return ps
+}
+
+func statusToProto(st sdktrace.Status) *tracepb.Status {
+	code := tracepb.Status_STATUS_CODE_UNSET
+	switch st.Code {
+	case codes.Ok:
+		code = tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		code = tracepb.Status_STATUS_CODE_ERROR
+	}
+	return &tracepb.Status{Code: code, Message: st.Description}
+}
+
+// SpansFromProto rebuilds spans, attaching their resource and scope.
+func SpansFromProto(rss []*tracepb.ResourceSpans) []sdktrace.ReadOnlySpan {
+	var out []sdktrace.ReadOnlySpan
+	for _, rs := range rss {
+		res := ResourceFromProto(rs.GetResource(), rs.GetSchemaUrl())
+		for _, ss := range rs.GetScopeSpans() {
+			scope := ScopeFromProto(ss.GetScope(), ss.GetSchemaUrl())
+			for _, s := range ss.GetSpans() {
+				out = append(out, spanFromProto(s, res, scope))
+			}
+		}
+	}
+	return out
+}
+
+func spanFromProto(s *tracepb.Span, res *resource.Resource, scope instrumentation.Scope) sdktrace.ReadOnlySpan {
+	stub := tracetest.SpanStub{
+		Name:                 s.GetName(),
+		SpanContext:          SpanContextFromProto(s.GetTraceId(), s.GetSpanId(), s.GetFlags(), s.GetTraceState()),
+		SpanKind:             trace.SpanKind(s.GetKind()),
+		StartTime:            FromUnixNano(s.GetStartTimeUnixNano()),
+		EndTime:              FromUnixNano(s.GetEndTimeUnixNano()),
+		Attributes:           AttrsFromProto(s.GetAttributes()),
+		Status:               statusFromProto(s.GetStatus()),
+		DroppedAttributes:    int(s.GetDroppedAttributesCount()),
+		DroppedEvents:        int(s.GetDroppedEventsCount()),
+		DroppedLinks:         int(s.GetDroppedLinksCount()),
+		Resource:             res,
+		InstrumentationScope: scope,
+	}
+	if len(s.GetParentSpanId()) > 0 {
+		stub.Parent = SpanContextFromProto(s.GetTraceId(), s.GetParentSpanId(), s.GetFlags(), "")
+	}
+	for _, e := range s.GetEvents() {
+		stub.Events = append(stub.Events, sdktrace.Event{
+			Name:                  e.GetName(),
+			Time:                  FromUnixNano(e.GetTimeUnixNano()),
+			Attributes:            AttrsFromProto(e.GetAttributes()),
+			DroppedAttributeCount: int(e.GetDroppedAttributesCount()),
+		})
+	}
+	for _, l := range s.GetLinks() {
+		stub.Links = append(stub.Links, sdktrace.Link{
+			SpanContext:           SpanContextFromProto(l.GetTraceId(), l.GetSpanId(), l.GetFlags(), l.GetTraceState()),
+			Attributes:            AttrsFromProto(l.GetAttributes()),
+			DroppedAttributeCount: int(l.GetDroppedAttributesCount()),
+		})
+	}
+	// A stub snapshot is a complete ReadOnlySpan, including the methods the
+	// SDK adds in later releases.
+	return stub.Snapshot()
+}
+
+func statusFromProto(st *tracepb.Status) sdktrace.Status {
+	switch st.GetCode() {
+	case tracepb.Status_STATUS_CODE_OK:
+		return sdktrace.Status{Code: codes.Ok}
+	case tracepb.Status_STATUS_CODE_ERROR:
+		return sdktrace.Status{Code: codes.Error, Description: st.GetMessage()}
+	default:
+		return sdktrace.Status{Code: codes.Unset}
+	}
+}
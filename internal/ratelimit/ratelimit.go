@@ -0,0 +1,51 @@
+// Package ratelimit is the token bucket behind the rate-limit samplers of
+// both Go SDKs (go/ampyobs and sdk/go/ampyobs): trace sampling by traces per
+// second and log sampling by records per second.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket refilled at a rate per second up to a burst.
+// Callers pass the current time, so it follows whatever clock they use.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a full bucket refilled at perSecond, holding up to burst
+// tokens (default: one second's worth, at least 1).
+func New(perSecond float64, burst int, now time.Time) *Limiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = max(perSecond, 1)
+	}
+	return &Limiter{rate: perSecond, burst: b, tokens: b, last: now}
+}
+
+// Allow spends a token, reporting false when none is left.
+func (l *Limiter) Allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.After(l.last) {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+		l.last = now
+	}
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Rate returns the refill rate in tokens per second.
+func (l *Limiter) Rate() float64 { return l.rate }
+
+// Burst returns the bucket size.
+func (l *Limiter) Burst() float64 { return l.burst }
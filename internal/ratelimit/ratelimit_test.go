@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond float64
+		burst     int
+		wantBurst int           // allowed at once
+		after     time.Duration // then, after this long
+		wantAfter int           // allowed
+	}{
+		{name: "burst defaults to one second", perSecond: 5, wantBurst: 5, after: time.Second, wantAfter: 5},
+		{name: "explicit burst", perSecond: 5, burst: 20, wantBurst: 20, after: 200 * time.Millisecond, wantAfter: 1},
+		{name: "refill is capped at burst", perSecond: 10, burst: 2, wantBurst: 2, after: time.Minute, wantAfter: 2},
+		{name: "below one per second", perSecond: 0.5, wantBurst: 1, after: time.Second, wantAfter: 0},
+		{name: "clock going back", perSecond: 5, wantBurst: 5, after: -time.Hour, wantAfter: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)
+			l := New(tt.perSecond, tt.burst, now)
+			allow := func() int {
+				n := 0
+				for range 100 {
+					if l.Allow(now) {
+						n++
+					}
+				}
+				return n
+			}
+			if got := allow(); got != tt.wantBurst {
+				t.Errorf("allowed %d at once, want %d", got, tt.wantBurst)
+			}
+			now = now.Add(tt.after)
+			if got := allow(); got != tt.wantAfter {
+				t.Errorf("allowed %d after %s, want %d", got, tt.after, tt.wantAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package tlsconfig builds the TLS client configs used by the OTLP exporters
+// of both Go SDKs (go/ampyobs and sdk/go/ampyobs). Certificates loaded from
+// files are re-read when the files change, so rotated certs are picked up
+// without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config holds the TLS settings of an SDK's TLSConfig, which converts to it
+// directly; see there for the meaning of each field.
+type Config struct {
+	CAFile string
+	CAPEM  []byte
+
+	CertFile string
+	KeyFile  string
+	CertPEM  []byte
+	KeyPEM   []byte
+
+	ServerName         string
+	MinVersion         string
+	InsecureSkipVerify bool
+	ReloadInterval     time.Duration
+}
+
+// DefaultReloadInterval is used when Config.ReloadInterval is not positive.
+const DefaultReloadInterval = 30 * time.Second
+
+// Enabled reports whether any TLS setting was provided.
+func (c Config) Enabled() bool {
+	return c.CAFile != "" || len(c.CAPEM) > 0 ||
+		c.CertFile != "" || c.KeyFile != "" || len(c.CertPEM) > 0 || len(c.KeyPEM) > 0 ||
+		c.ServerName != "" || c.MinVersion != "" || c.InsecureSkipVerify
+}
+
+// HasClientCert reports whether a client certificate was provided.
+func (c Config) HasClientCert() bool {
+	return c.CertFile != "" || len(c.CertPEM) > 0
+}
+
+// HasClientKey reports whether a client key was provided.
+func (c Config) HasClientKey() bool {
+	return c.KeyFile != "" || len(c.KeyPEM) > 0
+}
+
+// ParseVersion maps "1.2" (or "") and "1.3" to their tls.Version* constants.
+func ParseVersion(v string) (uint16, bool) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, true
+	case "1.3":
+		return tls.VersionTLS13, true
+	default:
+		return 0, false
+	}
+}
+
+// New builds a *tls.Config for exporters. The returned config consults a
+// reloader on every handshake for the client cert and CA pool.
+func New(c Config) (*tls.Config, error) {
+	minVersion, ok := ParseVersion(c.MinVersion)
+	if !ok {
+		return nil, fmt.Errorf("tls: unsupported min version %q", c.MinVersion)
+	}
+	r := &reloader{cfg: c, interval: c.ReloadInterval}
+	if r.interval <= 0 {
+		r.interval = DefaultReloadInterval
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: c.ServerName,
+	}
+	if c.HasClientCert() {
+		tc.GetClientCertificate = r.clientCertificate
+	}
+	switch {
+	case c.InsecureSkipVerify:
+		tc.InsecureSkipVerify = true
+	case c.CAFile != "" || len(c.CAPEM) > 0:
+		// Verify against the (reloadable) CA pool ourselves; the stdlib
+		// verifier only supports a fixed RootCAs.
+		tc.InsecureSkipVerify = true
+		tc.VerifyConnection = r.verifyConnection
+	}
+	return tc, nil
+}
+
+// reloader holds the current client certificate and CA pool and re-reads
+// the backing files when their modification time changes.
+type reloader struct {
+	cfg      Config
+	interval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+func (r *reloader) load() error {
+	modTimes := map[string]time.Time{}
+	read := func(path string, inline []byte) ([]byte, error) {
+		if path == "" {
+			return inline, nil
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		modTimes[path] = st.ModTime()
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		return b, nil
+	}
+
+	var pool *x509.CertPool
+	if r.cfg.CAFile != "" || len(r.cfg.CAPEM) > 0 {
+		pool = x509.NewCertPool()
+		caPEM, err := read(r.cfg.CAFile, nil)
+		if err != nil {
+			return err
+		}
+		for _, b := range [][]byte{caPEM, r.cfg.CAPEM} {
+			if len(b) > 0 && !pool.AppendCertsFromPEM(b) {
+				return errors.New("tls: no certificates found in CA PEM")
+			}
+		}
+	}
+
+	var cert *tls.Certificate
+	if r.cfg.HasClientCert() {
+		certPEM, err := read(r.cfg.CertFile, r.cfg.CertPEM)
+		if err != nil {
+			return err
+		}
+		keyPEM, err := read(r.cfg.KeyFile, r.cfg.KeyPEM)
+		if err != nil {
+			return err
+		}
+		c, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("tls: client key pair: %w", err)
+		}
+		cert = &c
+	}
+
+	r.mu.Lock()
+	r.pool, r.cert, r.modTimes, r.lastCheck = pool, cert, modTimes, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// maybeReload re-reads the files if any changed since the last load. Errors
+// keep the previous material so a half-written rotation does not break export.
+func (r *reloader) maybeReload() {
+	r.mu.RLock()
+	due := time.Since(r.lastCheck) >= r.interval
+	modTimes := r.modTimes
+	r.mu.RUnlock()
+	if !due || len(modTimes) == 0 {
+		return
+	}
+
+	changed := false
+	for path, mt := range modTimes {
+		if st, err := os.Stat(path); err == nil && !st.ModTime().Equal(mt) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		r.mu.Lock()
+		r.lastCheck = time.Now()
+		r.mu.Unlock()
+		return
+	}
+	if err := r.load(); err != nil {
+		r.mu.Lock()
+		r.lastCheck = time.Now()
+		r.mu.Unlock()
+	}
+}
+
+func (r *reloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return r.cert, nil
+}
+
+func (r *reloader) verifyConnection(cs tls.ConnectionState) error {
+	r.maybeReload()
+	r.mu.RLock()
+	pool := r.pool
+	r.mu.RUnlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: collector presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,257 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a throwaway certificate authority.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by ca.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb})
+}
+
+// newMTLSServer starts a server for "collector" that requires a client
+// certificate signed by ca.
+func newMTLSServer(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "collector", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := x509.NewCertPool()
+	clients.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clients,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(srv *httptest.Server, tc *tls.Config) error {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tc, DisableKeepAlives: true}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func writeFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnabledAndParseVersion(t *testing.T) {
+	if (Config{}).Enabled() {
+		t.Error("zero Config is enabled")
+	}
+	for _, c := range []Config{{CAFile: "ca.pem"}, {KeyPEM: []byte("k")}, {MinVersion: "1.3"}, {InsecureSkipVerify: true}} {
+		if !c.Enabled() {
+			t.Errorf("%+v not enabled", c)
+		}
+	}
+	tests := []struct {
+		in   string
+		want uint16
+		ok   bool
+	}{
+		{"", tls.VersionTLS12, true},
+		{"1.2", tls.VersionTLS12, true},
+		{"1.3", tls.VersionTLS13, true},
+		{"1.1", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseVersion(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseVersion(%q) = %v, %v", tt.in, got, ok)
+		}
+	}
+	if _, err := New(Config{MinVersion: "1.0"}); err == nil {
+		t.Error("New accepted min version 1.0")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	ca, other := newTestCA(t), newTestCA(t)
+	srv := newMTLSServer(t, ca)
+	certPEM, keyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "mtls",
+			cfg:  Config{CAPEM: ca.pem, CertPEM: certPEM, KeyPEM: keyPEM, ServerName: "collector"},
+		},
+		{
+			name:    "wrong ca",
+			cfg:     Config{CAPEM: other.pem, CertPEM: certPEM, KeyPEM: keyPEM, ServerName: "collector"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong server name",
+			cfg:     Config{CAPEM: ca.pem, CertPEM: certPEM, KeyPEM: keyPEM, ServerName: "elsewhere"},
+			wantErr: true,
+		},
+		{
+			name:    "no client certificate",
+			cfg:     Config{CAPEM: ca.pem, ServerName: "collector"},
+			wantErr: true,
+		},
+		{
+			name: "insecure skip verify",
+			cfg:  Config{CertPEM: certPEM, KeyPEM: keyPEM, InsecureSkipVerify: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := get(srv, tc); (err != nil) != tt.wantErr {
+				t.Errorf("get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing ca file", Config{CAFile: filepath.Join(dir, "missing.pem")}},
+		{"ca without certificates", Config{CAPEM: []byte("not pem")}},
+		{"bad key pair", Config{CertPEM: []byte("cert"), KeyPEM: []byte("key")}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.cfg); err == nil {
+			t.Errorf("%s: New succeeded", tt.name)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	srv := newMTLSServer(t, newCA)
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+
+	// Start with material from the old CA: the handshake fails both ways.
+	certPEM, keyPEM := oldCA.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	writeFile(t, caFile, oldCA.pem)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	tc, err := New(Config{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "collector", ReloadInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(srv, tc); err == nil {
+		t.Fatal("handshake with the old CA succeeded")
+	}
+
+	// Rotate every file; the next handshake picks them up.
+	certPEM, keyPEM = newCA.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	writeFile(t, caFile, newCA.pem)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{caFile, certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := get(srv, tc); err != nil {
+		t.Fatalf("handshake after rotation: %v", err)
+	}
+
+	// A half-written rotation keeps the previous material.
+	writeFile(t, keyFile, []byte("truncated"))
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(keyFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := get(srv, tc); err != nil {
+		t.Fatalf("handshake after a broken rotation: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package ampyobs
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -31,6 +32,26 @@ func FromDomainContext(ctx context.Context) (DomainContext, bool) {
 	return dc, ok
 }
 
+// attributes returns the non-empty fields under their log keys, for matching
+// debug targets.
+func (d DomainContext) attributes() []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, 7)
+	for _, f := range []struct{ key, value string }{
+		{"run_id", d.RunID},
+		{"as_of", d.AsOfISO},
+		{"universe_id", d.UniverseID},
+		{"message_id", d.MessageID},
+		{"client_order_id", d.ClientOrderID},
+		{"symbol", d.Symbol},
+		{"mic", d.MIC},
+	} {
+		if f.value != "" {
+			out = append(out, attribute.String(f.key, f.value))
+		}
+	}
+	return out
+}
+
 func (d DomainContext) toZapFields() []zap.Field {
 	out := make([]zap.Field, 0, 8)
 	if d.RunID != "" {
@@ -0,0 +1,119 @@
+package ampyobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/debugtarget"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BaggageDebug is the baggage member marking a flow for debugging, as in
+// go/ampyobs. Flows carrying it are sampled in full and log at debug level;
+// Handle.Propagator adds it to outgoing headers of flows matching a debug
+// target.
+const BaggageDebug = debugtarget.Baggage
+
+// DebugTarget is a flow forced to full sampling and debug logging.
+type DebugTarget struct {
+	Key     string    // DomainContext log key or span attribute, e.g. "run_id", "client_order_id"
+	Value   string    // e.g. the client order id a trader reported
+	Expires time.Time // zero: until removed
+}
+
+// WithDebug marks the flow of ctx for debugging via baggage, e.g. from an
+// API handler given a debug header.
+func WithDebug(ctx context.Context) context.Context {
+	return debugtarget.WithBaggage(ctx)
+}
+
+// AddDebugTarget forces full sampling and debug logging for flows whose
+// DomainContext or root span attributes have key set to value, for ttl
+// (0 keeps it until RemoveDebugTarget). Adding an existing target resets its
+// expiry.
+func (h *Handle) AddDebugTarget(key, value string, ttl time.Duration) {
+	h.debug.Add(key, value, ttl)
+}
+
+// RemoveDebugTarget stops debugging the flows of a target.
+func (h *Handle) RemoveDebugTarget(key, value string) {
+	h.debug.Remove(key, value)
+}
+
+// DebugTargets lists the targets that have not expired.
+func (h *Handle) DebugTargets() []DebugTarget {
+	targets := h.debug.List()
+	out := make([]DebugTarget, len(targets))
+	for i, t := range targets {
+		out[i] = DebugTarget(t)
+	}
+	return out
+}
+
+// IsDebug reports whether the flow of ctx is being debugged, through
+// baggage or a debug target matching its DomainContext.
+func (h *Handle) IsDebug(ctx context.Context) bool {
+	return h.debug.match(ctx, nil)
+}
+
+// debugTargets matches flows against the debug targets of a Handle, shared
+// with go/ampyobs through the debugtarget package.
+type debugTargets struct {
+	debugtarget.Set
+}
+
+// match reports whether the flow of ctx, or a span starting with attrs, is
+// being debugged. A nil t only honors the baggage marker.
+func (t *debugTargets) match(ctx context.Context, attrs []attribute.KeyValue) bool {
+	if debugtarget.HasBaggage(ctx) {
+		return true
+	}
+	if t == nil || t.Empty() {
+		return false
+	}
+	if dc, ok := FromDomainContext(ctx); ok && t.MatchAny(dc.attributes()) {
+		return true
+	}
+	return t.MatchAny(attrs)
+}
+
+// debugSampler samples every span of a debugged flow, even under a parent
+// that was not sampled, and defers to next otherwise.
+type debugSampler struct {
+	next  sdktrace.Sampler
+	debug *debugTargets
+}
+
+func (s debugSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !s.debug.match(p.ParentContext, p.Attributes) {
+		return s.next.ShouldSample(p)
+	}
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{debugtarget.Attr},
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (s debugSampler) Description() string {
+	return fmt.Sprintf("DebugSampler{%s}", s.next.Description())
+}
+
+// debugPropagator adds the BaggageDebug member when injecting a flow that
+// matches a debug target, so downstream services debug it too. It only
+// reaches the wire when the baggage propagator is configured.
+type debugPropagator struct {
+	propagation.TextMapPropagator
+	debug *debugTargets
+}
+
+func (p debugPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	if !debugtarget.HasBaggage(ctx) && p.debug.match(ctx, nil) {
+		ctx = debugtarget.WithBaggage(ctx)
+	}
+	p.TextMapPropagator.Inject(ctx, carrier)
+}
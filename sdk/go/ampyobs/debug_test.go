@@ -0,0 +1,130 @@
+package ampyobs
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestIsDebug(t *testing.T) {
+	h := &Handle{debug: &debugTargets{}}
+	h.AddDebugTarget("client_order_id", "co-9", 0)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"plain", context.Background(), false},
+		{"WithDebug", WithDebug(context.Background()), true},
+		{"matching domain context", WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-9", Symbol: "AAPL"}), true},
+		{"other order", WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-1"}), false},
+	}
+	for _, tt := range tests {
+		if got := h.IsDebug(tt.ctx); got != tt.want {
+			t.Errorf("%s: IsDebug = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	h.RemoveDebugTarget("client_order_id", "co-9")
+	if got := h.DebugTargets(); len(got) != 0 {
+		t.Errorf("DebugTargets() after remove = %+v", got)
+	}
+}
+
+func TestDebugLogging(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	levels, err := newLogLevels(Config{LogLevel: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	debug := &debugTargets{}
+	debug.Add("run_id", "bt-1", 0)
+	l := &zapLogger{base: zap.New(core), levels: levels, debug: debug}
+
+	l.Debug(context.Background(), "hidden")
+	l.Debug(WithDomainContext(context.Background(), DomainContext{RunID: "bt-1"}), "debugged run")
+	l.named("md").Debug(WithDebug(context.Background()), "debug baggage")
+	l.With(zap.Int("attempt", 1)).Debug(WithDomainContext(context.Background(), DomainContext{RunID: "bt-2"}), "other run")
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	if want := []string{"debugged run", "debug baggage"}; !slices.Equal(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestDebugSampling(t *testing.T) {
+	debug := &debugTargets{}
+	debug.Add("client_order_id", "co-9", 0)
+	spans := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(debugSampler{next: sdktrace.ParentBased(sdktrace.NeverSample()), debug: debug}),
+		sdktrace.WithSyncer(spans),
+	)
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []trace.SpanStartOption
+		want bool
+	}{
+		{name: "unrelated", ctx: context.Background(), want: false},
+		{name: "baggage", ctx: WithDebug(context.Background()), want: true},
+		{name: "domain context", ctx: WithDomainContext(context.Background(), DomainContext{ClientOrderID: "co-9"}), want: true},
+		{name: "root span attribute", ctx: context.Background(), opts: []trace.SpanStartOption{trace.WithAttributes(attribute.String("client_order_id", "co-9"))}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans.Reset()
+			_, root := tp.Tracer("test").Start(tt.ctx, "oms.submit", tt.opts...)
+			root.End()
+
+			got := spans.GetSpans()
+			if !tt.want {
+				if len(got) != 0 {
+					t.Errorf("exported %d spans of an undebugged flow", len(got))
+				}
+				return
+			}
+			if len(got) != 1 || !slices.Contains(got[0].Attributes, attribute.Bool(BaggageDebug, true)) {
+				t.Errorf("exported %+v, want one span marked %s", got, BaggageDebug)
+			}
+		})
+	}
+}
+
+func TestDebugPropagator(t *testing.T) {
+	debug := &debugTargets{}
+	debug.Add("client_order_id", "co-9", 0)
+	prop := debugPropagator{TextMapPropagator: propagation.Baggage{}, debug: debug}
+
+	tests := []struct {
+		name string
+		dc   DomainContext
+		want bool
+	}{
+		{"matching target", DomainContext{ClientOrderID: "co-9"}, true},
+		{"other flow", DomainContext{ClientOrderID: "co-1"}, false},
+	}
+	for _, tt := range tests {
+		carrier := propagation.MapCarrier{}
+		prop.Inject(WithDomainContext(context.Background(), tt.dc), carrier)
+		// The receiving service has no targets; the baggage alone marks the flow.
+		ctx := propagation.Baggage{}.Extract(context.Background(), carrier)
+		if got := baggage.FromContext(ctx).Member(BaggageDebug).Value() == "1"; got != tt.want {
+			t.Errorf("%s: downstream marked = %v (headers %v), want %v", tt.name, got, carrier, tt.want)
+		}
+	}
+}
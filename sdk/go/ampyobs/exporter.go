@@ -0,0 +1,202 @@
+package ampyobs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// OTLP wire protocols accepted by Config.Protocol.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+func normalizeProtocol(p string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(p)) {
+	case "", "grpc":
+		return ProtocolGRPC, nil
+	case "http", "http/protobuf":
+		return ProtocolHTTPProtobuf, nil
+	case "http/json":
+		return "", fmt.Errorf("protocol %q is only supported by go/ampyobs (use grpc or http/protobuf)", p)
+	default:
+		return "", fmt.Errorf("unsupported protocol %q (use grpc or http/protobuf)", p)
+	}
+}
+
+// endpoint is a parsed collector address.
+type endpoint struct {
+	hostport string
+	insecure bool
+	path     string // URL path for HTTP exporters; empty means the signal default
+}
+
+// collectorEndpoint resolves the collector address for protocol. A bare
+// "host:port" is plaintext unless TLS is configured; a URL's scheme decides.
+// As in the OTLP spec, a URL path is a base to which signalPath (e.g.
+// "/v1/traces") is appended for HTTP exporters.
+func (c Config) collectorEndpoint(protocol, signalPath string) endpoint {
+	raw := c.CollectorEndpoint
+	if raw == "" {
+		raw = c.CollectorGRPC
+	}
+	if raw == "" {
+		if protocol == ProtocolGRPC {
+			raw = "127.0.0.1:4317"
+		} else {
+			raw = "127.0.0.1:4318"
+		}
+	}
+	if !strings.Contains(raw, "://") {
+		return endpoint{hostport: raw, insecure: !c.TLS.enabled()}
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return endpoint{hostport: raw, insecure: !c.TLS.enabled()}
+	}
+	ep := endpoint{hostport: u.Host, insecure: u.Scheme == "http"}
+	if p := strings.TrimRight(u.Path, "/"); p != "" && protocol == ProtocolHTTPProtobuf {
+		ep.path = p + signalPath
+	}
+	return ep
+}
+
+func (c Config) exporterTLS() (*tls.Config, error) {
+	if !c.TLS.enabled() {
+		return &tls.Config{}, nil
+	}
+	return newTLSClientConfig(c.TLS)
+}
+
+func newSpanExporter(ctx context.Context, cfg Config, protocol string) (sdktrace.SpanExporter, error) {
+	ep := cfg.collectorEndpoint(protocol, "/v1/traces")
+	tc, err := cfg.exporterTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == ProtocolHTTPProtobuf {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(ep.hostport)}
+		if ep.path != "" {
+			opts = append(opts, otlptracehttp.WithURLPath(ep.path))
+		}
+		if ep.insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		} else {
+			opts = append(opts, otlptracehttp.WithTLSClientConfig(tc))
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp http exporter: %w", err)
+		}
+		return exp, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(ep.hostport)}
+	if ep.insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tc)))
+	}
+	exp, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+	return exp, nil
+}
+
+func newMetricExporter(ctx context.Context, cfg Config, protocol string) (sdkmetric.Exporter, error) {
+	ep := cfg.collectorEndpoint(protocol, "/v1/metrics")
+	tc, err := cfg.exporterTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	if protocol == ProtocolHTTPProtobuf {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(ep.hostport)}
+		if ep.path != "" {
+			opts = append(opts, otlpmetrichttp.WithURLPath(ep.path))
+		}
+		if ep.insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tc))
+		}
+		exp, err := otlpmetrichttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp metric http exporter: %w", err)
+		}
+		return exp, nil
+	}
+
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(ep.hostport)}
+	if ep.insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tc)))
+	}
+	exp, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("otlp metric exporter: %w", err)
+	}
+	return exp, nil
+}
+
+// defaultSampleRatio is the root ratio of the "parent" sampler, as in
+// go/ampyobs.
+const defaultSampleRatio = 0.25
+
+// newSampler maps Config.Sampler onto a parent-based sampler, with the same
+// names and meanings as go/ampyobs. Left empty, it keeps this flavor's
+// historical behavior of sampling every root span.
+func newSampler(cfg Config) (sdktrace.Sampler, error) {
+	switch strings.ToLower(cfg.Sampler) {
+	case "", "always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parent":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(defaultSampleRatio)), nil
+	case "always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "ratio":
+		if math.IsNaN(cfg.SampleRatio) || cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+			return nil, fmt.Errorf("sample ratio %v must be within [0, 1]", cfg.SampleRatio)
+		}
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio)), nil
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", cfg.Sampler)
+	}
+}
+
+// newPropagator builds the propagator named by Config.Propagators
+// (default: tracecontext and baggage).
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+	if len(names) == 0 {
+		names = []string{"tracecontext", "baggage"}
+	}
+	var props []propagation.TextMapPropagator
+	for _, n := range names {
+		switch strings.ToLower(strings.TrimSpace(n)) {
+		case "tracecontext":
+			props = append(props, propagation.TraceContext{})
+		case "baggage":
+			props = append(props, propagation.Baggage{})
+		case "none":
+		default:
+			return nil, fmt.Errorf("unknown propagator %q (use tracecontext, baggage or none)", n)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(props...), nil
+}
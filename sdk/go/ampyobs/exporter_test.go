@@ -0,0 +1,119 @@
+package ampyobs
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestNewSampler(t *testing.T) {
+	tests := []struct {
+		sampler string
+		ratio   float64
+		want    sdktrace.Sampler // nil when an error is expected
+	}{
+		{"", 0, sdktrace.ParentBased(sdktrace.AlwaysSample())},
+		{"always_on", 0, sdktrace.ParentBased(sdktrace.AlwaysSample())},
+		{"parent", 0, sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.25))},
+		{"Always_Off", 0, sdktrace.ParentBased(sdktrace.NeverSample())},
+		{"ratio", 0.1, sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.1))},
+		{"ratio", 1.5, nil},
+		{"ratelimit", 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := newSampler(Config{Sampler: tt.sampler, SampleRatio: tt.ratio})
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("newSampler(%q, %v) succeeded", tt.sampler, tt.ratio)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newSampler(%q, %v): %v", tt.sampler, tt.ratio, err)
+			continue
+		}
+		if got.Description() != tt.want.Description() {
+			t.Errorf("newSampler(%q, %v) = %s, want %s", tt.sampler, tt.ratio, got.Description(), tt.want.Description())
+		}
+	}
+}
+
+func TestNormalizeProtocol(t *testing.T) {
+	tests := []struct {
+		in, want, errHas string
+	}{
+		{in: "", want: ProtocolGRPC},
+		{in: " GRPC ", want: ProtocolGRPC},
+		{in: "http", want: ProtocolHTTPProtobuf},
+		{in: "http/protobuf", want: ProtocolHTTPProtobuf},
+		{in: "http/json", errHas: "go/ampyobs"},
+		{in: "thrift", errHas: "unsupported"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeProtocol(tt.in)
+		if tt.errHas != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errHas) {
+				t.Errorf("normalizeProtocol(%q) error = %v, want it to mention %q", tt.in, err, tt.errHas)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeProtocol(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCollectorEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		protocol string
+		want     endpoint
+	}{
+		{"grpc default", Config{}, ProtocolGRPC, endpoint{hostport: "127.0.0.1:4317", insecure: true}},
+		{"http default", Config{}, ProtocolHTTPProtobuf, endpoint{hostport: "127.0.0.1:4318", insecure: true}},
+		{"deprecated field", Config{CollectorGRPC: "otel:4317"}, ProtocolGRPC, endpoint{hostport: "otel:4317", insecure: true}},
+		{"endpoint wins", Config{CollectorEndpoint: "new:4317", CollectorGRPC: "old:4317"}, ProtocolGRPC, endpoint{hostport: "new:4317", insecure: true}},
+		{"bare with TLS", Config{CollectorEndpoint: "otel:4317", TLS: TLSConfig{CAFile: "ca.pem"}}, ProtocolGRPC, endpoint{hostport: "otel:4317"}},
+		{"https url", Config{CollectorEndpoint: "https://otel:4318"}, ProtocolHTTPProtobuf, endpoint{hostport: "otel:4318"}},
+		{"http url", Config{CollectorEndpoint: "http://otel:4318", TLS: TLSConfig{CAFile: "ca.pem"}}, ProtocolHTTPProtobuf, endpoint{hostport: "otel:4318", insecure: true}},
+		{"base path", Config{CollectorEndpoint: "https://gw.example/otel/"}, ProtocolHTTPProtobuf, endpoint{hostport: "gw.example", path: "/otel/v1/traces"}},
+		{"grpc ignores path", Config{CollectorEndpoint: "https://gw.example/otel"}, ProtocolGRPC, endpoint{hostport: "gw.example"}},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.collectorEndpoint(tt.protocol, "/v1/traces"); got != tt.want {
+			t.Errorf("%s: collectorEndpoint() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPropagator(t *testing.T) {
+	tests := []struct {
+		names   []string
+		fields  []string
+		wantErr bool
+	}{
+		{nil, []string{"traceparent", "tracestate", "baggage"}, false},
+		{[]string{"tracecontext"}, []string{"traceparent", "tracestate"}, false},
+		{[]string{"none"}, nil, false},
+		{[]string{"b3"}, nil, true},
+	}
+	for _, tt := range tests {
+		p, err := newPropagator(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newPropagator(%v) error = %v", tt.names, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		got := p.Fields()
+		sort.Strings(got)
+		sort.Strings(tt.fields)
+		if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("newPropagator(%v) fields = %v, want %v", tt.names, got, tt.fields)
+		}
+	}
+}
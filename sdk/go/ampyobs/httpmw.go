@@ -4,12 +4,11 @@ import (
 	"net/http"
 	"time"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 )
 
 func HTTPServerMiddleware(hdl *Handle) func(next http.Handler) http.Handler {
-	prop := otel.GetTextMapPropagator()
+	prop := hdl.Propagator()
 	tr := hdl.Tracer("http.server")
 
 	return func(next http.Handler) http.Handler {
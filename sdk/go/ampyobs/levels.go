@@ -0,0 +1,138 @@
+package ampyobs
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/loglevels"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggerLevel is the level of one logger as reported by Handle.Levels.
+type LoggerLevel struct {
+	Logger   string        `json:"logger"` // "" for the root logger
+	Level    zapcore.Level `json:"level"`
+	RevertAt *time.Time    `json:"revert_at,omitempty"` // set while a temporary level applies
+}
+
+// Named returns a logger whose records carry logger=name and whose level can
+// be set apart from the root's, e.g. Named("oms") or Named("md.ingest"). A
+// dotted name without a level of its own follows its parent ("md"), then the
+// root.
+func (h *Handle) Named(name string) Logger {
+	return h.logger.named(name)
+}
+
+// Level returns the level in effect for the named logger ("" for the root).
+func (h *Handle) Level(name string) zapcore.Level {
+	return h.levels.level(name)
+}
+
+// SetLevel sets the level of the named logger ("" for the root). With a
+// positive ttl the previous level comes back once it elapses.
+func (h *Handle) SetLevel(name string, level zapcore.Level, ttl time.Duration) {
+	h.levels.set(name, level, ttl)
+}
+
+// ResetLevel restores the configured level of the named logger (see
+// Config.LogLevels); a name without one follows its parent again.
+func (h *Handle) ResetLevel(name string) {
+	h.levels.reset(name)
+}
+
+// Levels lists the root level followed by every named level, by name.
+func (h *Handle) Levels() []LoggerLevel {
+	return h.levels.list()
+}
+
+// LevelHandler reads and changes log levels over HTTP:
+//
+//	GET                                       lists the levels as JSON
+//	PUT|POST ?logger=oms&level=debug&ttl=15m  sets a level (logger and ttl optional)
+//	DELETE   ?logger=oms                      restores the configured level
+//
+// Parameters may also be sent as a form body. Every call responds with the
+// levels after the change. Mount it on an internal admin port only.
+func (h *Handle) LevelHandler() http.Handler {
+	return h.levels.shared.Handler(
+		func(s string) (int, error) {
+			l, err := parseLevel(s)
+			return int(l), err
+		},
+		func(l int) string { return zapcore.Level(l).String() },
+	)
+}
+
+// parseLevel parses "debug", "INFO", "warn" and the like.
+func parseLevel(s string) (zapcore.Level, error) {
+	var l zapcore.Level
+	err := l.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return l, err
+}
+
+// ----------- Levels -----------
+
+// logLevels holds the root and named levels of a Handle, shared with
+// go/ampyobs through the loglevels package.
+type logLevels struct {
+	shared *loglevels.Levels
+}
+
+// newLogLevels applies cfg.LogLevel (default: debug) and cfg.LogLevels.
+func newLogLevels(cfg Config) (*logLevels, error) {
+	configured := map[string]int{"": int(zapcore.DebugLevel)}
+	if cfg.LogLevel != "" {
+		l, err := parseLevel(cfg.LogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("log level: %w", err)
+		}
+		configured[""] = int(l)
+	}
+	for _, name := range sortedKeys(cfg.LogLevels) {
+		l, err := parseLevel(cfg.LogLevels[name])
+		if err != nil {
+			return nil, fmt.Errorf("log level of %q: %w", name, err)
+		}
+		if name != "" {
+			configured[name] = int(l)
+		}
+	}
+	return &logLevels{shared: loglevels.New(configured)}, nil
+}
+
+func (l *logLevels) enabled(name string, level zapcore.Level) bool {
+	return level >= l.level(name)
+}
+
+func (l *logLevels) level(name string) zapcore.Level {
+	return zapcore.Level(l.shared.Level(name))
+}
+
+func (l *logLevels) set(name string, level zapcore.Level, ttl time.Duration) {
+	l.shared.Set(name, int(level), ttl)
+}
+
+func (l *logLevels) reset(name string) {
+	l.shared.Reset(name)
+}
+
+func (l *logLevels) list() []LoggerLevel {
+	entries := l.shared.List()
+	out := make([]LoggerLevel, len(entries))
+	for i, e := range entries {
+		out[i] = LoggerLevel{Logger: e.Logger, Level: zapcore.Level(e.Level), RevertAt: e.RevertAt}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,37 @@
+package ampyobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelHandler(t *testing.T) {
+	levels, err := newLogLevels(Config{LogLevel: "info", LogLevels: map[string]string{"md": "warn"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handle{levels: levels}
+
+	rec := httptest.NewRecorder()
+	h.LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/loglevels?logger=md.ingest&level=debug&ttl=1h", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var got []LoggerLevel
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[2].Logger != "md.ingest" || got[2].Level != zapcore.DebugLevel || got[2].RevertAt == nil {
+		t.Errorf("levels = %+v", got)
+	}
+	if h.Level("md.ingest.kafka") != zapcore.DebugLevel || h.Level("md.bars") != zapcore.WarnLevel || h.Level("oms") != zapcore.InfoLevel {
+		t.Errorf("levels after PUT: %+v", h.Levels())
+	}
+	if _, err := newLogLevels(Config{LogLevels: map[string]string{"oms": "loud"}}); err == nil {
+		t.Error("newLogLevels accepted an unknown level")
+	}
+}
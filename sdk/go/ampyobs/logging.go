@@ -19,11 +19,17 @@ type Logger interface {
 }
 
 type zapLogger struct {
-	base *zap.Logger
-	meta []zap.Field // static fields: service, env, version
+	base    *zap.Logger
+	meta    []zap.Field // static fields: service, env, version
+	levels  *logLevels
+	debug   *debugTargets // flows logged at debug level whatever levels says
+	name    string        // "" for the root logger
+	sampler *logSampler   // nil unless Config.LogSampling.Enabled
 }
 
-func newLogger(cfg Config) Logger {
+// newLogger writes every level to the core and filters by levels, so named
+// loggers can be more verbose than the root, and debugged flows by debug.
+func newLogger(cfg Config, levels *logLevels, debug *debugTargets) *zapLogger {
 	encCfg := zapcore.EncoderConfig{
 		TimeKey:       "ts",
 		LevelKey:      "level",
@@ -45,11 +51,20 @@ func newLogger(cfg Config) Logger {
 		zap.String("env", cfg.Environment),
 		zap.String("service_version", cfg.ServiceVersion),
 	}
-	return &zapLogger{base: z, meta: meta}
+	return &zapLogger{base: z, meta: meta, levels: levels, debug: debug}
 }
 
 func (l *zapLogger) With(kv ...zap.Field) Logger {
-	return &zapLogger{base: l.base, meta: append(append([]zap.Field{}, l.meta...), kv...)}
+	return &zapLogger{base: l.base, meta: append(append([]zap.Field{}, l.meta...), kv...), levels: l.levels, debug: l.debug, name: l.name, sampler: l.sampler}
+}
+
+// named returns a child logger; like zap, names nest with dots.
+func (l *zapLogger) named(name string) *zapLogger {
+	full := name
+	if l.name != "" {
+		full = l.name + "." + name
+	}
+	return &zapLogger{base: l.base.Named(name), meta: l.meta, levels: l.levels, debug: l.debug, name: full, sampler: l.sampler}
 }
 
 func (l *zapLogger) Info(ctx context.Context, msg string, kv ...zap.Field)  { l.log(ctx, zap.InfoLevel, msg, kv...) }
@@ -58,6 +73,12 @@ func (l *zapLogger) Error(ctx context.Context, msg string, kv ...zap.Field) { l.
 func (l *zapLogger) Debug(ctx context.Context, msg string, kv ...zap.Field) { l.log(ctx, zap.DebugLevel, msg, kv...) }
 
 func (l *zapLogger) log(ctx context.Context, level zapcore.Level, msg string, kv ...zap.Field) {
+	if !l.levels.enabled(l.name, level) && !l.debug.match(ctx, nil) {
+		return
+	}
+	if l.sampler != nil && !l.sampler.allow(level, msg) {
+		return
+	}
 	fields := append([]zap.Field{}, l.meta...)
 
 	// Attach trace/span ids if present
@@ -0,0 +1,91 @@
+package ampyobs
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/logsample"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogSamplingConfig protects the log pipeline from floods, such as a broker
+// reconnect loop logging the same error millions of times. Records are
+// counted per level and message in windows of Interval: the first First are
+// logged, then every Thereafter-th. RateLimit caps what is left across all
+// messages. Suppressed records are counted in the
+// ampy_obs_logs_suppressed_total metric and reported every SummaryInterval
+// as one warning per message, e.g.
+//
+//	suppressed 12,345 occurrences of "broker disconnected"
+type LogSamplingConfig struct {
+	Enabled         bool
+	Interval        time.Duration // counting window per message (default: 1s)
+	First           int           // records per message and window always logged (default: 100)
+	Thereafter      int           // then every Thereafter-th is logged (0: none)
+	RateLimit       float64       // records per second across all messages, after sampling (0: unlimited)
+	SummaryInterval time.Duration // how often suppressed counts are logged (default: 1m)
+}
+
+func (c LogSamplingConfig) validate() error {
+	if c.Interval < 0 || c.SummaryInterval < 0 {
+		return fmt.Errorf("log sampling: negative interval")
+	}
+	if c.First < 0 || c.Thereafter < 0 || c.RateLimit < 0 || math.IsNaN(c.RateLimit) {
+		return fmt.Errorf("log sampling: first, thereafter and rate limit must not be negative")
+	}
+	return nil
+}
+
+// logSampler adapts the shared logsample core to zap: summaries go to out
+// as warnings and suppressed records to the suppressed_total counter.
+type logSampler struct {
+	core    *logsample.Sampler
+	out     *zapLogger
+	counter *prometheus.CounterVec // by level and reason
+}
+
+func newLogSampler(cfg LogSamplingConfig, out *zapLogger, m *Metrics) *logSampler {
+	s := &logSampler{
+		out: out,
+		counter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ampy",
+			Name:      "obs_logs_suppressed_total",
+			Help:      "Log records dropped by log sampling, by level and reason",
+		}, []string{"level", "reason"}),
+	}
+	m.reg.MustRegister(s.counter)
+	s.core = logsample.New(logsample.Config{
+		Interval:        cfg.Interval,
+		First:           cfg.First,
+		Thereafter:      cfg.Thereafter,
+		RateLimit:       cfg.RateLimit,
+		SummaryInterval: cfg.SummaryInterval,
+	}, s.report, func(level int, reason string) {
+		s.counter.WithLabelValues(zapcore.Level(level).String(), reason).Inc()
+	})
+	return s
+}
+
+// allow reports whether a record is logged, counting it otherwise.
+func (s *logSampler) allow(level zapcore.Level, msg string) bool {
+	return s.core.Allow(int(level), msg)
+}
+
+// report logs one summary as a warning.
+func (s *logSampler) report(sum logsample.Summary) {
+	fields := append(append([]zap.Field{}, s.out.meta...),
+		zap.String("suppressed_message", sum.Message),
+		zap.String("suppressed_level", zapcore.Level(sum.Level).String()),
+		zap.Int64("suppressed", sum.Suppressed),
+	)
+	s.out.base.Warn(sum.Text(), fields...)
+}
+
+// summarize logs one warning per message suppressed since the last call.
+func (s *logSampler) summarize() { s.core.Summarize() }
+
+// shutdown stops the summary loop and reports what is still pending.
+func (s *logSampler) shutdown() { s.core.Shutdown() }
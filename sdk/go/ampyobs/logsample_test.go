@@ -0,0 +1,181 @@
+package ampyobs
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestSampler returns a sampler whose summaries land in the returned
+// observer, with its metrics registered on m.
+func newTestSampler(t *testing.T, cfg LogSamplingConfig, m *Metrics) (*logSampler, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	out := &zapLogger{base: zap.New(core), meta: []zap.Field{zap.String("service", "svc")}}
+	return newLogSampler(cfg, out, m), logs
+}
+
+// suppressedTotal reads ampy_obs_logs_suppressed_total for level and reason.
+func suppressedTotal(t *testing.T, m *Metrics, level, reason string) float64 {
+	t.Helper()
+	families, err := m.reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "ampy_obs_logs_suppressed_total" {
+			continue
+		}
+		for _, mt := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range mt.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["level"] == level && labels["reason"] == reason {
+				return mt.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestLogSampler(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       LogSamplingConfig
+		logged    int
+		reason    string
+		summaries []string
+	}{
+		{
+			name:      "first then every thereafter",
+			cfg:       LogSamplingConfig{First: 3, Thereafter: 5},
+			logged:    6, // 1, 2, 3, 8, 13 and 18
+			reason:    "sampling",
+			summaries: []string{`suppressed 14 occurrences of "broker disconnected"`},
+		},
+		{
+			name:      "rate limit across messages",
+			cfg:       LogSamplingConfig{RateLimit: 0.01}, // one token, not refilled during the test
+			logged:    1,
+			reason:    "ratelimit",
+			summaries: []string{`suppressed 19 occurrences of "broker disconnected"`, `suppressed 1 occurrences of "reconnected"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetrics()
+			s, logs := newTestSampler(t, tt.cfg, m)
+			logged := 0
+			for i := 0; i < 20; i++ {
+				if s.allow(zap.ErrorLevel, "broker disconnected") {
+					logged++
+				}
+			}
+			if logged != tt.logged {
+				t.Errorf("logged %d of 20, want %d", logged, tt.logged)
+			}
+			s.allow(zap.InfoLevel, "reconnected")
+			if got := suppressedTotal(t, m, "error", tt.reason); got != float64(20-tt.logged) {
+				t.Errorf("suppressed_total{error,%s} = %v, want %d", tt.reason, got, 20-tt.logged)
+			}
+
+			s.shutdown()
+			entries := logs.TakeAll()
+			if len(entries) != len(tt.summaries) {
+				t.Fatalf("%d summaries, want %d: %v", len(entries), len(tt.summaries), entries)
+			}
+			for i, e := range entries {
+				if e.Message != tt.summaries[i] || e.Level != zap.WarnLevel {
+					t.Errorf("summary %d = %s %q, want warn %q", i, e.Level, e.Message, tt.summaries[i])
+				}
+			}
+			fields := entries[0].ContextMap()
+			if fields["service"] != "svc" || fields["suppressed_message"] != "broker disconnected" || fields["suppressed_level"] != "error" || fields["suppressed"] != int64(20-tt.logged) {
+				t.Errorf("summary fields = %v", fields)
+			}
+			// Counts reset with every summary.
+			s.summarize()
+			if n := logs.Len(); n != 0 {
+				t.Errorf("second summary logged %d entries", n)
+			}
+		})
+	}
+}
+
+func TestLogSamplerWindow(t *testing.T) {
+	s, _ := newTestSampler(t, LogSamplingConfig{Interval: 20 * time.Millisecond, First: 1, SummaryInterval: time.Hour}, NewMetrics())
+	defer s.shutdown()
+	if !s.allow(zap.WarnLevel, "slow") || s.allow(zap.WarnLevel, "slow") {
+		t.Fatal("want only the first record of the window")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !s.allow(zap.WarnLevel, "slow") {
+		t.Error("first record of a new window suppressed")
+	}
+}
+
+func TestLogSamplerSummaryInterval(t *testing.T) {
+	s, logs := newTestSampler(t, LogSamplingConfig{First: 1, SummaryInterval: 10 * time.Millisecond}, NewMetrics())
+	defer s.shutdown()
+	s.allow(zap.ErrorLevel, "flood")
+	s.allow(zap.ErrorLevel, "flood")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logs.Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no summary before shutdown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLogSamplingConfigValidate(t *testing.T) {
+	tests := []struct {
+		cfg     LogSamplingConfig
+		wantErr bool
+	}{
+		{LogSamplingConfig{}, false},
+		{LogSamplingConfig{Enabled: true, Interval: time.Second, First: 10, Thereafter: 100, RateLimit: 50}, false},
+		{LogSamplingConfig{Interval: -time.Second}, true},
+		{LogSamplingConfig{SummaryInterval: -time.Second}, true},
+		{LogSamplingConfig{First: -1}, true},
+		{LogSamplingConfig{RateLimit: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v.validate() = %v, want error %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	levels, err := newLogLevels(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &zapLogger{base: zap.New(core), levels: levels}
+	l.sampler = newLogSampler(LogSamplingConfig{First: 2}, l, NewMetrics())
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		l.With(zap.Int("attempt", 1)).Error(ctx, "broker disconnected")
+	}
+	// Named loggers share the sampler.
+	l.named("md").Error(ctx, "broker disconnected")
+	l.sampler.shutdown()
+
+	var got []string
+	for _, e := range logs.All() {
+		got = append(got, e.Message)
+	}
+	want := []string{"broker disconnected", "broker disconnected", `suppressed 4 occurrences of "broker disconnected"`}
+	if !slices.Equal(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
@@ -2,36 +2,92 @@ package ampyobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// Config configures Init. Exporter headers, compression, timeouts and retry
+// are not Config fields in this flavor (go/ampyobs has them); the OTLP
+// exporters read them from the standard OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_EXPORTER_OTLP_COMPRESSION and OTEL_EXPORTER_OTLP_TIMEOUT variables.
 type Config struct {
-	ServiceName    string
-	ServiceVersion string
-	Environment    string
-	CollectorGRPC  string // "127.0.0.1:4317" (your Collector)
+	ServiceName       string
+	ServiceVersion    string
+	Environment       string
+	CollectorEndpoint string        // "host:port" or URL; default 127.0.0.1:4317 (grpc) / :4318 (http)
+	CollectorGRPC     string        // deprecated: use CollectorEndpoint
+	Protocol          string        // "grpc" | "http/protobuf" (default: "grpc")
+	TLS               TLSConfig     // TLS/mTLS toward the collector; plaintext when unset
+	Sampler           string        // "parent" (ratio 0.25) | "ratio" | "always_on" | "always_off" (default: always sample roots)
+	SampleRatio       float64       // used with Sampler "ratio"
+	Propagators       []string      // "tracecontext" | "baggage" | "none" (default: tracecontext, baggage)
+	EnableMetrics     bool          // OTLP metrics via an OTel MeterProvider, alongside the Prometheus registry
+	MetricInterval    time.Duration // OTLP metric export interval (default: 10s)
+	WAL               WALConfig     // disk-backed queue for batches the collector did not accept
+
+	// LogLevel is the initial root log level: "debug", "info", "warn" or
+	// "error" (default: debug). LogLevels sets loggers by name (see
+	// Handle.Named), e.g. {"oms": "debug", "md": "warn"}. Both can be
+	// changed at runtime with SetLevel or LevelHandler.
+	LogLevel  string
+	LogLevels map[string]string
+
+	// LogSampling drops repeats of a flooding message and reports how many
+	// were dropped; see LogSamplingConfig. Off by default.
+	LogSampling LogSamplingConfig
 }
 
 type Handle struct {
-	cfg     Config
-	tp      *sdktrace.TracerProvider
-	Logger  Logger
-	Metrics *Metrics
+	cfg    Config
+	tp     *sdktrace.TracerProvider
+	mp     *sdkmetric.MeterProvider
+	prop   propagation.TextMapPropagator
+	Logger Logger
+	logger *zapLogger // Logger as built, for Named
+	levels *logLevels
+	debug  *debugTargets
+
+	logSampler           *logSampler // nil unless Config.LogSampling.Enabled
+	walSpans, walMetrics *walQueue
+	Metrics              *Metrics
 }
 
 func Init(ctx context.Context, cfg Config) (*Handle, error) {
-	if cfg.CollectorGRPC == "" {
-		cfg.CollectorGRPC = "127.0.0.1:4317"
+	protocol, err := normalizeProtocol(cfg.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := newPropagator(cfg.Propagators)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := newLogLevels(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.LogSampling.validate(); err != nil {
+		return nil, err
 	}
+	debug := &debugTargets{}
+	sampler = debugSampler{next: sampler, debug: debug}
+	prop = debugPropagator{TextMapPropagator: prop, debug: debug}
+	logger := newLogger(cfg, levels, debug)
+
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
 			attribute.String("service.name", cfg.ServiceName),
@@ -43,26 +99,76 @@ func Init(ctx context.Context, cfg Config) (*Handle, error) {
 		return nil, fmt.Errorf("resource: %w", err)
 	}
 
-	exp, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithEndpoint(cfg.CollectorGRPC),
-		otlptracegrpc.WithInsecure(),
-	)
+	exp, err := newSpanExporter(ctx, cfg, protocol)
 	if err != nil {
-		return nil, fmt.Errorf("otlp exporter: %w", err)
+		return nil, err
+	}
+	var walSpans, walMetrics *walQueue
+	if cfg.WAL.enabled() {
+		w, err := newWALSpanExporter(exp, cfg.WAL)
+		if err != nil {
+			_ = exp.Shutdown(ctx)
+			return nil, err
+		}
+		exp, walSpans = w, w.core.q
 	}
-
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithBatcher(exp),
 		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
 	)
+
+	var mp *sdkmetric.MeterProvider
+	if cfg.EnableMetrics {
+		mexp, err := newMetricExporter(ctx, cfg, protocol)
+		if err != nil {
+			_ = tp.Shutdown(ctx)
+			return nil, err
+		}
+		if cfg.WAL.enabled() {
+			w, err := newWALMetricExporter(mexp, cfg.WAL)
+			if err != nil {
+				_ = mexp.Shutdown(ctx)
+				_ = tp.Shutdown(ctx)
+				return nil, err
+			}
+			mexp, walMetrics = w, w.core.q
+		}
+		interval := cfg.MetricInterval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		mp = sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(mexp, sdkmetric.WithInterval(interval))),
+		)
+		otel.SetMeterProvider(mp)
+	}
+
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTextMapPropagator(prop)
+
+	metrics := NewMetrics()
+	var ls *logSampler
+	if cfg.LogSampling.Enabled {
+		ls = newLogSampler(cfg.LogSampling, logger, metrics)
+		logger.sampler = ls
+	}
 
 	return &Handle{
 		cfg:     cfg,
 		tp:      tp,
-		Logger:  newLogger(cfg),
-		Metrics: NewMetrics(),
+		mp:      mp,
+		prop:    prop,
+		Logger:  logger,
+		logger:  logger,
+		levels:  levels,
+		debug:   debug,
+		Metrics: metrics,
+
+		logSampler: ls,
+		walSpans:   walSpans,
+		walMetrics: walMetrics,
 	}, nil
 }
 
@@ -70,8 +176,30 @@ func (h *Handle) Tracer(name string) trace.Tracer {
 	return h.tp.Tracer(name)
 }
 
+// Meter returns an OTel meter exported over OTLP. It is a no-op meter when
+// Config.EnableMetrics is false.
+func (h *Handle) Meter(name string) metric.Meter {
+	if h.mp == nil {
+		return noop.NewMeterProvider().Meter(name)
+	}
+	return h.mp.Meter(name)
+}
+
+// Propagator returns the propagator selected by Config.Propagators.
+func (h *Handle) Propagator() propagation.TextMapPropagator {
+	return h.prop
+}
+
 func (h *Handle) Shutdown(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	return h.tp.Shutdown(ctx)
+	if h.logSampler != nil {
+		h.logSampler.shutdown()
+	}
+	var errs []error
+	if h.mp != nil {
+		errs = append(errs, h.mp.Shutdown(ctx))
+	}
+	errs = append(errs, h.tp.Shutdown(ctx))
+	return errors.Join(errs...)
 }
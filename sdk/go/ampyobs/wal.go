@@ -0,0 +1,401 @@
+package ampyobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmpyFin/ampy-observability/internal/otlpconv"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultWALMaxBytes       = 256 << 20
+	defaultWALReplayInterval = 5 * time.Second
+	walExt                   = ".wal"
+)
+
+// WALConfig enables a write-ahead log in front of every exporter. Batches
+// that fail to export are written to disk as OTLP protobuf and replayed in
+// order once the collector accepts data again, including after a restart.
+// A directory must not be shared by two running processes.
+type WALConfig struct {
+	Dir            string        // enables the WAL; one subdirectory per signal
+	MaxBytes       int64         // per-signal disk budget; oldest batches are dropped beyond it (default: 256 MiB)
+	ReplayInterval time.Duration // how often persisted batches are retried (default: 5s)
+}
+
+func (w WALConfig) enabled() bool { return w.Dir != "" }
+
+// WALStats describes one signal's on-disk queue.
+type WALStats struct {
+	Batches  int   // batches waiting on disk
+	Bytes    int64 // size of those batches
+	Dropped  int64 // batches discarded to stay under MaxBytes, or unreadable
+	Replayed int64 // persisted batches exported after a failure
+}
+
+// WALReport holds WALStats per signal. It is zero when the WAL is disabled.
+type WALReport struct {
+	Spans, Metrics WALStats
+}
+
+// WALStats returns the current depth and counters of the handle's WAL.
+func (h *Handle) WALStats() WALReport {
+	return WALReport{Spans: h.walSpans.stats(), Metrics: h.walMetrics.stats()}
+}
+
+// walQueue is a FIFO of encoded batches, one file per batch, named by a
+// zero-padded sequence number so directory order is replay order.
+type walQueue struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	seq     uint64
+	entries []walEntry // oldest first
+	bytes   int64
+
+	dropped  atomic.Int64
+	replayed atomic.Int64
+}
+
+type walEntry struct {
+	name string
+	size int64
+}
+
+func openWALQueue(dir string, maxBytes int64) (*walQueue, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultWALMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("wal: %w", err)
+	}
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("wal: %w", err)
+	}
+	q := &walQueue{dir: dir, maxBytes: maxBytes}
+	for _, de := range des {
+		name := de.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			_ = os.Remove(filepath.Join(dir, name)) // torn write from a crash
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walExt), 10, 64)
+		if err != nil || !strings.HasSuffix(name, walExt) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		q.entries = append(q.entries, walEntry{name: name, size: info.Size()})
+		q.bytes += info.Size()
+		q.seq = max(q.seq, seq)
+	}
+	sort.Slice(q.entries, func(i, j int) bool { return q.entries[i].name < q.entries[j].name })
+	return q, nil
+}
+
+// push appends b, evicting the oldest batches if the queue would exceed maxBytes.
+func (q *walQueue) push(b []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	name := fmt.Sprintf("%020d%s", q.seq, walExt)
+	path := filepath.Join(q.dir, name)
+	if err := os.WriteFile(path+".tmp", b, 0o600); err != nil {
+		return fmt.Errorf("wal: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("wal: %w", err)
+	}
+	q.entries = append(q.entries, walEntry{name: name, size: int64(len(b))})
+	q.bytes += int64(len(b))
+	for q.bytes > q.maxBytes && len(q.entries) > 0 {
+		q.removeLocked(q.entries[0])
+		q.dropped.Add(1)
+	}
+	return nil
+}
+
+// peek returns the oldest batch. ok is false when the queue is empty.
+func (q *walQueue) peek() (e walEntry, b []byte, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return walEntry{}, nil, false, nil
+	}
+	e = q.entries[0]
+	b, err = os.ReadFile(filepath.Join(q.dir, e.name))
+	return e, b, true, err
+}
+
+// remove deletes e if it is still queued (it may have been evicted meanwhile).
+func (q *walQueue) remove(e walEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeLocked(e)
+}
+
+func (q *walQueue) removeLocked(e walEntry) {
+	for i, cur := range q.entries {
+		if cur.name == e.name {
+			_ = os.Remove(filepath.Join(q.dir, e.name))
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			q.bytes -= e.size
+			return
+		}
+	}
+}
+
+func (q *walQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+func (q *walQueue) stats() WALStats {
+	if q == nil {
+		return WALStats{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return WALStats{
+		Batches:  len(q.entries),
+		Bytes:    q.bytes,
+		Dropped:  q.dropped.Load(),
+		Replayed: q.replayed.Load(),
+	}
+}
+
+// walCore implements the persist-and-replay logic shared by the signal
+// wrappers; T is the batch type handed to the wrapped exporter.
+type walCore[T any] struct {
+	q      *walQueue
+	encode func(T) ([]byte, error)
+	decode func([]byte) (T, error)
+	export func(context.Context, T) error
+
+	sendMu sync.Mutex // keeps direct exports and replays in order
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newWALCore[T any](cfg WALConfig, signal string, encode func(T) ([]byte, error), decode func([]byte) (T, error), export func(context.Context, T) error) (*walCore[T], error) {
+	q, err := openWALQueue(filepath.Join(cfg.Dir, signal), cfg.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.ReplayInterval
+	if interval <= 0 {
+		interval = defaultWALReplayInterval
+	}
+	w := &walCore[T]{
+		q:      q,
+		encode: encode,
+		decode: decode,
+		export: export,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.replayLoop(interval)
+	return w, nil
+}
+
+// exportBatch sends batch directly while the queue is empty; otherwise, or
+// if the send fails, it is appended to the WAL to keep export order.
+func (w *walCore[T]) exportBatch(ctx context.Context, batch T) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	var sendErr error
+	if w.q.len() == 0 {
+		if sendErr = w.export(ctx, batch); sendErr == nil {
+			return nil
+		}
+	}
+	b, err := w.encode(batch)
+	if err == nil {
+		err = w.q.push(b)
+	}
+	if err != nil {
+		return errors.Join(sendErr, err)
+	}
+	if sendErr != nil {
+		otel.Handle(fmt.Errorf("wal: export failed, batch persisted for replay: %w", sendErr))
+	}
+	return nil
+}
+
+// drain replays persisted batches oldest first until the queue is empty or
+// an export fails.
+func (w *walCore[T]) drain(ctx context.Context) error {
+	for {
+		if err := w.replayOne(ctx); err != nil || w.q.len() == 0 {
+			return err
+		}
+	}
+}
+
+func (w *walCore[T]) replayOne(ctx context.Context) error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	e, b, ok, err := w.q.peek()
+	if !ok {
+		return nil
+	}
+	var batch T
+	if err == nil {
+		batch, err = w.decode(b)
+	}
+	if err != nil {
+		// Unreadable entries would block the queue forever.
+		w.q.remove(e)
+		w.q.dropped.Add(1)
+		otel.Handle(fmt.Errorf("wal: dropping unreadable batch %s: %w", e.name, err))
+		return nil
+	}
+	if err := w.export(ctx, batch); err != nil {
+		return err
+	}
+	w.q.remove(e)
+	w.q.replayed.Add(1)
+	return nil
+}
+
+func (w *walCore[T]) replayLoop(interval time.Duration) {
+	defer close(w.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+			_ = w.drain(ctx)
+		}
+	}
+}
+
+// close stops the replay loop and makes a last drain attempt; whatever is
+// left stays on disk for the next process.
+func (w *walCore[T]) close(ctx context.Context) error {
+	select {
+	case <-w.stop:
+		return nil
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	if err := w.drain(ctx); err != nil {
+		return fmt.Errorf("wal: %d batches left on disk: %w", w.q.len(), err)
+	}
+	return nil
+}
+
+// walSpanExporter persists span batches the wrapped exporter fails to send.
+type walSpanExporter struct {
+	sdktrace.SpanExporter
+	core *walCore[[]sdktrace.ReadOnlySpan]
+}
+
+func newWALSpanExporter(exp sdktrace.SpanExporter, cfg WALConfig) (*walSpanExporter, error) {
+	core, err := newWALCore(cfg, "traces",
+		func(spans []sdktrace.ReadOnlySpan) ([]byte, error) {
+			return proto.Marshal(&collectortracepb.ExportTraceServiceRequest{ResourceSpans: otlpconv.SpansToProto(spans)})
+		},
+		func(b []byte) ([]sdktrace.ReadOnlySpan, error) {
+			var req collectortracepb.ExportTraceServiceRequest
+			if err := proto.Unmarshal(b, &req); err != nil {
+				return nil, err
+			}
+			return otlpconv.SpansFromProto(req.GetResourceSpans()), nil
+		},
+		exp.ExportSpans,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &walSpanExporter{SpanExporter: exp, core: core}, nil
+}
+
+func (e *walSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return e.core.exportBatch(ctx, spans)
+}
+
+func (e *walSpanExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.core.close(ctx), e.SpanExporter.Shutdown(ctx))
+}
+
+// walMetricExporter persists metric exports the wrapped exporter fails to send.
+type walMetricExporter struct {
+	sdkmetric.Exporter
+	core *walCore[*metricdata.ResourceMetrics]
+}
+
+func newWALMetricExporter(exp sdkmetric.Exporter, cfg WALConfig) (*walMetricExporter, error) {
+	core, err := newWALCore(cfg, "metrics",
+		func(rm *metricdata.ResourceMetrics) ([]byte, error) {
+			return proto.Marshal(&collectormetricspb.ExportMetricsServiceRequest{
+				ResourceMetrics: []*metricspb.ResourceMetrics{otlpconv.MetricsToProto(rm)},
+			})
+		},
+		func(b []byte) (*metricdata.ResourceMetrics, error) {
+			var req collectormetricspb.ExportMetricsServiceRequest
+			if err := proto.Unmarshal(b, &req); err != nil {
+				return nil, err
+			}
+			if len(req.GetResourceMetrics()) != 1 {
+				return nil, fmt.Errorf("expected 1 resource, got %d", len(req.GetResourceMetrics()))
+			}
+			return otlpconv.MetricsFromProto(req.GetResourceMetrics()[0]), nil
+		},
+		exp.Export,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &walMetricExporter{Exporter: exp, core: core}, nil
+}
+
+func (e *walMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.core.exportBatch(ctx, rm)
+}
+
+func (e *walMetricExporter) ForceFlush(ctx context.Context) error {
+	return errors.Join(e.core.drain(ctx), e.Exporter.ForceFlush(ctx))
+}
+
+func (e *walMetricExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.core.close(ctx), e.Exporter.Shutdown(ctx))
+}